@@ -1,15 +1,22 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
+	"time"
 
+	"github.com/joho/godotenv"
+	"github.com/orbex-dev/orbex/internal/version"
 	"github.com/spf13/cobra"
 )
 
@@ -35,6 +42,8 @@ func main() {
 	root.AddCommand(pauseCmd())
 	root.AddCommand(resumeCmd())
 	root.AddCommand(killCmd())
+	root.AddCommand(eventsCmd())
+	root.AddCommand(versionCmd())
 
 	if err := root.Execute(); err != nil {
 		os.Exit(1)
@@ -50,11 +59,16 @@ func jobsCmd() *cobra.Command {
 	}
 
 	// orbex jobs list
+	var listTag string
 	list := &cobra.Command{
 		Use:   "list",
 		Short: "List all jobs",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			body, err := apiGet("/jobs")
+			path := "/jobs"
+			if listTag != "" {
+				path += "?tag=" + url.QueryEscape(listTag)
+			}
+			body, err := apiGet(path)
 			if err != nil {
 				return err
 			}
@@ -62,23 +76,35 @@ func jobsCmd() *cobra.Command {
 			json.Unmarshal(body, &jobs)
 
 			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-			fmt.Fprintln(w, "ID\tNAME\tIMAGE\tSCHEDULE\tACTIVE")
+			fmt.Fprintln(w, "ID\tNAME\tIMAGE\tSCHEDULE\tACTIVE\tLAST STATUS")
 			for _, j := range jobs {
 				schedule := "—"
 				if s, ok := j["schedule"].(string); ok {
 					schedule = s
 				}
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\n",
-					truncID(j["id"]), j["name"], j["image"], schedule, j["is_active"])
+				lastStatus := paintStatus(os.Stdout, "", "—")
+				if lr, ok := j["last_run"].(map[string]interface{}); ok {
+					text := fmt.Sprint(lr["status"])
+					if code, ok := lr["exit_code"].(float64); ok && code != 0 {
+						text += fmt.Sprintf(" (exit %d)", int(code))
+					}
+					lastStatus = paintStatus(os.Stdout, lr["status"], text)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\t%s\n",
+					truncID(j["id"]), j["name"], j["image"], schedule, j["is_active"], lastStatus)
 			}
 			w.Flush()
 			return nil
 		},
 	}
+	list.Flags().StringVar(&listTag, "tag", "", "Only list jobs with this tag")
 
 	// orbex jobs create
-	var name, image, command, schedule string
-	var timeout int
+	var name, image, command, schedule, notifyOn, memory, cpu, gpus, dependsOn, envFile string
+	var timeout, maxConcurrent int
+	var env, tags []string
+	var notifyIncludeLogs bool
+	var templateVars bool
 	create := &cobra.Command{
 		Use:   "create",
 		Short: "Create a new job",
@@ -96,6 +122,40 @@ func jobsCmd() *cobra.Command {
 			if timeout > 0 {
 				payload["timeout_seconds"] = timeout
 			}
+			if maxConcurrent > 0 {
+				payload["max_concurrent_runs"] = maxConcurrent
+			}
+			if notifyOn != "" {
+				payload["notify_on"] = notifyOn
+			}
+			if notifyIncludeLogs {
+				payload["notify_include_logs"] = true
+			}
+			if templateVars {
+				payload["template_vars"] = true
+			}
+			if memory != "" {
+				payload["memory"] = memory
+			}
+			if cpu != "" {
+				payload["cpu"] = cpu
+			}
+			if gpus != "" {
+				payload["gpus"] = gpus
+			}
+			if dependsOn != "" {
+				payload["depends_on"] = dependsOn
+			}
+			if len(tags) > 0 {
+				payload["tags"] = tags
+			}
+			envMap, err := parseEnv(envFile, env)
+			if err != nil {
+				return err
+			}
+			if len(envMap) > 0 {
+				payload["env"] = envMap
+			}
 
 			body, err := apiPost("/jobs", payload)
 			if err != nil {
@@ -112,13 +172,25 @@ func jobsCmd() *cobra.Command {
 	create.Flags().StringVar(&command, "command", "", "Command (space-separated)")
 	create.Flags().StringVar(&schedule, "schedule", "", "Cron schedule")
 	create.Flags().IntVar(&timeout, "timeout", 0, "Timeout in seconds")
+	create.Flags().IntVar(&maxConcurrent, "max-concurrent-runs", 0, "Most runs of this job in flight at once (0 = no limit)")
+	create.Flags().StringVar(&memory, "memory", "", "Memory limit (e.g. 512Mi, 1Gi)")
+	create.Flags().StringVar(&cpu, "cpu", "", "CPU limit in cores (e.g. 0.5) or millicores (e.g. 500m)")
+	create.Flags().StringVar(&gpus, "gpus", "", "NVIDIA GPUs to expose: all or a count (e.g. 1)")
+	create.Flags().StringVar(&dependsOn, "depends-on", "", "Job ID to run after (each successful run triggers this job)")
+	create.Flags().StringArrayVar(&env, "env", nil, "Environment variable as KEY=value (repeatable)")
+	create.Flags().StringVar(&envFile, "env-file", "", "Read environment variables from a .env file")
+	create.Flags().StringVar(&notifyOn, "notify-on", "", "When to notify: all, failure, success, failure_and_recovery")
+	create.Flags().BoolVar(&notifyIncludeLogs, "notify-include-logs", false, "Attach the end of the run's logs to notifications")
+	create.Flags().BoolVar(&templateVars, "template-vars", false, "Resolve run variables such as {{.RunID}} and {{.Labels.key}} in the command and env")
+	create.Flags().StringArrayVar(&tags, "tag", nil, "Tag for grouping jobs (repeatable)")
 	create.MarkFlagRequired("name")
 	create.MarkFlagRequired("image")
 
 	// orbex jobs get <id>
+	var statsWindow string
 	get := &cobra.Command{
 		Use:   "get [job-id]",
-		Short: "Get job details",
+		Short: "Get job details and a summary of recent runs",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			body, err := apiGet("/jobs/" + args[0])
@@ -128,9 +200,21 @@ func jobsCmd() *cobra.Command {
 			var job map[string]interface{}
 			json.Unmarshal(body, &job)
 			printJSON(job)
+
+			// The summary is a convenience; the job itself was fetched.
+			body, err = apiGet("/jobs/" + args[0] + "/stats?window=" + url.QueryEscape(statsWindow))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Run stats unavailable: %v\n", err)
+				return nil
+			}
+			var stats map[string]interface{}
+			json.Unmarshal(body, &stats)
+			fmt.Println()
+			fmt.Print(statsSummary(stats))
 			return nil
 		},
 	}
+	get.Flags().StringVar(&statsWindow, "window", "7d", "Window for the run summary (e.g. 7d or 12h)")
 
 	// orbex jobs delete <id>
 	del := &cobra.Command{
@@ -147,28 +231,251 @@ func jobsCmd() *cobra.Command {
 		},
 	}
 
-	cmd.AddCommand(list, create, get, del)
+	// orbex jobs enable <id>
+	enable := &cobra.Command{
+		Use:   "enable [job-id]",
+		Short: "Enable a job so it can be triggered and scheduled",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := apiPost("/jobs/"+args[0]+"/enable", nil)
+			if err != nil {
+				return err
+			}
+			fmt.Println("✓ Job enabled")
+			return nil
+		},
+	}
+
+	// orbex jobs disable <id>
+	disable := &cobra.Command{
+		Use:   "disable [job-id]",
+		Short: "Disable a job without deleting it (in-flight runs keep going)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := apiPost("/jobs/"+args[0]+"/disable", nil)
+			if err != nil {
+				return err
+			}
+			fmt.Println("✓ Job disabled")
+			return nil
+		},
+	}
+
+	// orbex jobs clone <id>
+	var cloneName string
+	clone := &cobra.Command{
+		Use:   "clone [job-id]",
+		Short: "Create a copy of a job",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			payload := map[string]interface{}{}
+			if cloneName != "" {
+				payload["name"] = cloneName
+			}
+			body, err := apiPost("/jobs/"+args[0]+"/clone", payload)
+			if err != nil {
+				return err
+			}
+			var job map[string]interface{}
+			json.Unmarshal(body, &job)
+			fmt.Printf("✓ Cloned job: %s (%s)\n", job["name"], truncID(job["id"]))
+			return nil
+		},
+	}
+	clone.Flags().StringVar(&cloneName, "name", "", "Name for the new job (default: <name>-copy)")
+
+	// orbex jobs kill-runs <id>
+	var killTimeout int
+	killRuns := &cobra.Command{
+		Use:   "kill-runs [job-id]",
+		Short: "Kill all running and paused runs of a job",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var payload interface{}
+			if cmd.Flags().Changed("timeout") {
+				payload = map[string]interface{}{"timeout_seconds": killTimeout}
+			}
+			body, err := apiPost("/jobs/"+args[0]+"/runs/kill", payload)
+			if err != nil {
+				return err
+			}
+			var resp map[string]interface{}
+			json.Unmarshal(body, &resp)
+			fmt.Printf("✓ Killed %v runs\n", resp["killed"])
+			return nil
+		},
+	}
+	killRuns.Flags().IntVar(&killTimeout, "timeout", 10, "Seconds to wait after SIGTERM before SIGKILL")
+
+	// orbex jobs schedule preview <id>
+	scheduleCmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Inspect a job's cron schedule",
+	}
+	var previewCount int
+	preview := &cobra.Command{
+		Use:   "preview [job-id]",
+		Short: "Show the next times a job's schedule fires",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := apiGet(fmt.Sprintf("/jobs/%s/schedule/preview?count=%d", args[0], previewCount))
+			if err != nil {
+				return err
+			}
+			var resp struct {
+				Schedule string      `json:"schedule"`
+				Next     []time.Time `json:"next"`
+			}
+			json.Unmarshal(body, &resp)
+			fmt.Printf("Schedule: %s\n", resp.Schedule)
+			for _, t := range resp.Next {
+				fmt.Printf("  %s\n", t.Local().Format("Mon 2006-01-02 15:04 MST"))
+			}
+			return nil
+		},
+	}
+	preview.Flags().IntVar(&previewCount, "count", 5, "Number of fire times to show")
+	scheduleCmd.AddCommand(preview)
+
+	// orbex jobs trigger-all --tag <tag>
+	var triggerTag string
+	var dryRun bool
+	triggerAll := &cobra.Command{
+		Use:   "trigger-all",
+		Short: "Trigger a run of every active job with a tag",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := apiGet("/jobs?tag=" + url.QueryEscape(triggerTag))
+			if err != nil {
+				return err
+			}
+			var jobs []map[string]interface{}
+			json.Unmarshal(body, &jobs)
+			if len(jobs) == 0 {
+				fmt.Printf("No jobs tagged %q\n", triggerTag)
+				return nil
+			}
+
+			failed := 0
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "JOB\tNAME\tRUN")
+			for _, j := range jobs {
+				id, _ := j["id"].(string)
+				if active, _ := j["is_active"].(bool); !active {
+					fmt.Fprintf(w, "%s\t%s\tskipped (disabled)\n", truncID(id), j["name"])
+					continue
+				}
+				if dryRun {
+					fmt.Fprintf(w, "%s\t%s\twould trigger\n", truncID(id), j["name"])
+					continue
+				}
+				body, err := apiPost("/jobs/"+id+"/run", nil)
+				if err != nil {
+					failed++
+					fmt.Fprintf(w, "%s\t%s\tfailed: %v\n", truncID(id), j["name"], err)
+					continue
+				}
+				var run map[string]interface{}
+				json.Unmarshal(body, &run)
+				fmt.Fprintf(w, "%s\t%s\t%s\n", truncID(id), j["name"], run["id"])
+			}
+			w.Flush()
+			if failed > 0 {
+				return fmt.Errorf("%d of %d triggers failed", failed, len(jobs))
+			}
+			return nil
+		},
+	}
+	triggerAll.Flags().StringVar(&triggerTag, "tag", "", "Tag of the jobs to trigger (required)")
+	triggerAll.Flags().BoolVar(&dryRun, "dry-run", false, "List the jobs that would be triggered without triggering them")
+	triggerAll.MarkFlagRequired("tag")
+
+	cmd.AddCommand(list, create, get, del, enable, disable, clone, killRuns, triggerAll, scheduleCmd)
 	return cmd
 }
 
 // ─── Run (trigger) ────────────────────────────────────────────
 
 func runCmd() *cobra.Command {
-	return &cobra.Command{
+	var wait bool
+	var waitTimeout int
+	var labels, env []string
+	var envFile, memory, cpu string
+	cmd := &cobra.Command{
 		Use:   "run [job-id]",
 		Short: "Trigger a job run",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			body, err := apiPost("/jobs/"+args[0]+"/run", nil)
+			path := "/jobs/" + args[0] + "/run"
+			if wait {
+				path += "?wait=true"
+				if waitTimeout > 0 {
+					path += fmt.Sprintf("&timeout=%d", waitTimeout)
+				}
+			}
+			req := map[string]interface{}{}
+			if len(labels) > 0 {
+				labelMap := map[string]string{}
+				for _, l := range labels {
+					k, v, ok := strings.Cut(l, "=")
+					if !ok || k == "" {
+						return fmt.Errorf("invalid label %q (want key=value)", l)
+					}
+					labelMap[k] = v
+				}
+				req["labels"] = labelMap
+			}
+			envMap, err := parseEnv(envFile, env)
+			if err != nil {
+				return err
+			}
+			if len(envMap) > 0 {
+				req["env"] = envMap
+			}
+			if memory != "" {
+				req["memory"] = memory
+			}
+			if cpu != "" {
+				req["cpu"] = cpu
+			}
+			var payload interface{}
+			if len(req) > 0 {
+				payload = req
+			}
+			var spin *spinner
+			if wait {
+				spin = startSpinner(os.Stderr, "Waiting for run to finish...")
+			}
+			body, err := apiPost(path, payload)
+			spin.stop()
 			if err != nil {
 				return err
 			}
 			var run map[string]interface{}
 			json.Unmarshal(body, &run)
-			fmt.Printf("✓ Run triggered: %s (status: %s)\n", truncID(run["id"]), run["status"])
+			if !wait {
+				fmt.Printf("✓ Run triggered: %s (status: %s)\n", truncID(run["id"]), colorStatus(os.Stdout, run["status"]))
+				return nil
+			}
+
+			if logs, ok := run["logs_tail"].(string); ok && logs != "" {
+				fmt.Print(logs)
+			}
+			if !isTerminal(run["status"]) {
+				return fmt.Errorf("run %s still %s after waiting", truncID(run["id"]), run["status"])
+			}
+			fmt.Fprintf(os.Stderr, "Run %s finished: %s\n", truncID(run["id"]), colorStatus(os.Stderr, run["status"]))
+			exitForRun(run)
 			return nil
 		},
 	}
+	cmd.Flags().BoolVar(&wait, "wait", false, "Block until the run finishes and exit with its exit code")
+	cmd.Flags().IntVar(&waitTimeout, "wait-timeout", 0, "Max seconds to wait (server caps this)")
+	cmd.Flags().StringArrayVar(&labels, "label", nil, "Label to attach to the run as key=value (repeatable)")
+	cmd.Flags().StringArrayVar(&env, "env", nil, "Environment variable for this run as KEY=value (repeatable)")
+	cmd.Flags().StringVar(&envFile, "env-file", "", "Read environment variables for this run from a .env file")
+	cmd.Flags().StringVar(&memory, "memory", "", "Memory limit for this run (e.g. 2Gi), instead of the job's")
+	cmd.Flags().StringVar(&cpu, "cpu", "", "CPU limit for this run in cores (e.g. 2) or millicores (e.g. 1500m)")
+	return cmd
 }
 
 // ─── Runs ────────────────────────────────────────────
@@ -193,7 +500,7 @@ func runsCmd() *cobra.Command {
 			json.Unmarshal(body, &runs)
 
 			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-			fmt.Fprintln(w, "ID\tSTATUS\tEXIT\tDURATION\tCREATED")
+			fmt.Fprintln(w, "ID\tSTATUS\tSOURCE\tEXIT\tDURATION\tCREATED")
 			for _, r := range runs {
 				exit := "—"
 				if e, ok := r["exit_code"].(float64); ok {
@@ -203,8 +510,12 @@ func runsCmd() *cobra.Command {
 				if d, ok := r["duration_ms"].(float64); ok {
 					dur = fmt.Sprintf("%.1fs", d/1000)
 				}
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-					truncID(r["id"]), r["status"], exit, dur, truncTime(r["created_at"]))
+				source := "—"
+				if s, ok := r["trigger_source"].(string); ok {
+					source = s
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					truncID(r["id"]), colorStatus(os.Stdout, r["status"]), source, exit, dur, truncTime(r["created_at"]))
 			}
 			w.Flush()
 			return nil
@@ -224,49 +535,281 @@ func runsCmd() *cobra.Command {
 			var run map[string]interface{}
 			json.Unmarshal(body, &run)
 			printJSON(run)
+			exitForRun(run)
+			return nil
+		},
+	}
+
+	// orbex runs priority <run-id> <priority>
+	priority := &cobra.Command{
+		Use:   "priority [run-id] [priority]",
+		Short: "Change the queue priority of a pending run (higher runs first)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid priority %q", args[1])
+			}
+			if _, err := apiPatch("/runs/"+args[0]+"/priority", map[string]interface{}{"priority": p}); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Run %s priority set to %d\n", args[0], p)
+			return nil
+		},
+	}
+
+	// orbex runs watch <run-id>
+	var interval time.Duration
+	var withLogs bool
+	watch := &cobra.Command{
+		Use:   "watch [run-id]",
+		Short: "Follow a run until it finishes, then exit with its exit code",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runID := args[0]
+			printedLines := 0
+			lastStatus := ""
+			var spin *spinner
+			if !withLogs {
+				spin = startSpinner(os.Stdout, "Run "+truncID(runID))
+			}
+			defer spin.stop()
+			for {
+				body, err := apiGet("/runs/" + runID)
+				if err != nil {
+					return err
+				}
+				var run map[string]interface{}
+				json.Unmarshal(body, &run)
+
+				if withLogs {
+					if body, err := apiGet("/runs/" + runID + "/logs"); err == nil {
+						var data map[string]string
+						json.Unmarshal(body, &data)
+						lines := strings.SplitAfter(data["logs"], "\n")
+						if n := len(lines); n > 0 && lines[n-1] == "" {
+							lines = lines[:n-1]
+						}
+						for _, l := range lines[min(printedLines, len(lines)):] {
+							fmt.Print(l)
+						}
+						printedLines = max(printedLines, len(lines))
+					}
+					// Status goes to stderr on its own line so it doesn't mangle log output
+					if status := fmt.Sprint(run["status"]); status != lastStatus {
+						fmt.Fprintf(os.Stderr, "── %s\n", watchLine(os.Stderr, run))
+						lastStatus = status
+					}
+				} else {
+					spin.update(watchLine(os.Stdout, run))
+				}
+
+				if isTerminal(run["status"]) {
+					// Stop the spinner before exitForRun, whose os.Exit
+					// skips the deferred stop
+					spin.stop()
+					if !withLogs && spin.tty {
+						// Leave the final state on screen in place of the spinner
+						fmt.Println(watchLine(os.Stdout, run))
+					}
+					exitForRun(run)
+					return nil
+				}
+				time.Sleep(interval)
+			}
+		},
+	}
+	watch.Flags().DurationVar(&interval, "interval", 2*time.Second, "Polling interval")
+	watch.Flags().BoolVar(&withLogs, "logs", false, "Also stream the run's logs")
+
+	// orbex runs prune --job <job-id> [--status failed] [--older-than 7d]
+	var pruneJob, pruneStatus, pruneOlderThan string
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete a job's finished runs by status and/or age",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if pruneStatus == "" && pruneOlderThan == "" {
+				return fmt.Errorf("--status or --older-than is required")
+			}
+			q := url.Values{}
+			if pruneStatus != "" {
+				q.Set("status", pruneStatus)
+			}
+			if pruneOlderThan != "" {
+				q.Set("older_than", pruneOlderThan)
+			}
+			body, err := apiDelete("/jobs/" + pruneJob + "/runs?" + q.Encode())
+			if err != nil {
+				return err
+			}
+			var result map[string]int
+			json.Unmarshal(body, &result)
+			fmt.Printf("✓ Deleted %d runs\n", result["deleted"])
 			return nil
 		},
 	}
+	prune.Flags().StringVar(&pruneJob, "job", "", "Job whose runs to delete")
+	prune.Flags().StringVar(&pruneStatus, "status", "", "Only delete runs with this status: succeeded, failed or cancelled")
+	prune.Flags().StringVar(&pruneOlderThan, "older-than", "", "Only delete runs created longer ago than this (e.g. 7d or 12h)")
+	prune.MarkFlagRequired("job")
 
-	cmd.AddCommand(list, get)
+	cmd.AddCommand(list, get, priority, watch, prune)
 	return cmd
 }
 
+// watchLine renders a one-line run summary for runs watch, colored for f.
+func watchLine(f *os.File, run map[string]interface{}) string {
+	line := fmt.Sprintf("Run %s: %s", truncID(run["id"]), colorStatus(f, run["status"]))
+	if d, ok := run["duration_ms"].(float64); ok {
+		line += fmt.Sprintf("  %.1fs", d/1000)
+	} else if s, ok := run["started_at"].(string); ok {
+		if started, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			line += fmt.Sprintf("  %.0fs", time.Since(started).Seconds())
+		}
+	}
+	if e, ok := run["exit_code"].(float64); ok {
+		line += fmt.Sprintf("  exit %d", int(e))
+	}
+	return line
+}
+
+// statsSummary renders a job's run stats for jobs get.
+func statsSummary(stats map[string]interface{}) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Runs in the last %v: %v total, %v succeeded, %v failed, %v cancelled\n",
+		stats["window"], stats["total_runs"], stats["succeeded"], stats["failed"], stats["cancelled"])
+	if rate, ok := stats["success_rate"].(float64); ok {
+		fmt.Fprintf(&b, "  Success rate: %.1f%%\n", rate*100)
+	}
+	if p50, ok := stats["p50_duration_ms"].(float64); ok {
+		p95, _ := stats["p95_duration_ms"].(float64)
+		fmt.Fprintf(&b, "  Duration:     p50 %.1fs, p95 %.1fs\n", p50/1000, p95/1000)
+	}
+	if wait, ok := stats["avg_wait_ms"].(float64); ok {
+		fmt.Fprintf(&b, "  Avg wait:     %.1fs\n", wait/1000)
+	}
+	return b.String()
+}
+
 // ─── Logs ────────────────────────────────────────────
 
 func logsCmd() *cobra.Command {
-	return &cobra.Command{
+	var since, level, output string
+	cmd := &cobra.Command{
 		Use:   "logs [run-id]",
 		Short: "Get logs for a run",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			body, err := apiGet("/runs/" + args[0] + "/logs")
+			if output != "" {
+				return downloadLogs(args[0], output)
+			}
+			q := url.Values{}
+			if since != "" {
+				q.Set("since", since)
+			}
+			if level != "" {
+				q.Set("format", "json")
+				q.Set("level", level)
+			}
+			path := "/runs/" + args[0] + "/logs"
+			if len(q) > 0 {
+				path += "?" + q.Encode()
+			}
+			body, err := apiGet(path)
 			if err != nil {
 				return err
 			}
+			if level != "" {
+				var data struct {
+					Lines []struct {
+						Text string `json:"text"`
+					} `json:"lines"`
+					Message string `json:"message"`
+				}
+				json.Unmarshal(body, &data)
+				for _, line := range data.Lines {
+					fmt.Println(line.Text)
+				}
+				if data.Message != "" {
+					fmt.Fprintln(os.Stderr, data.Message)
+				}
+				return nil
+			}
 			var data map[string]string
 			json.Unmarshal(body, &data)
 			fmt.Print(data["logs"])
+			if data["message"] != "" {
+				fmt.Fprintln(os.Stderr, data["message"])
+			}
 			return nil
 		},
 	}
+	cmd.Flags().StringVar(&since, "since", "", "Only show logs written since a time (RFC 3339) or age (e.g. 10m, 2d)")
+	cmd.Flags().StringVar(&level, "level", "", "Only show JSON log lines at this level (e.g. error)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Save the run's complete logs to this file instead of printing them")
+	cmd.MarkFlagsMutuallyExclusive("output", "since")
+	cmd.MarkFlagsMutuallyExclusive("output", "level")
+	return cmd
+}
+
+// downloadLogs saves a run's logs to path, streaming them from the download
+// endpoint.
+func downloadLogs(runID, path string) error {
+	resp, err := apiStream("/runs/" + runID + "/logs/download")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("saving logs: %w", err)
+	}
+	fmt.Printf("✓ Saved %d bytes of logs to %s\n", n, path)
+	return nil
 }
 
 // ─── Pause / Resume / Kill ────────────────────────────
 
 func pauseCmd() *cobra.Command {
-	return &cobra.Command{
+	var timeout time.Duration
+	var onExpiry string
+	cmd := &cobra.Command{
 		Use: "pause [run-id]", Short: "Pause a running container",
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			_, err := apiPost("/runs/"+args[0]+"/pause", nil)
+			req := map[string]interface{}{}
+			if timeout > 0 {
+				req["timeout_seconds"] = int(timeout.Seconds())
+			}
+			if onExpiry != "" {
+				req["on_expiry"] = onExpiry
+			}
+			var payload interface{}
+			if len(req) > 0 {
+				payload = req
+			}
+			body, err := apiPost("/runs/"+args[0]+"/pause", payload)
 			if err != nil {
 				return err
 			}
-			fmt.Println("✓ Run paused")
+			var resp map[string]string
+			json.Unmarshal(body, &resp)
+			fmt.Printf("✓ Run paused (%s at %s)\n", resp["on_expiry"], resp["pause_expires_at"])
 			return nil
 		},
 	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "How long to pause (e.g. 30m); default is the server's maximum")
+	cmd.Flags().StringVar(&onExpiry, "on-expiry", "", "What happens when the pause runs out: resume or kill")
+	return cmd
 }
 
 func resumeCmd() *cobra.Command {
@@ -285,18 +828,138 @@ func resumeCmd() *cobra.Command {
 }
 
 func killCmd() *cobra.Command {
-	return &cobra.Command{
+	var timeout int
+	cmd := &cobra.Command{
 		Use: "kill [run-id]", Short: "Kill a running container",
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			_, err := apiPost("/runs/"+args[0]+"/kill", nil)
+			var payload interface{}
+			if cmd.Flags().Changed("timeout") {
+				payload = map[string]interface{}{"timeout_seconds": timeout}
+			}
+			body, err := apiPost("/runs/"+args[0]+"/kill", payload)
 			if err != nil {
 				return err
 			}
+			var resp map[string]interface{}
+			json.Unmarshal(body, &resp)
+			if sig, ok := resp["stop_signal"].(string); ok {
+				fmt.Printf("✓ Run killed (%s)\n", sig)
+				return nil
+			}
 			fmt.Println("✓ Run killed")
 			return nil
 		},
 	}
+	cmd.Flags().IntVar(&timeout, "timeout", 10, "Seconds to wait after SIGTERM before SIGKILL")
+	return cmd
+}
+
+// ─── Events ──────────────────────────────────────────
+
+func eventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Follow job and run lifecycle events",
+	}
+
+	// orbex events tail [--job <job-id>]
+	var jobID string
+	var asJSON bool
+	tail := &cobra.Command{
+		Use:   "tail",
+		Short: "Print run events live until interrupted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "/events"
+			if jobID != "" {
+				path += "?job_id=" + url.QueryEscape(jobID)
+			}
+			resp, err := apiStream(path)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			scanner := bufio.NewScanner(resp.Body)
+			scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+				if !ok {
+					continue
+				}
+				if asJSON {
+					fmt.Println(data)
+					continue
+				}
+				var ev map[string]interface{}
+				if json.Unmarshal([]byte(data), &ev) != nil {
+					continue
+				}
+				fmt.Println(eventLine(os.Stdout, ev))
+			}
+			if err := scanner.Err(); err != nil {
+				return fmt.Errorf("event stream closed: %w", err)
+			}
+			return nil
+		},
+	}
+	tail.Flags().StringVar(&jobID, "job", "", "Only show events for this job ID")
+	tail.Flags().BoolVar(&asJSON, "json", false, "Print each event as raw JSON")
+
+	cmd.AddCommand(tail)
+	return cmd
+}
+
+// eventLine renders a run event for events tail, colored for f.
+func eventLine(f *os.File, ev map[string]interface{}) string {
+	line := fmt.Sprintf("%s  %-20s  run %s  %s",
+		time.Now().Format("15:04:05"), ev["job_name"], truncID(ev["id"]), colorStatus(f, ev["status"]))
+	if d, ok := ev["status_detail"].(string); ok {
+		line += "  " + d
+	}
+	if e, ok := ev["exit_code"].(float64); ok {
+		line += fmt.Sprintf("  exit %d", int(e))
+	}
+	if r, ok := ev["failure_reason"].(string); ok {
+		line += "  (" + r + ")"
+	}
+	return line
+}
+
+// ─── Version ─────────────────────────────────────────
+
+func versionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the CLI and server versions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Printf("Client: %s\n", versionLine(version.Get()))
+
+			body, err := apiGet("/version")
+			if err != nil {
+				return fmt.Errorf("querying server version: %w", err)
+			}
+			var server version.Info
+			json.Unmarshal(body, &server)
+			fmt.Printf("Server: %s\n", versionLine(server))
+			return nil
+		},
+	}
+}
+
+// versionLine renders build info on one line.
+func versionLine(v version.Info) string {
+	line := v.Version
+	if v.Commit != "" {
+		line += " (commit " + truncID(v.Commit)
+		if v.BuildTime != "" {
+			line += ", built " + v.BuildTime
+		}
+		line += ")"
+	}
+	return line + " " + v.GoVersion
 }
 
 // ─── HTTP Helpers ────────────────────────────────────
@@ -309,6 +972,10 @@ func apiPost(path string, payload interface{}) ([]byte, error) {
 	return apiRequest("POST", path, payload)
 }
 
+func apiPatch(path string, payload interface{}) ([]byte, error) {
+	return apiRequest("PATCH", path, payload)
+}
+
 func apiDelete(path string) ([]byte, error) {
 	return apiRequest("DELETE", path, nil)
 }
@@ -320,11 +987,29 @@ func apiRequest(method, path string, payload interface{}) ([]byte, error) {
 		body = bytes.NewReader(data)
 	}
 
+	resp, err := apiDo(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// apiStream opens a long-lived GET such as a server-sent event stream. The
+// caller reads and closes the response body.
+func apiStream(path string) (*http.Response, error) {
+	return apiDo("GET", path, nil)
+}
+
+// apiDo sends an authenticated request and turns error statuses into errors.
+func apiDo(method, path string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequest(method, apiURL+path, body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "orbex-cli")
 	if apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+apiKey)
 	}
@@ -333,11 +1018,10 @@ func apiRequest(method, path string, payload interface{}) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("connection failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
 		var apiErr map[string]string
 		json.Unmarshal(respBody, &apiErr)
 		msg := apiErr["message"]
@@ -347,7 +1031,7 @@ func apiRequest(method, path string, payload interface{}) ([]byte, error) {
 		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, msg)
 	}
 
-	return respBody, nil
+	return resp, nil
 }
 
 // ─── Format Helpers ────────────────────────────────────
@@ -368,11 +1052,180 @@ func truncTime(v interface{}) string {
 	return s
 }
 
+// useColor reports whether output written to f may be colored: f is a
+// terminal and NO_COLOR (https://no-color.org) isn't set.
+func useColor(f *os.File) bool {
+	return os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isTTY(f)
+}
+
+func isTTY(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Status colors. Every code, including the default for uncolored statuses,
+// is the same length so tabwriter columns stay aligned.
+const (
+	colorGreen   = "\033[32m"
+	colorRed     = "\033[31m"
+	colorYellow  = "\033[33m"
+	colorDefault = "\033[39m"
+	colorReset   = "\033[0m"
+)
+
+// colorStatus renders a run status, colored when f is a terminal.
+func colorStatus(f *os.File, status interface{}) string {
+	return paintStatus(f, status, fmt.Sprint(status))
+}
+
+// paintStatus renders text in the color for status when f is a terminal:
+// green for succeeded, red for failed, yellow for running.
+func paintStatus(f *os.File, status interface{}, text string) string {
+	if !useColor(f) {
+		return text
+	}
+	color := colorDefault
+	switch status {
+	case "succeeded":
+		color = colorGreen
+	case "failed":
+		color = colorRed
+	case "running":
+		color = colorYellow
+	}
+	return color + text + colorReset
+}
+
+// spinner redraws a status line with an animated frame on a terminal. On
+// anything else it prints each distinct line once instead. A nil *spinner
+// is a no-op.
+type spinner struct {
+	f    *os.File
+	tty  bool
+	mu   sync.Mutex
+	text string
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// startSpinner starts a spinner on f showing text.
+func startSpinner(f *os.File, text string) *spinner {
+	s := &spinner{f: f, tty: isTTY(f), done: make(chan struct{})}
+	if !s.tty {
+		s.update(text)
+		return s
+	}
+	s.text = text
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			s.mu.Lock()
+			fmt.Fprintf(s.f, "\r\033[K%s %s", spinnerFrames[i%len(spinnerFrames)], s.text)
+			s.mu.Unlock()
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return s
+}
+
+// update replaces the text shown next to the spinner.
+func (s *spinner) update(text string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.tty && text != s.text {
+		fmt.Fprintln(s.f, text)
+	}
+	s.text = text
+}
+
+// stop halts the spinner and clears its line. It is safe to call twice.
+func (s *spinner) stop() {
+	if s == nil || !s.tty {
+		return
+	}
+	s.once.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		fmt.Fprint(s.f, "\r\033[K")
+	})
+}
+
+// isTerminal reports whether a run status from the API is final.
+func isTerminal(status interface{}) bool {
+	switch status {
+	case "succeeded", "failed", "cancelled":
+		return true
+	}
+	return false
+}
+
+// runExitCode maps a finished run to a process exit code: the container's
+// own exit code when it has one, otherwise 0 for success and 1 for failure.
+// Codes outside 1-255 become 1 so they can't wrap around to success.
+func runExitCode(run map[string]interface{}) int {
+	if e, ok := run["exit_code"].(float64); ok && e != 0 {
+		if e < 1 || e > 255 {
+			return 1
+		}
+		return int(e)
+	}
+	if run["status"] == "succeeded" {
+		return 0
+	}
+	return 1
+}
+
+// exitForRun exits the process with the run's exit code if it finished
+// unsuccessfully, so CI pipelines and `set -e` see the failure. Runs that
+// succeeded or are still in flight leave the process exit status alone.
+func exitForRun(run map[string]interface{}) {
+	if !isTerminal(run["status"]) {
+		return
+	}
+	if code := runExitCode(run); code != 0 {
+		os.Exit(code)
+	}
+}
+
 func printJSON(v interface{}) {
 	data, _ := json.MarshalIndent(v, "", "  ")
 	fmt.Println(string(data))
 }
 
+// parseEnv merges variables from a .env file with KEY=value pairs given on
+// the command line; the command line wins on conflicts.
+func parseEnv(file string, pairs []string) (map[string]string, error) {
+	env := map[string]string{}
+	if file != "" {
+		vars, err := godotenv.Read(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading env file %s: %w", file, err)
+		}
+		env = vars
+	}
+	for _, p := range pairs {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid env %q (want KEY=value)", p)
+		}
+		env[k] = v
+	}
+	return env, nil
+}
+
 func envOrDefault(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/orbex-dev/orbex/internal/api"
+	"github.com/orbex-dev/orbex/internal/config"
+	"github.com/orbex-dev/orbex/internal/database"
+)
+
+// createKey implements "orbex-server create-key --email <email>": it mints an
+// API key for a user directly in the database, for recovering an account
+// whose keys have all been lost. It returns the process exit code.
+func createKey(args []string) int {
+	fs := flag.NewFlagSet("create-key", flag.ContinueOnError)
+	email := fs.String("email", "", "Email of the user to create the key for (required)")
+	name := fs.String("name", "recovery", "Name for the new key")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *email == "" {
+		fmt.Fprintln(os.Stderr, "usage: orbex-server create-key --email <email> [--name <name>]")
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	db, err := database.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	key, err := api.MintAPIKey(ctx, db, *email, *name)
+	if errors.Is(err, api.ErrUserNotFound) {
+		fmt.Fprintf(os.Stderr, "No user with email %s\n", *email)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create key: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("✓ Created API key %q (%s) for %s\n", key.Name, key.Prefix, *email)
+	fmt.Println(key.Key)
+	fmt.Println("Store it now — it won't be shown again.")
+	return 0
+}
@@ -15,14 +15,26 @@ import (
 	"github.com/orbex-dev/orbex/internal/config"
 	"github.com/orbex-dev/orbex/internal/database"
 	"github.com/orbex-dev/orbex/internal/docker"
+	"github.com/orbex-dev/orbex/internal/logstore"
 	"github.com/orbex-dev/orbex/internal/storage"
+	"github.com/orbex-dev/orbex/internal/version"
 	"github.com/orbex-dev/orbex/internal/worker"
 )
 
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	if len(os.Args) > 1 && os.Args[1] == "create-key" {
+		os.Exit(createKey(os.Args[2:]))
+	}
+
 	log.Println("🚀 Orbex — Run anything. Know everything.")
 	log.Println("─────────────────────────────────────────")
+	if v := version.Get(); v.Commit != "" {
+		log.Printf("Version: %s (%s, %s)", v.Version, v.Commit, v.GoVersion)
+	} else {
+		log.Printf("Version: %s (%s)", v.Version, v.GoVersion)
+	}
 
 	// Load configuration
 	cfg, err := config.Load()
@@ -52,13 +64,38 @@ func main() {
 	}
 	log.Println("✓ Migrations complete")
 
+	// Seal plaintext envs, and those sealed with an older key, with the active key
+	if cfg.EnvKeys != nil {
+		n, err := cfg.EnvKeys.Rotate(ctx, db)
+		if err != nil {
+			log.Fatalf("Failed to encrypt job env: %v", err)
+		}
+		log.Printf("✓ Job env encrypted with key %q (%d jobs re-encrypted)", cfg.EnvKeys.ActiveID(), n)
+	}
+
 	// Connect to Docker
 	log.Println("Connecting to Docker...")
-	dockerClient, err := docker.New()
+	dockerTLS := docker.TLSFiles{
+		CACertFile: cfg.DockerTLSCACertFile,
+		CertFile:   cfg.DockerTLSCertFile,
+		KeyFile:    cfg.DockerTLSKeyFile,
+	}
+	dockerClient, err := docker.New(cfg.DockerHost, dockerTLS)
 	if err != nil {
 		log.Fatalf("Failed to connect to Docker: %v", err)
 	}
 	defer dockerClient.Close()
+	dockerClient.PullTimeout = cfg.ImagePullTimeout
+	dockerClient.MaxConcurrentPulls = cfg.MaxConcurrentPulls
+	remotes := make(map[string]docker.RemoteHost, len(cfg.DockerHosts))
+	for name, h := range cfg.DockerHosts {
+		remotes[name] = docker.RemoteHost{Addr: h.Addr, TLS: h.TLS}
+	}
+	dockerHosts, err := docker.NewHosts(dockerClient, remotes, dockerTLS)
+	if err != nil {
+		log.Fatalf("Failed to connect to Docker: %v", err)
+	}
+	defer dockerHosts.Close()
 	log.Println("✓ Docker connected")
 
 	// Connect to MinIO storage
@@ -75,21 +112,59 @@ func main() {
 	}
 	log.Println("✓ MinIO connected")
 
+	logStore, err := logstore.New(cfg.LogStore, db, storageClient)
+	if err != nil {
+		log.Fatalf("Failed to set up log store: %v", err)
+	}
+
 	// Start background worker
-	w := worker.New(db, dockerClient, storageClient, worker.Config{
-		MaxConcurrent: cfg.MaxConcurrentRuns,
-		PollInterval:  time.Second,
+	w := worker.New(db, dockerHosts, storageClient, logStore, worker.Config{
+		MaxConcurrent:    cfg.MaxConcurrentRuns,
+		PollInterval:     cfg.WorkerPollInterval,
+		MaxPollInterval:  cfg.WorkerMaxPollInterval,
+		ClaimBatchSize:   cfg.WorkerClaimBatchSize,
+		RetentionDays:    cfg.RunRetentionDays,
+		RetentionMaxRuns: cfg.RunRetentionMaxRuns,
+		LogRetentionDays: cfg.LogRetentionDays,
+
+		HeartbeatInterval: cfg.HeartbeatInterval,
+		ReaperInterval:    cfg.ReaperInterval,
+		StaleThreshold:    cfg.StaleThreshold,
+
+		BlockNetworkByDefault: cfg.BlockNetworkByDefault,
+		MaxStoredLogBytes:     cfg.MaxStoredLogBytes,
+		KeepFailedContainers:  cfg.KeepFailedContainers,
+		KeptContainerTTL:      cfg.KeptContainerTTL,
+		MaxPauseDuration:      cfg.MaxPauseDuration,
+		PauseExpiryAction:     cfg.PauseExpiryAction,
+		EnvKeys:               cfg.EnvKeys,
+		DefaultEnv:            cfg.DefaultRunEnv,
+		AllowedCapabilities:   cfg.AllowedCapabilities,
+		SMTP: worker.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		},
 	})
 
+	if cfg.MaintenanceMode {
+		if err := w.SetMaintenance(ctx, true); err != nil {
+			log.Fatalf("Failed to enter maintenance mode: %v", err)
+		}
+	}
+
 	workerCtx, workerCancel := context.WithCancel(ctx)
 	go w.Run(workerCtx)
 	go w.RunReaper(workerCtx)
 	go w.RunScheduler(workerCtx)
 	go w.RunBuilder(workerCtx)
+	go w.RunRetention(workerCtx)
 	log.Printf("✓ Worker started (maxConcurrent=%d)", cfg.MaxConcurrentRuns)
 
 	// Create router
-	router := api.NewRouter(db, dockerClient, storageClient, cfg)
+	router := api.NewRouter(db, dockerHosts, storageClient, logStore, w, cfg)
 
 	// Create HTTP server
 	srv := &http.Server{
@@ -105,10 +180,20 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		log.Printf("✓ Server listening on %s", cfg.Addr())
+		scheme := "http"
+		if cfg.TLSEnabled() {
+			scheme = "https"
+		}
+		log.Printf("✓ Server listening on %s (%s)", cfg.Addr(), scheme)
 		log.Println("─────────────────────────────────────────")
 		log.Println("Routes:")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if cfg.TLSEnabled() {
+			err = srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
@@ -116,7 +201,7 @@ func main() {
 	<-quit
 	log.Println("\nShutting down gracefully...")
 
-	// Stop worker first
+	// Stop claiming new runs first; in-flight runs get the grace period to finish
 	workerCancel()
 	w.Shutdown(30 * time.Second)
 
@@ -185,9 +185,9 @@ export default function JobDetailPage() {
         } catch (err) { setToast({ message: `Failed: ${err}`, type: 'error' }); }
     }
 
-    async function handleGenerateWebhook() {
+    async function handleGenerateWebhook(regenerate = false) {
         try {
-            const result = await api.generateWebhook(jobId);
+            const result = await api.generateWebhook(jobId, regenerate);
             setWebhookData({ token: result.webhook_token, triggerUrl: result.trigger_url });
             loadData();
         } catch (err) { setToast({ message: `Failed: ${err}`, type: 'error' }); }
@@ -364,11 +364,11 @@ export default function JobDetailPage() {
                             <code className="block text-[10px] text-orange-400/70 font-mono truncate mb-2">{fullWebhookUrl}</code>
                             <div className="flex gap-2">
                                 <button onClick={() => navigator.clipboard.writeText(fullWebhookUrl)} className="btn-ghost btn-sm flex-1 justify-center">Copy URL</button>
-                                <button onClick={handleGenerateWebhook} className="btn-ghost btn-sm flex-1 justify-center">Regenerate</button>
+                                <button onClick={() => handleGenerateWebhook(true)} className="btn-ghost btn-sm flex-1 justify-center">Regenerate</button>
                             </div>
                         </div>
                     ) : (
-                        <button onClick={handleGenerateWebhook} className="btn-ghost btn-sm w-full justify-center">Generate Webhook</button>
+                        <button onClick={() => handleGenerateWebhook()} className="btn-ghost btn-sm w-full justify-center">Generate Webhook</button>
                     )}
                 </div>
                 {/* API */}
@@ -166,8 +166,9 @@ export const api = {
     apiFetch<{ logs: string }>(`/runs/${runId}/logs`),
 
   // Webhooks
-  generateWebhook: (jobId: string) =>
-    apiFetch<{ webhook_token: string; trigger_url: string }>(`/jobs/${jobId}/webhook`, { method: 'POST' }),
+  generateWebhook: (jobId: string, regenerate = false) =>
+    apiFetch<{ webhook_token: string; trigger_url: string; created: boolean }>(
+      `/jobs/${jobId}/webhook${regenerate ? '?regenerate=true' : ''}`, { method: 'POST' }),
 
   // API Keys (for settings page — still needed for programmatic access)
   generateApiKey: (email: string, password: string) =>
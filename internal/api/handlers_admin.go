@@ -0,0 +1,241 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/orbex-dev/orbex/internal/database"
+	"github.com/orbex-dev/orbex/internal/models"
+	"github.com/orbex-dev/orbex/internal/worker"
+)
+
+// WorkerControl is the part of the worker the admin endpoints operate on.
+type WorkerControl interface {
+	Drain()
+	Resume()
+	Draining() bool
+	SetMaintenance(ctx context.Context, on bool) error
+	Maintenance(ctx context.Context) (bool, error)
+	ActiveRuns() int
+	UnlockQueueItem(ctx context.Context, queueID uuid.UUID) (models.QueueItem, error)
+}
+
+// AdminHandler serves operator endpoints guarded by AdminMiddleware.
+type AdminHandler struct {
+	db     *database.DB
+	worker WorkerControl
+}
+
+// NewAdminHandler creates a new AdminHandler.
+func NewAdminHandler(db *database.DB, worker WorkerControl) *AdminHandler {
+	return &AdminHandler{db: db, worker: worker}
+}
+
+// WorkerStatus reports whether the worker is draining and how many runs it
+// still has in flight.
+func (h *AdminHandler) WorkerStatus(w http.ResponseWriter, r *http.Request) {
+	h.writeStatus(w, r)
+}
+
+// Drain stops the worker from claiming new runs while in-flight runs finish.
+// Poll WorkerStatus until active_runs reaches 0 before stopping the server.
+func (h *AdminHandler) Drain(w http.ResponseWriter, r *http.Request) {
+	h.worker.Drain()
+	h.writeStatus(w, r)
+}
+
+// Resume lets a drained worker claim runs again.
+func (h *AdminHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	h.worker.Resume()
+	h.writeStatus(w, r)
+}
+
+// EnableMaintenance puts every server on the database in maintenance mode:
+// run triggers are refused with 503 and the scheduler stops enqueueing,
+// while reads keep working.
+func (h *AdminHandler) EnableMaintenance(w http.ResponseWriter, r *http.Request) {
+	h.setMaintenance(w, r, true)
+}
+
+// DisableMaintenance ends maintenance mode.
+func (h *AdminHandler) DisableMaintenance(w http.ResponseWriter, r *http.Request) {
+	h.setMaintenance(w, r, false)
+}
+
+func (h *AdminHandler) setMaintenance(w http.ResponseWriter, r *http.Request, on bool) {
+	if err := h.worker.SetMaintenance(r.Context(), on); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to set maintenance mode",
+		})
+		return
+	}
+	h.writeStatus(w, r)
+}
+
+// UnlockQueueItem releases a queue item stuck in the picked state, e.g. after
+// its worker crashed, so its run is claimed again. Runs that have already
+// started are refused with 409. The reaper does this on its own for items
+// picked more than a couple of minutes ago; this is for not waiting on it.
+func (h *AdminHandler) UnlockQueueItem(w http.ResponseWriter, r *http.Request) {
+	queueID, err := uuid.Parse(chi.URLParam(r, "queueID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid queue item ID",
+		})
+		return
+	}
+
+	item, err := h.worker.UnlockQueueItem(r.Context(), queueID)
+	switch {
+	case errors.Is(err, worker.ErrQueueItemNotFound):
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Queue item not found",
+		})
+	case errors.Is(err, worker.ErrQueueItemStarted):
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{
+			Error: "invalid_state", Message: err.Error(),
+		})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to unlock queue item",
+		})
+	default:
+		writeJSON(w, http.StatusOK, item)
+	}
+}
+
+func (h *AdminHandler) writeStatus(w http.ResponseWriter, r *http.Request) {
+	maintenance, err := h.worker.Maintenance(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to read maintenance mode",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, models.WorkerStatus{
+		Draining:    h.worker.Draining(),
+		Maintenance: maintenance,
+		ActiveRuns:  h.worker.ActiveRuns(),
+	})
+}
+
+// ListRuns returns runs across all users, newest first, listRunsLimit at a
+// time, optionally filtered by ?status=, ?user_id= and ?job_id=. Paging
+// works as in RunHandler.ListRuns. Unlike the user-facing endpoints it is
+// not scoped to the caller, so it can show a stuck worker or a runaway job.
+func (h *AdminHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var status *models.RunStatus
+	if v := q.Get("status"); v != "" {
+		s := models.RunStatus(v)
+		if !validRunStatus(s) {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid_request", Message: "status must be one of: pending, running, succeeded, failed, paused, cancelled",
+			})
+			return
+		}
+		status = &s
+	}
+	userID, err := optionalUUID(q.Get("user_id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid user_id",
+		})
+		return
+	}
+	jobID, err := optionalUUID(q.Get("job_id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid job_id",
+		})
+		return
+	}
+	var before *time.Time
+	var beforeID uuid.UUID
+	if v := q.Get("before"); v != "" {
+		t, id, err := parseRunCursor(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid_request", Message: err.Error(),
+			})
+			return
+		}
+		before, beforeID = &t, id
+	}
+
+	const filter = `
+		WHERE ($1::run_status IS NULL OR status = $1)
+		  AND ($2::uuid IS NULL OR user_id = $2)
+		  AND ($3::uuid IS NULL OR job_id = $3)`
+	rows, err := h.db.Pool.Query(r.Context(), `
+		SELECT id, job_id, user_id, status, container_id, exit_code, error_message,
+		       started_at, finished_at, paused_at, heartbeat_at, duration_ms, stop_signal, failure_reason,
+		       docker_host, labels, trigger_source, attempt, version, `+runWaitMsColumn+`, created_at
+		FROM job_runs`+filter+`
+		  AND ($4::timestamptz IS NULL OR (created_at, id) < ($4, $5))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $6
+	`, status, userID, jobID, before, beforeID, listRunsLimit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to list runs",
+		})
+		return
+	}
+	defer rows.Close()
+
+	runs := []models.JobRun{}
+	for rows.Next() {
+		var run models.JobRun
+		if err := rows.Scan(
+			&run.ID, &run.JobID, &run.UserID, &run.Status, &run.ContainerID,
+			&run.ExitCode, &run.ErrorMessage, &run.StartedAt, &run.FinishedAt,
+			&run.PausedAt, &run.HeartbeatAt, &run.DurationMs, &run.StopSignal, &run.FailureReason,
+			&run.DockerHost, &run.Labels, &run.TriggerSource, &run.Attempt, &run.Version, &run.WaitMs, &run.CreatedAt,
+		); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	var page models.Pagination
+	if wantsEnvelope(r) {
+		if err := h.db.Pool.QueryRow(r.Context(), `SELECT count(*) FROM job_runs`+filter,
+			status, userID, jobID).Scan(&page.Total); err != nil {
+			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+				Error: "internal_error", Message: "Failed to list runs",
+			})
+			return
+		}
+		if len(runs) == listRunsLimit {
+			page.NextCursor = keysetCursor(runs[len(runs)-1].CreatedAt, runs[len(runs)-1].ID)
+		}
+	}
+	writeList(w, r, runs, page)
+}
+
+// optionalUUID parses a UUID query value, returning nil when it is empty.
+func optionalUUID(v string) (*uuid.UUID, error) {
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// validRunStatus reports whether s is a known run status.
+func validRunStatus(s models.RunStatus) bool {
+	switch s {
+	case models.RunStatusPending, models.RunStatusRunning, models.RunStatusSucceeded,
+		models.RunStatusFailed, models.RunStatusPaused, models.RunStatusCancelled:
+		return true
+	}
+	return false
+}
@@ -1,15 +1,20 @@
 package api
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/orbex-dev/orbex/internal/config"
 	"github.com/orbex-dev/orbex/internal/database"
 	"github.com/orbex-dev/orbex/internal/models"
 	"golang.org/x/crypto/bcrypt"
@@ -17,21 +22,31 @@ import (
 
 // AuthHandler handles user registration and API key management.
 type AuthHandler struct {
-	db *database.DB
+	db             *database.DB
+	jwt            *JWTSigner
+	refreshTTL     time.Duration
+	bcryptCost     int
+	passwordPolicy PasswordPolicy
 }
 
 // NewAuthHandler creates a new AuthHandler.
-func NewAuthHandler(db *database.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db *database.DB, cfg *config.Config, jwt *JWTSigner) *AuthHandler {
+	return &AuthHandler{
+		db:         db,
+		jwt:        jwt,
+		refreshTTL: cfg.JWTRefreshTTL,
+		bcryptCost: cfg.BcryptCost,
+		passwordPolicy: PasswordPolicy{
+			MinLength:      cfg.PasswordMinLength,
+			MinCharClasses: cfg.PasswordMinCharClasses,
+		},
+	}
 }
 
 // Register creates a new user account.
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req models.RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_request", Message: "Invalid JSON body",
-		})
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
@@ -42,15 +57,15 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(req.Password) < 8 {
+	if msg := h.passwordPolicy.Validate(req.Password); msg != "" {
 		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Error: "validation_error", Message: "Password must be at least 8 characters",
+			Error: "validation_error", Message: msg,
 		})
 		return
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), h.bcryptCost)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
 			Error: "internal_error", Message: "Failed to process registration",
@@ -67,9 +82,14 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	`, req.Email, string(hashedPassword)).Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
-		// Check for duplicate email
-		writeJSON(w, http.StatusConflict, models.ErrorResponse{
-			Error: "conflict", Message: "Email already registered",
+		if isDuplicateError(err) {
+			writeJSON(w, http.StatusConflict, models.ErrorResponse{
+				Error: "conflict", Message: "Email already registered",
+			})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to create account",
 		})
 		return
 	}
@@ -91,7 +111,9 @@ func (h *AuthHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		Name string `json:"name"`
 	}
-	_ = json.NewDecoder(r.Body).Decode(&body)
+	if r.ContentLength != 0 && !decodeJSON(w, r, &body) {
+		return
+	}
 	if body.Name == "" {
 		body.Name = "default"
 	}
@@ -127,16 +149,106 @@ func (h *AuthHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, apiKey)
 }
 
+// KeyUsage returns the sampled usage log for one of the user's API keys,
+// newest first. Page with ?before=<next_cursor> and narrow
+// with ?ip=; ?envelope=true adds the total and the next page's cursor.
+func (h *AuthHandler) KeyUsage(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	keyID, err := uuid.Parse(chi.URLParam(r, "keyID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid key ID",
+		})
+		return
+	}
+
+	q := r.URL.Query()
+	limit := 50
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 200 {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid_request", Message: "limit must be between 1 and 200",
+			})
+			return
+		}
+		limit = n
+	}
+	// A bare timestamp gets ID 0, below every usage ID, so it pages as
+	// used_at < before did
+	before, beforeID := time.Now(), int64(0)
+	if v := q.Get("before"); v != "" {
+		t, id, err := parseCursor(v)
+		if err == nil && id != "" {
+			beforeID, err = strconv.ParseInt(id, 10, 64)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid_request", Message: errInvalidCursor.Error(),
+			})
+			return
+		}
+		before = t
+	}
+
+	var exists bool
+	if err := h.db.Pool.QueryRow(r.Context(),
+		`SELECT EXISTS(SELECT 1 FROM api_keys WHERE id = $1 AND user_id = $2)`, keyID, user.ID,
+	).Scan(&exists); err != nil || !exists {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "API key not found",
+		})
+		return
+	}
+
+	rows, err := h.db.Pool.Query(r.Context(), `
+		SELECT id, key_id, ip, method, path, user_agent, used_at
+		FROM api_key_usage
+		WHERE key_id = $1 AND (used_at, id) < ($2, $5) AND ($3 = '' OR ip = $3)
+		ORDER BY used_at DESC, id DESC
+		LIMIT $4
+	`, keyID, before, q.Get("ip"), limit, beforeID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to load key usage",
+		})
+		return
+	}
+	defer rows.Close()
+
+	usage := []models.APIKeyUsage{}
+	for rows.Next() {
+		var u models.APIKeyUsage
+		if err := rows.Scan(&u.ID, &u.KeyID, &u.IP, &u.Method, &u.Path, &u.UserAgent, &u.UsedAt); err != nil {
+			continue
+		}
+		usage = append(usage, u)
+	}
+
+	var page models.Pagination
+	if wantsEnvelope(r) {
+		if err := h.db.Pool.QueryRow(r.Context(), `
+			SELECT count(*) FROM api_key_usage WHERE key_id = $1 AND ($2 = '' OR ip = $2)
+		`, keyID, q.Get("ip")).Scan(&page.Total); err != nil {
+			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+				Error: "internal_error", Message: "Failed to load key usage",
+			})
+			return
+		}
+		if len(usage) == limit {
+			page.NextCursor = keysetCursor(usage[len(usage)-1].UsedAt, usage[len(usage)-1].ID)
+		}
+	}
+	writeList(w, r, usage, page)
+}
+
 // Login validates credentials and creates a session with an httpOnly cookie.
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_request", Message: "Invalid JSON body",
-		})
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
@@ -168,6 +280,20 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Issue tokens before the session so a failure here doesn't leave the
+	// client holding a cookie for a login that returned 500
+	var resp models.LoginResponse
+	_ = h.db.Pool.QueryRow(r.Context(), `
+		SELECT id, email, created_at, updated_at FROM users WHERE id = $1
+	`, userID).Scan(&resp.ID, &resp.Email, &resp.CreatedAt, &resp.UpdatedAt)
+
+	if err := h.issueTokens(r.Context(), userID, &resp); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to create session",
+		})
+		return
+	}
+
 	// Generate session token
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
@@ -204,17 +330,104 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		MaxAge:   7 * 24 * 60 * 60, // 7 days
 	})
 
-	// Return user info
-	var user models.User
-	_ = h.db.Pool.QueryRow(r.Context(), `
+	// Return user info plus tokens for clients that don't use the cookie
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Refresh exchanges a refresh token for a new access token. The refresh
+// token is single-use: it is rotated and the old one revoked.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.RefreshToken == "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "validation_error", Message: "refresh_token is required",
+		})
+		return
+	}
+
+	var userID uuid.UUID
+	var expiresAt time.Time
+	err := h.db.Pool.QueryRow(r.Context(), `
+		DELETE FROM refresh_tokens WHERE token_hash = $1
+		RETURNING user_id, expires_at
+	`, hashToken(req.RefreshToken)).Scan(&userID, &expiresAt)
+	if err != nil || time.Now().After(expiresAt) {
+		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{
+			Error: "unauthorized", Message: "Invalid or expired refresh token",
+		})
+		return
+	}
+
+	var resp models.LoginResponse
+	err = h.db.Pool.QueryRow(r.Context(), `
 		SELECT id, email, created_at, updated_at FROM users WHERE id = $1
-	`, userID).Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	`, userID).Scan(&resp.ID, &resp.Email, &resp.CreatedAt, &resp.UpdatedAt)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{
+			Error: "unauthorized", Message: "Invalid or expired refresh token",
+		})
+		return
+	}
 
-	writeJSON(w, http.StatusOK, user)
+	if err := h.issueTokens(r.Context(), userID, &resp); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to refresh token",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// issueTokens fills resp with a new access token and a stored refresh token.
+func (h *AuthHandler) issueTokens(ctx context.Context, userID uuid.UUID, resp *models.LoginResponse) error {
+	accessToken, expiresAt, err := h.jwt.Issue(userID)
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	refreshToken := hex.EncodeToString(b)
+	if _, err := h.db.Pool.Exec(ctx, `
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, hashToken(refreshToken), time.Now().Add(h.refreshTTL)); err != nil {
+		return err
+	}
+
+	resp.AccessToken = accessToken
+	resp.RefreshToken = refreshToken
+	resp.ExpiresAt = expiresAt
+	return nil
+}
+
+// hashToken returns the hex SHA-256 of a bearer secret for storage.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // Logout deletes the session and clears the cookie.
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	// Token clients revoke their refresh token by sending it; cookie clients
+	// send no body at all, which is fine
+	if r.ContentLength != 0 {
+		var req models.RefreshRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		if req.RefreshToken != "" {
+			_, _ = h.db.Pool.Exec(r.Context(),
+				"DELETE FROM refresh_tokens WHERE token_hash = $1", hashToken(req.RefreshToken))
+		}
+	}
+
 	cookie, err := r.Cookie("orbex_session")
 	if err == nil && cookie.Value != "" {
 		tokenHash := sha256.Sum256([]byte(cookie.Value))
@@ -254,10 +467,7 @@ func (h *AuthHandler) GenerateBootstrapKey(w http.ResponseWriter, r *http.Reques
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_request", Message: "Invalid JSON body",
-		})
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
@@ -323,16 +533,15 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		CurrentPassword string `json:"current_password"`
 		NewPassword     string `json:"new_password"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "invalid request"})
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 	if req.CurrentPassword == "" || req.NewPassword == "" {
 		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "both current and new password required"})
 		return
 	}
-	if len(req.NewPassword) < 8 {
-		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "new password must be at least 8 characters"})
+	if msg := h.passwordPolicy.Validate(req.NewPassword); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Error: "validation_error", Message: msg})
 		return
 	}
 
@@ -349,7 +558,7 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Hash new password
-	newHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	newHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), h.bcryptCost)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "failed to hash password"})
 		return
@@ -365,6 +574,41 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "password_updated"})
 }
 
+// ErrUserNotFound is returned by MintAPIKey when no user has the email.
+var ErrUserNotFound = errors.New("user not found")
+
+// MintAPIKey creates an API key named name for the user with the given email,
+// straight against the database. It backs orbex-server create-key, the way
+// back in when every key of an account has been lost.
+func MintAPIKey(ctx context.Context, db *database.DB, email, name string) (models.APIKeyResponse, error) {
+	var apiKey models.APIKeyResponse
+	var userID uuid.UUID
+	err := db.Pool.QueryRow(ctx, `SELECT id FROM users WHERE email = $1`, email).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apiKey, ErrUserNotFound
+	}
+	if err != nil {
+		return apiKey, err
+	}
+
+	rawKey, keyHash, prefix, err := generateAPIKey()
+	if err != nil {
+		return apiKey, err
+	}
+	err = db.Pool.QueryRow(ctx, `
+		INSERT INTO api_keys (user_id, name, key_hash, prefix)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, name, prefix, created_at
+	`, userID, name, keyHash, prefix).Scan(
+		&apiKey.ID, &apiKey.Name, &apiKey.Prefix, &apiKey.CreatedAt,
+	)
+	if err != nil {
+		return apiKey, err
+	}
+	apiKey.Key = rawKey
+	return apiKey, nil
+}
+
 // generateAPIKey creates a random API key with prefix "obx_".
 func generateAPIKey() (rawKey, keyHash, prefix string, err error) {
 	// Generate 32 random bytes
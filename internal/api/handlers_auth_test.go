@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/orbex-dev/orbex/internal/database"
+)
+
+// TestRegisterReportsNonDuplicateErrors checks that a database failure on
+// Register is a 500, not reported as the email already being registered.
+func TestRegisterReportsNonDuplicateErrors(t *testing.T) {
+	// Nothing listens on port 1, so every query fails to connect
+	pool, err := pgxpool.New(context.Background(), "postgres://orbex@127.0.0.1:1/orbex?connect_timeout=1")
+	if err != nil {
+		t.Fatalf("creating pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	h := &AuthHandler{db: &database.DB{Pool: pool}}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/register",
+		strings.NewReader(`{"email":"a@example.com","password":"correct horse battery staple"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.Register(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500; body: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestRegisterDuplicateEmail checks that registering a taken email is a 409.
+func TestRegisterDuplicateEmail(t *testing.T) {
+	db := testDB(t)
+	user := createUser(t, db)
+	h := &AuthHandler{db: db}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/register",
+		strings.NewReader(`{"email":"`+user.Email+`","password":"correct horse battery staple"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.Register(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409; body: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -1,25 +1,189 @@
 package api
 
 import (
+	"context"
 	"crypto/rand"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/orbex-dev/orbex/internal/config"
 	"github.com/orbex-dev/orbex/internal/database"
+	"github.com/orbex-dev/orbex/internal/docker"
+	"github.com/orbex-dev/orbex/internal/envcrypt"
 	"github.com/orbex-dev/orbex/internal/models"
+	"github.com/orbex-dev/orbex/internal/schedule"
 )
 
+// jobColumns is the column list read by scanJob. Every query that returns a
+// full job row selects (or RETURNs) exactly these columns.
+const jobColumns = `id, user_id, name, image, command, env, env_sealed, env_key_id, sensitive_env, memory_mb, cpu_millicores,
+		timeout_seconds, start_timeout_seconds, max_concurrent_runs, schedule, script, script_lang,
+		source_type, github_repo, github_branch, github_token_id, dockerfile_path, source_config,
+		retention_days, retention_max_runs, log_retention_days, notify_on, notify_include_logs, template_vars, daily_runtime_budget_seconds, depends_on, image_digest,
+		keep_failed_containers, stdin, network_access, restart_policy, log_driver, gpus, docker_host, dns, extra_hosts, cap_add, cap_drop, tags, is_active, created_at, updated_at`
+
+// scanJob scans a row selected with jobColumns into a Job, decrypting its
+// env. Any extra destinations receive columns selected after jobColumns.
+// An env that can't be decrypted (its key was removed from
+// ENV_ENCRYPTION_KEYS) is logged and left out rather than failing the read.
+func (h *JobHandler) scanJob(row pgx.Row, extra ...any) (models.Job, error) {
+	var job models.Job
+	var envJSON, envSealed []byte
+	var envKeyID *string
+	dest := []any{
+		&job.ID, &job.UserID, &job.Name, &job.Image, &job.Command,
+		&envJSON, &envSealed, &envKeyID, &job.SensitiveEnv, &job.MemoryMB, &job.CPUMillicores, &job.TimeoutSeconds, &job.StartTimeoutSeconds, &job.MaxConcurrentRuns,
+		&job.Schedule, &job.Script, &job.ScriptLang,
+		&job.SourceType, &job.GithubRepo, &job.GithubBranch, &job.GithubTokenID, &job.DockerfilePath, &job.SourceConfig,
+		&job.RetentionDays, &job.RetentionMaxRuns, &job.LogRetentionDays, &job.NotifyOn, &job.NotifyIncludeLogs, &job.TemplateVars, &job.DailyRuntimeBudgetSeconds, &job.DependsOn, &job.ImageDigest,
+		&job.KeepFailedContainers, &job.Stdin, &job.NetworkAccess, &job.RestartPolicy, &job.LogDriver, &job.GPUs, &job.DockerHost, &job.DNS, &job.ExtraHosts, &job.CapAdd, &job.CapDrop, &job.Tags, &job.IsActive, &job.CreatedAt, &job.UpdatedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return job, err
+	}
+	env, err := h.envKeys.DecodeEnv(envJSON, envSealed, envKeyID, job.ID)
+	if err != nil {
+		log.Printf("[api] Warning: reading env of job %s: %v", job.ID, err)
+	}
+	job.Env = redactEnv(env, job.SensitiveEnv)
+	return job, nil
+}
+
+// validNotifyOn reports whether v is a supported notify_on policy.
+func validNotifyOn(v string) bool {
+	switch v {
+	case "all", "failure", "success", "failure_and_recovery":
+		return true
+	}
+	return false
+}
+
+// resourceUnitError describes a rejected human-friendly resource field: either
+// it failed to parse, or it was sent together with its canonical counterpart.
+func resourceUnitError(field, canonical string, parseErr error) string {
+	if parseErr != nil {
+		return parseErr.Error()
+	}
+	return fmt.Sprintf("Specify either %s or %s, not both", field, canonical)
+}
+
+// imageDigestPattern matches the digests accepted for pinning a job's image.
+var imageDigestPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// maxSchedulePreview caps how many fire times SchedulePreview returns.
+const maxSchedulePreview = 100
+
+// maxDependencyDepth bounds how far checkDependency walks up a job chain.
+const maxDependencyDepth = 100
+
+// checkDependency validates making jobID (uuid.Nil for a job being created)
+// depend on parentID. The parent must be one of the user's jobs, and jobID
+// must not already appear in the parent's chain of ancestors, which would
+// close a cycle. It returns a message describing the problem, or "".
+func (h *JobHandler) checkDependency(ctx context.Context, userID, jobID, parentID uuid.UUID) string {
+	var chainLen, cycles int
+	err := h.db.Pool.QueryRow(ctx, `
+		WITH RECURSIVE chain (id, depends_on, depth) AS (
+			SELECT id, depends_on, 1 FROM jobs WHERE id = $1 AND user_id = $2
+			UNION ALL
+			SELECT j.id, j.depends_on, c.depth + 1
+			FROM jobs j JOIN chain c ON j.id = c.depends_on
+			WHERE c.depth < $4
+		)
+		SELECT count(*), count(*) FILTER (WHERE id = $3) FROM chain
+	`, parentID, userID, jobID, maxDependencyDepth).Scan(&chainLen, &cycles)
+	switch {
+	case err != nil:
+		return "Failed to check depends_on"
+	case chainLen == 0:
+		return "depends_on job not found"
+	case cycles > 0:
+		return "depends_on would create a dependency cycle"
+	case chainLen >= maxDependencyDepth:
+		return fmt.Sprintf("Dependency chains are limited to %d jobs", maxDependencyDepth)
+	}
+	return ""
+}
+
 // JobHandler handles job CRUD operations.
 type JobHandler struct {
-	db *database.DB
+	db          *database.DB
+	quotas      Quotas
+	limits      SpecLimits
+	dockerHosts map[string]config.DockerHostAddr // Named daemons jobs may select with docker_host
+	allowedCaps []string                         // Capabilities jobs may add (ALLOWED_CAPABILITIES)
+	gpuHosts    *gpuHosts                        // Which daemons support GPU containers
+	envKeys     *envcrypt.Keyring                // Seals env at rest; nil = stored in plaintext
 }
 
 // NewJobHandler creates a new JobHandler.
-func NewJobHandler(db *database.DB) *JobHandler {
-	return &JobHandler{db: db}
+func NewJobHandler(db *database.DB, dockerHosts *docker.Hosts, cfg *config.Config) *JobHandler {
+	return &JobHandler{
+		db: db,
+		quotas: Quotas{
+			MaxJobs:          cfg.MaxJobsPerUser,
+			MaxScheduledJobs: cfg.MaxScheduledJobsPerUser,
+		},
+		limits: SpecLimits{
+			MaxCommandBytes: cfg.MaxCommandBytes,
+			MaxEnvVars:      cfg.MaxEnvVars,
+			MaxEnvBytes:     cfg.MaxEnvBytes,
+		},
+		dockerHosts: cfg.DockerHosts,
+		allowedCaps: cfg.AllowedCapabilities,
+		gpuHosts:    &gpuHosts{hosts: dockerHosts},
+		envKeys:     cfg.EnvKeys,
+	}
+}
+
+// gpuProbeTTL is how long the answer to which daemons support GPUs is
+// reused before they're asked again.
+const gpuProbeTTL = 5 * time.Minute
+
+// gpuHosts tracks which daemons support GPU containers. Daemons are probed
+// on first use rather than at startup, and again once the answer is older
+// than gpuProbeTTL, so a daemon that gains (or loses) its NVIDIA runtime is
+// noticed without a restart.
+type gpuHosts struct {
+	hosts *docker.Hosts
+
+	mu       sync.Mutex
+	probedAt time.Time
+	gpu      map[string]bool // By host name ("" = default)
+}
+
+// get returns the daemons that support GPUs, keyed by host name ("" for the
+// default). A nil gpuHosts, or one without daemons, reports none.
+func (g *gpuHosts) get() map[string]bool {
+	if g == nil || g.hosts == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.gpu == nil || time.Since(g.probedAt) > gpuProbeTTL {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		g.gpu = g.hosts.GPUHosts(ctx)
+		cancel()
+		g.probedAt = time.Now()
+	}
+	return g.gpu
+}
+
+// knownDockerHost reports whether name is one of the configured DOCKER_HOSTS.
+func (h *JobHandler) knownDockerHost(name string) bool {
+	_, ok := h.dockerHosts[name]
+	return ok
 }
 
 // Create creates a new job definition.
@@ -27,34 +191,19 @@ func (h *JobHandler) Create(w http.ResponseWriter, r *http.Request) {
 	user := UserFromContext(r.Context())
 
 	var req models.CreateJobRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_request", Message: "Invalid JSON body",
-		})
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	if req.Name == "" {
-		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Error: "validation_error", Message: "Name is required",
-		})
+	if errs := h.validateCreateJob(&req); len(errs) > 0 {
+		errs.write(w)
 		return
 	}
 
-	// Default source type
+	// Apply defaults
 	if req.SourceType == "" {
 		req.SourceType = "image"
 	}
-
-	// Image required only for 'image' source type
-	if req.SourceType == "image" && req.Image == "" {
-		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Error: "validation_error", Message: "Image is required for docker image source type",
-		})
-		return
-	}
-
-	// Apply defaults
 	if req.MemoryMB == 0 {
 		req.MemoryMB = 512
 	}
@@ -64,32 +213,94 @@ func (h *JobHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if req.TimeoutSeconds == 0 {
 		req.TimeoutSeconds = 3600
 	}
+	if req.StartTimeoutSeconds != nil && *req.StartTimeoutSeconds == 0 {
+		req.StartTimeoutSeconds = nil // No start deadline
+	}
+	if req.MaxConcurrentRuns != nil && *req.MaxConcurrentRuns == 0 {
+		req.MaxConcurrentRuns = nil // No per-job limit
+	}
+	if req.LogRetentionDays != nil && *req.LogRetentionDays == 0 {
+		req.LogRetentionDays = nil // Fall back to the global log retention
+	}
+	if req.DailyRuntimeBudgetSeconds != nil && *req.DailyRuntimeBudgetSeconds == 0 {
+		req.DailyRuntimeBudgetSeconds = nil // No budget
+	}
 	if req.Env == nil {
 		req.Env = map[string]string{}
 	}
+	if req.SensitiveEnv == nil {
+		req.SensitiveEnv = []string{}
+	}
+	if req.DNS == nil {
+		req.DNS = []string{}
+	}
+	if req.ExtraHosts == nil {
+		req.ExtraHosts = []string{}
+	}
+	if req.CapAdd == nil {
+		req.CapAdd = []string{}
+	}
+	if req.CapDrop == nil {
+		req.CapDrop = []string{}
+	}
+	if req.Tags == nil {
+		req.Tags = []string{}
+	}
+	if req.NotifyOn == "" {
+		req.NotifyOn = "all"
+	}
+
+	if req.DependsOn != nil {
+		if msg := h.checkDependency(r.Context(), user.ID, uuid.Nil, *req.DependsOn); msg != "" {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "validation_error", Message: msg,
+			})
+			return
+		}
+	}
+
+	newScheduled := 0
+	if req.Schedule != nil && *req.Schedule != "" {
+		newScheduled = 1
+	}
+	tx, err := h.db.Pool.Begin(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to create job",
+		})
+		return
+	}
+	defer tx.Rollback(r.Context())
 
-	envJSON, _ := json.Marshal(req.Env)
+	if !h.checkQuota(w, r, tx, user.ID, 1, newScheduled) {
+		return
+	}
+
+	// The ID is chosen up front so a sealed env can be bound to its job
+	jobID := uuid.New()
+	envJSON, envSealed, envKeyID, err := h.envKeys.EncodeEnv(req.Env, jobID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to create job",
+		})
+		return
+	}
 	sourceConfigJSON := req.SourceConfig
 	if sourceConfigJSON == nil {
 		sourceConfigJSON = []byte("{}")
 	}
 
-	var job models.Job
-	err := h.db.Pool.QueryRow(r.Context(), `
-		INSERT INTO jobs (user_id, name, image, command, env, memory_mb, cpu_millicores, timeout_seconds, schedule, script, script_lang, source_type, github_repo, github_branch, github_token_id, dockerfile_path, source_config)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
-		RETURNING id, user_id, name, image, command, env, memory_mb, cpu_millicores, timeout_seconds, schedule, script, script_lang, source_type, github_repo, github_branch, github_token_id, dockerfile_path, source_config, is_active, created_at, updated_at
-	`, user.ID, req.Name, req.Image, req.Command, envJSON,
-		req.MemoryMB, req.CPUMillicores, req.TimeoutSeconds, req.Schedule,
+	job, err := h.scanJob(tx.QueryRow(r.Context(), `
+		INSERT INTO jobs (user_id, name, image, command, env, env_sealed, env_key_id, sensitive_env, memory_mb, cpu_millicores, timeout_seconds, start_timeout_seconds, max_concurrent_runs, schedule, script, script_lang, source_type, github_repo, github_branch, github_token_id, dockerfile_path, source_config, retention_days, retention_max_runs, log_retention_days, notify_on, notify_include_logs, template_vars, daily_runtime_budget_seconds, depends_on, image_digest, keep_failed_containers, stdin, network_access, restart_policy, log_driver, gpus, docker_host, dns, extra_hosts, cap_add, cap_drop, tags, id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, $38, $39, $40, $41, $42, $43, $44)
+		RETURNING `+jobColumns,
+		user.ID, req.Name, req.Image, req.Command, envJSON, envSealed, envKeyID, req.SensitiveEnv,
+		req.MemoryMB, req.CPUMillicores, req.TimeoutSeconds, req.StartTimeoutSeconds, req.MaxConcurrentRuns, req.Schedule,
 		req.Script, req.ScriptLang, req.SourceType,
 		req.GithubRepo, req.GithubBranch, req.GithubTokenID, req.DockerfilePath, sourceConfigJSON,
-	).Scan(
-		&job.ID, &job.UserID, &job.Name, &job.Image, &job.Command,
-		&envJSON, &job.MemoryMB, &job.CPUMillicores, &job.TimeoutSeconds,
-		&job.Schedule, &job.Script, &job.ScriptLang,
-		&job.SourceType, &job.GithubRepo, &job.GithubBranch, &job.GithubTokenID, &job.DockerfilePath, &job.SourceConfig,
-		&job.IsActive, &job.CreatedAt, &job.UpdatedAt,
-	)
+		req.RetentionDays, req.RetentionMaxRuns, req.LogRetentionDays, req.NotifyOn, req.NotifyIncludeLogs, req.TemplateVars, req.DailyRuntimeBudgetSeconds, req.DependsOn, req.ImageDigest,
+		req.KeepFailedContainers, req.Stdin, req.NetworkAccess, req.RestartPolicy, req.LogDriver, req.GPUs, req.DockerHost, req.DNS, req.ExtraHosts, req.CapAdd, req.CapDrop, req.Tags, jobID,
+	))
 
 	if err != nil {
 		if isDuplicateError(err) {
@@ -104,23 +315,40 @@ func (h *JobHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_ = json.Unmarshal(envJSON, &job.Env)
+	if err := tx.Commit(r.Context()); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to create job",
+		})
+		return
+	}
+
 	writeJSON(w, http.StatusCreated, job)
 }
 
-// List returns all jobs for the authenticated user.
+// List returns all jobs for the authenticated user, each with a summary of
+// its most recent run. ?tag= keeps only jobs with that tag.
 func (h *JobHandler) List(w http.ResponseWriter, r *http.Request) {
 	user := UserFromContext(r.Context())
+	var tag *string
+	if t := r.URL.Query().Get("tag"); t != "" {
+		tag = &t
+	}
 
 	rows, err := h.db.Pool.Query(r.Context(), `
-		SELECT id, user_id, name, image, command, env, memory_mb, cpu_millicores,
-		       timeout_seconds, schedule, script, script_lang,
-		       source_type, github_repo, github_branch, github_token_id, dockerfile_path, source_config,
-		       is_active, created_at, updated_at
+		SELECT `+jobColumns+`,
+		       lr.last_run_id, lr.last_run_status, lr.last_run_exit_code, lr.last_run_finished_at
 		FROM jobs
-		WHERE user_id = $1
+		LEFT JOIN LATERAL (
+			SELECT id AS last_run_id, status AS last_run_status,
+			       exit_code AS last_run_exit_code, finished_at AS last_run_finished_at
+			FROM job_runs
+			WHERE job_id = jobs.id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) lr ON true
+		WHERE user_id = $1 AND ($2::text IS NULL OR $2 = ANY(tags))
 		ORDER BY created_at DESC
-	`, user.ID)
+	`, user.ID, tag)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
 			Error: "internal_error", Message: "Failed to list jobs",
@@ -131,28 +359,27 @@ func (h *JobHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	var jobs []models.Job
 	for rows.Next() {
-		var job models.Job
-		var envJSON []byte
-		if err := rows.Scan(
-			&job.ID, &job.UserID, &job.Name, &job.Image, &job.Command,
-			&envJSON, &job.MemoryMB, &job.CPUMillicores, &job.TimeoutSeconds,
-			&job.Schedule, &job.Script, &job.ScriptLang,
-			&job.SourceType, &job.GithubRepo, &job.GithubBranch, &job.GithubTokenID, &job.DockerfilePath, &job.SourceConfig,
-			&job.IsActive, &job.CreatedAt, &job.UpdatedAt,
-		); err != nil {
+		var lastID *uuid.UUID
+		var lastRun models.RunSummary
+		var lastStatus *models.RunStatus
+		job, err := h.scanJob(rows, &lastID, &lastStatus, &lastRun.ExitCode, &lastRun.FinishedAt)
+		if err != nil {
 			continue
 		}
-		_ = json.Unmarshal(envJSON, &job.Env)
+		if lastID != nil {
+			lastRun.ID, lastRun.Status = *lastID, *lastStatus
+			job.LastRun = &lastRun
+		}
 		jobs = append(jobs, job)
 	}
 
 	if jobs == nil {
 		jobs = []models.Job{}
 	}
-	writeJSON(w, http.StatusOK, jobs)
+	writeList(w, r, jobs, models.Pagination{Total: len(jobs)})
 }
 
-// Get returns a single job by ID.
+// Get returns a single job by ID, honoring If-None-Match.
 func (h *JobHandler) Get(w http.ResponseWriter, r *http.Request) {
 	user := UserFromContext(r.Context())
 	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
@@ -163,22 +390,11 @@ func (h *JobHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var job models.Job
-	var envJSON []byte
-	err = h.db.Pool.QueryRow(r.Context(), `
-		SELECT id, user_id, name, image, command, env, memory_mb, cpu_millicores,
-		       timeout_seconds, schedule, script, script_lang,
-		       source_type, github_repo, github_branch, github_token_id, dockerfile_path, source_config,
-		       is_active, created_at, updated_at
+	job, err := h.scanJob(h.db.Pool.QueryRow(r.Context(), `
+		SELECT `+jobColumns+`
 		FROM jobs
 		WHERE id = $1 AND user_id = $2
-	`, jobID, user.ID).Scan(
-		&job.ID, &job.UserID, &job.Name, &job.Image, &job.Command,
-		&envJSON, &job.MemoryMB, &job.CPUMillicores, &job.TimeoutSeconds,
-		&job.Schedule, &job.Script, &job.ScriptLang,
-		&job.SourceType, &job.GithubRepo, &job.GithubBranch, &job.GithubTokenID, &job.DockerfilePath, &job.SourceConfig,
-		&job.IsActive, &job.CreatedAt, &job.UpdatedAt,
-	)
+	`, jobID, user.ID))
 
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
@@ -187,8 +403,7 @@ func (h *JobHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_ = json.Unmarshal(envJSON, &job.Env)
-	writeJSON(w, http.StatusOK, job)
+	writeJSONWithETag(w, r, job)
 }
 
 // Delete removes a job definition.
@@ -228,12 +443,61 @@ func (h *JobHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req models.UpdateJobRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_request", Message: "Invalid JSON body",
-		})
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if errs := h.validateUpdateJob(&req); len(errs) > 0 {
+		errs.write(w)
 		return
 	}
+	if req.TemplateVars != nil || req.Command != nil || req.Env != nil || req.GPUs != nil || req.DockerHost != nil {
+		// Templates and GPUs are checked against the job as it will be after
+		// the update, filling in whatever the request leaves out
+		current, err := h.scanJob(h.db.Pool.QueryRow(r.Context(), `
+			SELECT `+jobColumns+` FROM jobs WHERE id = $1 AND user_id = $2
+		`, jobID, user.ID))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+				Error: "not_found", Message: "Job not found",
+			})
+			return
+		}
+		templateVars, command, env := current.TemplateVars, current.Command, current.Env
+		if req.TemplateVars != nil {
+			templateVars = *req.TemplateVars
+		}
+		if req.Command != nil {
+			command = *req.Command
+		}
+		if req.Env != nil {
+			env = *req.Env
+		}
+		var gpus, host string
+		if current.GPUs != nil {
+			gpus = *current.GPUs
+		}
+		if current.DockerHost != nil {
+			host = *current.DockerHost
+		}
+		if req.GPUs != nil {
+			gpus = *req.GPUs
+		}
+		if req.DockerHost != nil {
+			host = *req.DockerHost
+		}
+
+		var errs fieldErrors
+		if templateVars {
+			checkTemplates(&errs, command, env)
+		}
+		if gpus != "" {
+			h.checkGPUs(&errs, gpus, host)
+		}
+		if len(errs) > 0 {
+			errs.write(w)
+			return
+		}
+	}
 
 	// Build dynamic SET clause — only update provided fields
 	setClauses := []string{"updated_at = now()"}
@@ -256,9 +520,36 @@ func (h *JobHandler) Update(w http.ResponseWriter, r *http.Request) {
 		argIdx++
 	}
 	if req.Env != nil {
-		envJSON, _ := json.Marshal(*req.Env)
-		setClauses = append(setClauses, fmt.Sprintf("env = $%d", argIdx))
-		args = append(args, envJSON)
+		var explicit []string
+		if req.SensitiveEnv != nil {
+			explicit = *req.SensitiveEnv
+		}
+		if err := h.restoreRedactedEnv(r.Context(), jobID, user.ID, *req.Env, explicit); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+					Error: "not_found", Message: "Job not found",
+				})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+				Error: "internal_error", Message: "Failed to read the job's stored env",
+			})
+			return
+		}
+		envJSON, envSealed, envKeyID, err := h.envKeys.EncodeEnv(*req.Env, jobID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+				Error: "internal_error", Message: "Failed to update job",
+			})
+			return
+		}
+		setClauses = append(setClauses, fmt.Sprintf("env = $%d, env_sealed = $%d, env_key_id = $%d", argIdx, argIdx+1, argIdx+2))
+		args = append(args, envJSON, envSealed, envKeyID)
+		argIdx += 3
+	}
+	if req.SensitiveEnv != nil {
+		setClauses = append(setClauses, fmt.Sprintf("sensitive_env = $%d", argIdx))
+		args = append(args, *req.SensitiveEnv)
 		argIdx++
 	}
 	if req.MemoryMB != nil {
@@ -276,6 +567,24 @@ func (h *JobHandler) Update(w http.ResponseWriter, r *http.Request) {
 		args = append(args, *req.TimeoutSeconds)
 		argIdx++
 	}
+	if req.StartTimeoutSeconds != nil {
+		setClauses = append(setClauses, fmt.Sprintf("start_timeout_seconds = $%d", argIdx))
+		if *req.StartTimeoutSeconds == 0 {
+			args = append(args, nil) // remove the start deadline
+		} else {
+			args = append(args, *req.StartTimeoutSeconds)
+		}
+		argIdx++
+	}
+	if req.MaxConcurrentRuns != nil {
+		setClauses = append(setClauses, fmt.Sprintf("max_concurrent_runs = $%d", argIdx))
+		if *req.MaxConcurrentRuns == 0 {
+			args = append(args, nil) // remove the limit
+		} else {
+			args = append(args, *req.MaxConcurrentRuns)
+		}
+		argIdx++
+	}
 	if req.Schedule != nil {
 		setClauses = append(setClauses, fmt.Sprintf("schedule = $%d", argIdx))
 		if *req.Schedule == "" {
@@ -333,6 +642,162 @@ func (h *JobHandler) Update(w http.ResponseWriter, r *http.Request) {
 		args = append(args, *req.SourceConfig)
 		argIdx++
 	}
+	if req.RetentionDays != nil {
+		setClauses = append(setClauses, fmt.Sprintf("retention_days = $%d", argIdx))
+		if *req.RetentionDays == 0 {
+			args = append(args, nil) // fall back to global retention
+		} else {
+			args = append(args, *req.RetentionDays)
+		}
+		argIdx++
+	}
+	if req.RetentionMaxRuns != nil {
+		setClauses = append(setClauses, fmt.Sprintf("retention_max_runs = $%d", argIdx))
+		if *req.RetentionMaxRuns == 0 {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.RetentionMaxRuns)
+		}
+		argIdx++
+	}
+	if req.LogRetentionDays != nil {
+		setClauses = append(setClauses, fmt.Sprintf("log_retention_days = $%d", argIdx))
+		if *req.LogRetentionDays == 0 {
+			args = append(args, nil) // fall back to the global log retention
+		} else {
+			args = append(args, *req.LogRetentionDays)
+		}
+		argIdx++
+	}
+	if req.NotifyOn != nil {
+		setClauses = append(setClauses, fmt.Sprintf("notify_on = $%d", argIdx))
+		args = append(args, *req.NotifyOn)
+		argIdx++
+	}
+	if req.NotifyIncludeLogs != nil {
+		setClauses = append(setClauses, fmt.Sprintf("notify_include_logs = $%d", argIdx))
+		args = append(args, *req.NotifyIncludeLogs)
+		argIdx++
+	}
+	if req.TemplateVars != nil {
+		setClauses = append(setClauses, fmt.Sprintf("template_vars = $%d", argIdx))
+		args = append(args, *req.TemplateVars)
+		argIdx++
+	}
+	if req.ImageDigest != nil {
+		setClauses = append(setClauses, fmt.Sprintf("image_digest = $%d", argIdx))
+		if *req.ImageDigest == "" {
+			args = append(args, nil) // unpin
+		} else {
+			args = append(args, *req.ImageDigest)
+		}
+		argIdx++
+	}
+	if req.DependsOn != nil {
+		setClauses = append(setClauses, fmt.Sprintf("depends_on = $%d", argIdx))
+		if *req.DependsOn == "" {
+			args = append(args, nil) // remove the dependency
+		} else {
+			parentID := uuid.MustParse(*req.DependsOn) // Checked by validateUpdateJob
+			if msg := h.checkDependency(r.Context(), user.ID, jobID, parentID); msg != "" {
+				writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+					Error: "validation_error", Message: msg,
+				})
+				return
+			}
+			args = append(args, parentID)
+		}
+		argIdx++
+	}
+	if req.KeepFailedContainers != nil {
+		setClauses = append(setClauses, fmt.Sprintf("keep_failed_containers = $%d", argIdx))
+		args = append(args, *req.KeepFailedContainers)
+		argIdx++
+	}
+	if req.RestartPolicy != nil {
+		setClauses = append(setClauses, fmt.Sprintf("restart_policy = $%d", argIdx))
+		if *req.RestartPolicy == "" || *req.RestartPolicy == "no" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.RestartPolicy)
+		}
+		argIdx++
+	}
+	if req.LogDriver != nil {
+		setClauses = append(setClauses, fmt.Sprintf("log_driver = $%d", argIdx))
+		if *req.LogDriver == "" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.LogDriver)
+		}
+		argIdx++
+	}
+	if req.GPUs != nil {
+		setClauses = append(setClauses, fmt.Sprintf("gpus = $%d", argIdx))
+		if *req.GPUs == "" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.GPUs)
+		}
+		argIdx++
+	}
+	if req.DockerHost != nil {
+		setClauses = append(setClauses, fmt.Sprintf("docker_host = $%d", argIdx))
+		if *req.DockerHost == "" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.DockerHost)
+		}
+		argIdx++
+	}
+	if req.NetworkAccess != nil {
+		setClauses = append(setClauses, fmt.Sprintf("network_access = $%d", argIdx))
+		args = append(args, *req.NetworkAccess)
+		argIdx++
+	}
+	if req.DNS != nil {
+		setClauses = append(setClauses, fmt.Sprintf("dns = $%d", argIdx))
+		args = append(args, *req.DNS)
+		argIdx++
+	}
+	if req.ExtraHosts != nil {
+		setClauses = append(setClauses, fmt.Sprintf("extra_hosts = $%d", argIdx))
+		args = append(args, *req.ExtraHosts)
+		argIdx++
+	}
+	if req.CapAdd != nil {
+		setClauses = append(setClauses, fmt.Sprintf("cap_add = $%d", argIdx))
+		args = append(args, *req.CapAdd)
+		argIdx++
+	}
+	if req.CapDrop != nil {
+		setClauses = append(setClauses, fmt.Sprintf("cap_drop = $%d", argIdx))
+		args = append(args, *req.CapDrop)
+		argIdx++
+	}
+	if req.Tags != nil {
+		setClauses = append(setClauses, fmt.Sprintf("tags = $%d", argIdx))
+		args = append(args, *req.Tags)
+		argIdx++
+	}
+	if req.Stdin != nil {
+		setClauses = append(setClauses, fmt.Sprintf("stdin = $%d", argIdx))
+		if *req.Stdin == "" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.Stdin)
+		}
+		argIdx++
+	}
+	if req.DailyRuntimeBudgetSeconds != nil {
+		setClauses = append(setClauses, fmt.Sprintf("daily_runtime_budget_seconds = $%d", argIdx))
+		if *req.DailyRuntimeBudgetSeconds == 0 {
+			args = append(args, nil) // remove the budget
+		} else {
+			args = append(args, *req.DailyRuntimeBudgetSeconds)
+		}
+		argIdx++
+	}
 
 	if len(args) == 0 {
 		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
@@ -346,18 +811,23 @@ func (h *JobHandler) Update(w http.ResponseWriter, r *http.Request) {
 	query := fmt.Sprintf(`
 		UPDATE jobs SET %s
 		WHERE id = $%d AND user_id = $%d
-		RETURNING id, user_id, name, image, command, env, memory_mb, cpu_millicores, timeout_seconds, schedule, script, script_lang, source_type, github_repo, github_branch, github_token_id, dockerfile_path, source_config, is_active, created_at, updated_at
-	`, joinStrings(setClauses, ", "), argIdx, argIdx+1)
+		RETURNING %s
+	`, joinStrings(setClauses, ", "), argIdx, argIdx+1, jobColumns)
 
-	var job models.Job
-	var envJSON []byte
-	err = h.db.Pool.QueryRow(r.Context(), query, args...).Scan(
-		&job.ID, &job.UserID, &job.Name, &job.Image, &job.Command,
-		&envJSON, &job.MemoryMB, &job.CPUMillicores, &job.TimeoutSeconds,
-		&job.Schedule, &job.Script, &job.ScriptLang,
-		&job.SourceType, &job.GithubRepo, &job.GithubBranch, &job.GithubTokenID, &job.DockerfilePath, &job.SourceConfig,
-		&job.IsActive, &job.CreatedAt, &job.UpdatedAt,
-	)
+	tx, err := h.db.Pool.Begin(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to update job",
+		})
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	if req.Schedule != nil && *req.Schedule != "" && !h.checkScheduleQuota(w, r, tx, user.ID, jobID) {
+		return
+	}
+
+	job, err := h.scanJob(tx.QueryRow(r.Context(), query, args...))
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
 			Error: "not_found", Message: "Job not found",
@@ -365,10 +835,368 @@ func (h *JobHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_ = json.Unmarshal(envJSON, &job.Env)
+	if err := tx.Commit(r.Context()); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to update job",
+		})
+		return
+	}
+
 	writeJSON(w, http.StatusOK, job)
 }
 
+// Enable re-activates a job so it can be triggered and scheduled again.
+func (h *JobHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, true)
+}
+
+// Disable deactivates a job. Scheduled and manual triggers are refused until
+// it is re-enabled; runs already in flight are left to finish.
+func (h *JobHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, false)
+}
+
+// setActive flips a job's is_active flag and returns the updated job.
+func (h *JobHandler) setActive(w http.ResponseWriter, r *http.Request, active bool) {
+	user := UserFromContext(r.Context())
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid job ID",
+		})
+		return
+	}
+
+	job, err := h.scanJob(h.db.Pool.QueryRow(r.Context(), `
+		UPDATE jobs SET is_active = $1, updated_at = now()
+		WHERE id = $2 AND user_id = $3
+		RETURNING `+jobColumns,
+		active, jobID, user.ID,
+	))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Job not found",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// cloneableJobColumns are the job columns copied by Clone. Identity, the
+// webhook token and timestamps are deliberately left out, as is the env,
+// which Clone re-seals for the new job.
+const cloneableJobColumns = `image, command, sensitive_env, memory_mb, cpu_millicores, timeout_seconds, start_timeout_seconds, max_concurrent_runs,
+		schedule, script, script_lang, source_type, github_repo, github_branch, github_token_id,
+		dockerfile_path, source_config, retention_days, retention_max_runs, log_retention_days, notify_on, notify_include_logs, template_vars,
+		daily_runtime_budget_seconds, depends_on, image_digest, keep_failed_containers, stdin, network_access, restart_policy, log_driver, gpus, docker_host, dns, extra_hosts, cap_add, cap_drop, tags, is_active`
+
+// Clone creates a copy of a job under a new name (default "<name>-copy"),
+// including its notification channels. Uploaded source files are not copied.
+func (h *JobHandler) Clone(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid job ID",
+		})
+		return
+	}
+
+	var req models.CloneJobRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &req) {
+		return
+	}
+
+	tx, err := h.db.Pool.Begin(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to clone job",
+		})
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	// The clone counts against the quotas, including its copied schedule
+	var scheduled bool
+	var envJSON, envSealed []byte
+	var envKeyID *string
+	err = tx.QueryRow(r.Context(), `
+		SELECT schedule IS NOT NULL, env, env_sealed, env_key_id FROM jobs WHERE id = $1 AND user_id = $2
+	`, jobID, user.ID).Scan(&scheduled, &envJSON, &envSealed, &envKeyID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Job not found",
+		})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to clone job",
+		})
+		return
+	}
+	newScheduled := 0
+	if scheduled {
+		newScheduled = 1
+	}
+	if !h.checkQuota(w, r, tx, user.ID, 1, newScheduled) {
+		return
+	}
+
+	// A sealed env is bound to its job, so it is decrypted and sealed again
+	// for the clone rather than copied
+	env, err := h.envKeys.DecodeEnv(envJSON, envSealed, envKeyID, jobID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to read the job's stored env",
+		})
+		return
+	}
+	cloneID := uuid.New()
+	if envJSON, envSealed, envKeyID, err = h.envKeys.EncodeEnv(env, cloneID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to clone job",
+		})
+		return
+	}
+
+	job, err := h.scanJob(tx.QueryRow(r.Context(), `
+		INSERT INTO jobs (id, user_id, name, env, env_sealed, env_key_id, `+cloneableJobColumns+`)
+		SELECT $4, user_id, COALESCE(NULLIF($3, ''), name || '-copy'), $5, $6, $7, `+cloneableJobColumns+`
+		FROM jobs
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+jobColumns,
+		jobID, user.ID, req.Name, cloneID, envJSON, envSealed, envKeyID,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+				Error: "not_found", Message: "Job not found",
+			})
+			return
+		}
+		if isDuplicateError(err) {
+			writeJSON(w, http.StatusConflict, models.ErrorResponse{
+				Error: "conflict", Message: "A job with this name already exists",
+			})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to clone job",
+		})
+		return
+	}
+
+	if _, err := tx.Exec(r.Context(), `
+		INSERT INTO notification_channels (job_id, type, target, events)
+		SELECT $2, type, target, events FROM notification_channels WHERE job_id = $1
+	`, jobID, job.ID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to clone job",
+		})
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to clone job",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, job)
+}
+
+// Usage reports the job's runtime consumed today against its daily budget.
+// The day boundary is midnight in the database's time zone, matching the
+// worker's budget check.
+func (h *JobHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid job ID",
+		})
+		return
+	}
+
+	usage := models.JobUsage{JobID: jobID}
+	var usedMs int64
+	err = h.db.Pool.QueryRow(r.Context(), `
+		SELECT j.daily_runtime_budget_seconds,
+		       COALESCE((SELECT SUM(duration_ms) FROM job_runs
+		                 WHERE job_id = j.id AND created_at >= date_trunc('day', now())), 0)
+		FROM jobs j
+		WHERE j.id = $1 AND j.user_id = $2
+	`, jobID, user.ID).Scan(&usage.DailyRuntimeBudgetSeconds, &usedMs)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Job not found",
+		})
+		return
+	}
+
+	usage.RuntimeUsedTodaySeconds = usedMs / 1000
+	if usage.DailyRuntimeBudgetSeconds != nil {
+		remaining := max(int64(*usage.DailyRuntimeBudgetSeconds)-usage.RuntimeUsedTodaySeconds, 0)
+		usage.RuntimeRemainingSeconds = &remaining
+	}
+
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// defaultStatsWindow is the window Stats covers when ?window= is omitted.
+const defaultStatsWindow = "7d"
+
+// Stats reports run counts, success rate, duration percentiles and average
+// queue wait for the job's runs created within ?window= (default 7d), all
+// computed in one pass over job_runs.
+func (h *JobHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid job ID",
+		})
+		return
+	}
+
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = defaultStatsWindow
+	}
+	age, err := parseAge(window)
+	if err != nil || age == 0 {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "window must be a positive age such as 7d or 12h",
+		})
+		return
+	}
+	if !requireOwnedJob(w, r, h.db, jobID, user.ID) {
+		return
+	}
+
+	stats := models.JobStats{JobID: jobID, Window: window, Since: time.Now().Add(-age).UTC()}
+	var p50, p95, avgDuration, avgWait *float64
+	err = h.db.Pool.QueryRow(r.Context(), `
+		SELECT count(*),
+		       count(*) FILTER (WHERE status = 'succeeded'),
+		       count(*) FILTER (WHERE status = 'failed'),
+		       count(*) FILTER (WHERE status = 'cancelled'),
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY duration_ms),
+		       percentile_cont(0.95) WITHIN GROUP (ORDER BY duration_ms),
+		       avg(duration_ms)::float8,
+		       avg(`+runWaitMsColumn+`)::float8
+		FROM job_runs
+		WHERE job_id = $1 AND created_at >= $2
+	`, jobID, stats.Since).Scan(&stats.TotalRuns, &stats.Succeeded, &stats.Failed, &stats.Cancelled,
+		&p50, &p95, &avgDuration, &avgWait)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to compute job stats",
+		})
+		return
+	}
+
+	if finished := stats.Succeeded + stats.Failed; finished > 0 {
+		rate := float64(stats.Succeeded) / float64(finished)
+		stats.SuccessRate = &rate
+	}
+	stats.P50DurationMs = roundMs(p50)
+	stats.P95DurationMs = roundMs(p95)
+	stats.AvgDurationMs = roundMs(avgDuration)
+	stats.AvgWaitMs = roundMs(avgWait)
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// roundMs rounds an aggregate in milliseconds, keeping NULL (no rows) as nil.
+func roundMs(v *float64) *int64 {
+	if v == nil {
+		return nil
+	}
+	ms := int64(math.Round(*v))
+	return &ms
+}
+
+// Validate checks a job definition with the same rules as Create without
+// saving it, reporting every problem at once.
+func (h *JobHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateJobRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if errs := h.validateCreateJob(&req); len(errs) > 0 {
+		errs.write(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"valid": true})
+}
+
+// SchedulePreview returns the next ?count= (default 5, max
+// maxSchedulePreview) times the job's cron schedule fires, so a mistyped
+// expression shows up before the job relies on it. A CRON_TZ= prefix in the
+// schedule is honored.
+func (h *JobHandler) SchedulePreview(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid job ID",
+		})
+		return
+	}
+
+	count := 5
+	if v := r.URL.Query().Get("count"); v != "" {
+		count, err = strconv.Atoi(v)
+		if err != nil || count < 1 || count > maxSchedulePreview {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid_request", Message: fmt.Sprintf("count must be between 1 and %d", maxSchedulePreview),
+			})
+			return
+		}
+	}
+
+	var expr *string
+	err = h.db.Pool.QueryRow(r.Context(), `
+		SELECT schedule FROM jobs WHERE id = $1 AND user_id = $2
+	`, jobID, user.ID).Scan(&expr)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Job not found",
+		})
+		return
+	}
+	if expr == nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Job has no schedule",
+		})
+		return
+	}
+
+	sched, err := schedule.Parse(*expr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "validation_error", Message: fmt.Sprintf("Invalid schedule %q: %v", *expr, err),
+		})
+		return
+	}
+
+	preview := models.SchedulePreview{Schedule: *expr, Next: make([]time.Time, 0, count)}
+	t := time.Now()
+	for range count {
+		t = sched.Next(t)
+		if t.IsZero() {
+			break // The expression never fires again
+		}
+		preview.Next = append(preview.Next, t)
+	}
+
+	writeJSON(w, http.StatusOK, preview)
+}
+
 // joinStrings joins string slices (avoiding strings import for one use).
 func joinStrings(parts []string, sep string) string {
 	result := ""
@@ -381,7 +1209,10 @@ func joinStrings(parts []string, sep string) string {
 	return result
 }
 
-// GenerateWebhookToken creates or regenerates a webhook token for a job.
+// GenerateWebhookToken returns a job's webhook token, creating one if the job
+// has none. An existing token is only replaced with ?regenerate=true, so a
+// repeated call can't break integrations using it; "created" in the response
+// says whether the token is new.
 func (h *JobHandler) GenerateWebhookToken(w http.ResponseWriter, r *http.Request) {
 	user := UserFromContext(r.Context())
 	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
@@ -402,20 +1233,26 @@ func (h *JobHandler) GenerateWebhookToken(w http.ResponseWriter, r *http.Request
 	}
 	token := fmt.Sprintf("whk_%x", tokenBytes)
 
-	// Update the job with the new token
-	tag, err := h.db.Pool.Exec(r.Context(), `
-		UPDATE jobs SET webhook_token = $1, updated_at = now()
+	// Keep the existing token unless asked to regenerate, in one statement so
+	// concurrent calls agree on the token
+	regenerate := r.URL.Query().Get("regenerate") == "true"
+	var current string
+	err = h.db.Pool.QueryRow(r.Context(), `
+		UPDATE jobs SET webhook_token = CASE WHEN $4 OR webhook_token IS NULL THEN $1 ELSE webhook_token END,
+		                updated_at = CASE WHEN $4 OR webhook_token IS NULL THEN now() ELSE updated_at END
 		WHERE id = $2 AND user_id = $3
-	`, token, jobID, user.ID)
-	if err != nil || tag.RowsAffected() == 0 {
+		RETURNING webhook_token
+	`, token, jobID, user.ID, regenerate).Scan(&current)
+	if err != nil {
 		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
 			Error: "not_found", Message: "Job not found",
 		})
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{
-		"webhook_token": token,
-		"trigger_url":   fmt.Sprintf("/api/v1/webhooks/%s/trigger", token),
+	writeJSON(w, http.StatusOK, map[string]any{
+		"webhook_token": current,
+		"trigger_url":   fmt.Sprintf("/api/v1/webhooks/%s/trigger", current),
+		"created":       current == token,
 	})
 }
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"net/http"
+	"net/mail"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/orbex-dev/orbex/internal/database"
+	"github.com/orbex-dev/orbex/internal/models"
+)
+
+// NotificationHandler manages a job's notification channels.
+type NotificationHandler struct {
+	db *database.DB
+}
+
+// NewNotificationHandler creates a new NotificationHandler.
+func NewNotificationHandler(db *database.DB) *NotificationHandler {
+	return &NotificationHandler{db: db}
+}
+
+// List returns all notification channels for a job.
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid job ID",
+		})
+		return
+	}
+
+	if !requireOwnedJob(w, r, h.db, jobID, user.ID) {
+		return
+	}
+
+	rows, err := h.db.Pool.Query(r.Context(), `
+		SELECT c.id, c.job_id, c.type, c.target, c.events, c.created_at
+		FROM notification_channels c
+		JOIN jobs j ON j.id = c.job_id
+		WHERE c.job_id = $1 AND j.user_id = $2
+		ORDER BY c.created_at
+	`, jobID, user.ID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to list notification channels",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var channels []models.NotificationChannel
+	for rows.Next() {
+		var c models.NotificationChannel
+		if err := rows.Scan(&c.ID, &c.JobID, &c.Type, &c.Target, &c.Events, &c.CreatedAt); err != nil {
+			continue
+		}
+		channels = append(channels, c)
+	}
+
+	if channels == nil {
+		channels = []models.NotificationChannel{}
+	}
+	writeList(w, r, channels, models.Pagination{Total: len(channels)})
+}
+
+// Create adds a notification channel to a job.
+func (h *NotificationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid job ID",
+		})
+		return
+	}
+
+	var req models.CreateNotificationChannelRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Events == "" {
+		req.Events = "all"
+	}
+	if msg := validateChannel(req); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "validation_error", Message: msg,
+		})
+		return
+	}
+
+	var c models.NotificationChannel
+	err = h.db.Pool.QueryRow(r.Context(), `
+		INSERT INTO notification_channels (job_id, type, target, events)
+		SELECT id, $3, $4, $5 FROM jobs WHERE id = $1 AND user_id = $2
+		RETURNING id, job_id, type, target, events, created_at
+	`, jobID, user.ID, req.Type, req.Target, req.Events).Scan(
+		&c.ID, &c.JobID, &c.Type, &c.Target, &c.Events, &c.CreatedAt,
+	)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Job not found",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// Delete removes a notification channel from a job.
+func (h *NotificationHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid job ID",
+		})
+		return
+	}
+	channelID, err := uuid.Parse(chi.URLParam(r, "channelID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid channel ID",
+		})
+		return
+	}
+
+	tag, err := h.db.Pool.Exec(r.Context(), `
+		DELETE FROM notification_channels c
+		USING jobs j
+		WHERE c.id = $1 AND c.job_id = $2 AND j.id = c.job_id AND j.user_id = $3
+	`, channelID, jobID, user.ID)
+	if err != nil || tag.RowsAffected() == 0 {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Notification channel not found",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateChannel returns a validation message, or "" if the channel is valid.
+func validateChannel(req models.CreateNotificationChannelRequest) string {
+	switch req.Events {
+	case "all", "success", "failure":
+	default:
+		return "events must be one of: all, success, failure"
+	}
+
+	switch req.Type {
+	case "webhook", "slack":
+		u, err := url.Parse(req.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "target must be an http(s) URL"
+		}
+	case "email":
+		if _, err := mail.ParseAddress(req.Target); err != nil {
+			return "target must be a valid email address"
+		}
+	default:
+		return "type must be one of: webhook, slack, email"
+	}
+	return ""
+}
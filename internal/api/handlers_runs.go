@@ -1,26 +1,102 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/orbex-dev/orbex/internal/config"
 	"github.com/orbex-dev/orbex/internal/database"
 	"github.com/orbex-dev/orbex/internal/docker"
+	"github.com/orbex-dev/orbex/internal/envcrypt"
+	"github.com/orbex-dev/orbex/internal/logstore"
 	"github.com/orbex-dev/orbex/internal/models"
+	"github.com/orbex-dev/orbex/internal/storage"
 )
 
+const (
+	// maxRunWait caps how long TriggerRun?wait=true blocks for a run to finish.
+	maxRunWait = 30 * time.Minute
+	// runWaitPollInterval is how often a waiting trigger re-checks the run.
+	runWaitPollInterval = time.Second
+	// idempotencyWindow is how long an Idempotency-Key maps to the run it created.
+	idempotencyWindow = 24 * time.Hour
+	// maxIdempotencyKeyLen bounds the Idempotency-Key header.
+	maxIdempotencyKeyLen = 255
+	// defaultKillTimeout is the SIGTERM grace period when KillRun gets none.
+	defaultKillTimeout = 10
+	// maxKillTimeout caps the grace period a KillRun request may ask for.
+	maxKillTimeout = 300
+	// maxRunLabels bounds how many labels a run may carry.
+	maxRunLabels = 64
+	// maxJSONFullLogBytes bounds the full logs GetRunLogs parses into lines
+	// for format=json; larger ones are only served as plain text.
+	maxJSONFullLogBytes = 32 << 20
+)
+
+// listRunsLimit is the page size of ListRuns.
+const listRunsLimit = 50
+
+// cliUserAgent prefixes the User-Agent sent by the orbex CLI, which marks the
+// runs it triggers with trigger_source "cli".
+const cliUserAgent = "orbex-cli"
+
+// runWaitMsColumn computes a run's time in the queue, NULL until it starts.
+const runWaitMsColumn = `(EXTRACT(EPOCH FROM started_at - created_at) * 1000)::bigint`
+
 // RunHandler handles job run operations.
 type RunHandler struct {
-	db     *database.DB
-	docker *docker.Client
+	db      *database.DB
+	hosts   *docker.Hosts
+	logs    *logstore.Stores
+	storage *storage.Client   // Holds full logs of runs from before the log store; may be nil
+	envKeys *envcrypt.Keyring // Seals per-run env at rest; nil = stored in plaintext
+
+	maxRunMemoryMB      int // Caps per-run memory overrides (0 = unlimited)
+	maxRunCPUMillicores int // Caps per-run CPU overrides (0 = unlimited)
+
+	maxPauseDuration  time.Duration // Default and longest pause
+	pauseExpiryAction string        // What happens when a pause runs out, unless the pause says
 }
 
 // NewRunHandler creates a new RunHandler.
-func NewRunHandler(db *database.DB, dockerClient *docker.Client) *RunHandler {
-	return &RunHandler{db: db, docker: dockerClient}
+func NewRunHandler(db *database.DB, dockerHosts *docker.Hosts, logStore *logstore.Stores, storageClient *storage.Client, cfg *config.Config) *RunHandler {
+	return &RunHandler{
+		db:                  db,
+		hosts:               dockerHosts,
+		logs:                logStore,
+		storage:             storageClient,
+		envKeys:             cfg.EnvKeys,
+		maxRunMemoryMB:      cfg.MaxRunMemoryMB,
+		maxRunCPUMillicores: cfg.MaxRunCPUMillicores,
+		maxPauseDuration:    cfg.MaxPauseDuration,
+		pauseExpiryAction:   cfg.PauseExpiryAction,
+	}
+}
+
+// dockerFor returns the client for the daemon a run was placed on. Runs on a
+// host that is no longer configured fall back to the default daemon.
+func (h *RunHandler) dockerFor(host *string) *docker.Client {
+	if host != nil {
+		if dc, ok := h.hosts.Get(*host); ok {
+			return dc
+		}
+	}
+	return h.hosts.Default
 }
 
 // TriggerRun starts a new run for a job.
@@ -36,14 +112,13 @@ func (h *RunHandler) TriggerRun(w http.ResponseWriter, r *http.Request) {
 
 	// Fetch job definition
 	var job models.Job
-	var envJSON []byte
 	err = h.db.Pool.QueryRow(r.Context(), `
-		SELECT id, user_id, name, image, command, env, memory_mb, cpu_millicores, timeout_seconds
+		SELECT id, user_id, name, image, command, memory_mb, cpu_millicores, timeout_seconds
 		FROM jobs
 		WHERE id = $1 AND user_id = $2 AND is_active = true
 	`, jobID, user.ID).Scan(
 		&job.ID, &job.UserID, &job.Name, &job.Image, &job.Command,
-		&envJSON, &job.MemoryMB, &job.CPUMillicores, &job.TimeoutSeconds,
+		&job.MemoryMB, &job.CPUMillicores, &job.TimeoutSeconds,
 	)
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
@@ -51,37 +126,95 @@ func (h *RunHandler) TriggerRun(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	_ = json.Unmarshal(envJSON, &job.Env)
 
-	// Create job run record
-	var run models.JobRun
-	err = h.db.Pool.QueryRow(r.Context(), `
-		INSERT INTO job_runs (job_id, user_id, status)
-		VALUES ($1, $2, 'pending'::run_status)
-		RETURNING id, job_id, user_id, status, created_at
-	`, job.ID, user.ID).Scan(&run.ID, &run.JobID, &run.UserID, &run.Status, &run.CreatedAt)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error", Message: "Failed to create run",
+	var req models.TriggerRunRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &req) {
+		return
+	}
+	if msg := validateLabels(req.Labels); msg != "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "validation_error", Message: msg,
 		})
 		return
 	}
+	if errs := h.validateRunOverrides(&req); len(errs) > 0 {
+		errs.write(w)
+		return
+	}
 
-	// Enqueue the run
-	_, err = h.db.Pool.Exec(r.Context(), `
-		INSERT INTO job_queue (job_id, run_id)
-		VALUES ($1, $2)
-	`, job.ID, run.ID)
+	idempotencyKey, ok := idempotencyKeyFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// The CLI identifies itself by User-Agent; other callers count as the API
+	source := models.TriggerAPI
+	if strings.HasPrefix(r.UserAgent(), cliUserAgent) {
+		source = models.TriggerCLI
+	}
+
+	// Create and enqueue the run — worker picks it up via SKIP LOCKED polling
+	run, created, err := h.enqueueRun(r.Context(), job.ID, user.ID, idempotencyKey, source, req.Labels, runOverrides{
+		Env:           req.Env,
+		Stdin:         req.Stdin,
+		MemoryMB:      req.MemoryMB,
+		CPUMillicores: req.CPUMillicores,
+	})
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error", Message: "Failed to enqueue run",
+			Error: "internal_error", Message: "Failed to create run",
 		})
 		return
 	}
 
-	// Worker will pick this up via SKIP LOCKED polling
+	if r.URL.Query().Get("wait") == "true" {
+		h.waitForRun(w, r, run.ID, user.ID)
+		return
+	}
 
-	writeJSON(w, http.StatusAccepted, run)
+	writeRunCreated(w, run, created)
+}
+
+// waitForRun blocks until a run reaches a terminal state and writes it with 200.
+// The wait is bounded by ?timeout= (seconds, capped at maxRunWait); if it
+// expires first, the run's current state is written with 202 instead.
+func (h *RunHandler) waitForRun(w http.ResponseWriter, r *http.Request, runID, userID uuid.UUID) {
+	wait := maxRunWait
+	if secs, err := strconv.Atoi(r.URL.Query().Get("timeout")); err == nil && secs > 0 && time.Duration(secs)*time.Second < wait {
+		wait = time.Duration(secs) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+
+	ticker := time.NewTicker(runWaitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			run, err := h.fetchRun(context.WithoutCancel(ctx), runID, userID)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+					Error: "internal_error", Message: "Failed to fetch run",
+				})
+				return
+			}
+			writeJSON(w, http.StatusAccepted, run)
+			return
+		case <-ticker.C:
+			run, err := h.fetchRun(ctx, runID, userID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+					Error: "not_found", Message: "Run not found",
+				})
+				return
+			}
+			if err == nil && run.Status.IsTerminal() {
+				writeJSON(w, http.StatusOK, run)
+				return
+			}
+		}
+	}
 }
 
 // WebhookTrigger accepts a webhook token to trigger a job run without API key auth.
@@ -96,14 +229,13 @@ func (h *RunHandler) WebhookTrigger(w http.ResponseWriter, r *http.Request) {
 
 	// Look up job by webhook token
 	var job models.Job
-	var envJSON []byte
 	err := h.db.Pool.QueryRow(r.Context(), `
-		SELECT id, user_id, name, image, command, env, memory_mb, cpu_millicores, timeout_seconds
+		SELECT id, user_id, name, image, command, memory_mb, cpu_millicores, timeout_seconds
 		FROM jobs
 		WHERE webhook_token = $1 AND is_active = true
 	`, token).Scan(
 		&job.ID, &job.UserID, &job.Name, &job.Image, &job.Command,
-		&envJSON, &job.MemoryMB, &job.CPUMillicores, &job.TimeoutSeconds,
+		&job.MemoryMB, &job.CPUMillicores, &job.TimeoutSeconds,
 	)
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
@@ -111,15 +243,13 @@ func (h *RunHandler) WebhookTrigger(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	_ = json.Unmarshal(envJSON, &job.Env)
 
-	// Create job run record
-	var run models.JobRun
-	err = h.db.Pool.QueryRow(r.Context(), `
-		INSERT INTO job_runs (job_id, user_id, status)
-		VALUES ($1, $2, 'pending'::run_status)
-		RETURNING id, job_id, user_id, status, created_at
-	`, job.ID, job.UserID).Scan(&run.ID, &run.JobID, &run.UserID, &run.Status, &run.CreatedAt)
+	idempotencyKey, ok := idempotencyKeyFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	run, created, err := h.enqueueRun(r.Context(), job.ID, job.UserID, idempotencyKey, models.TriggerWebhook, map[string]string{"source": "webhook"}, runOverrides{})
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
 			Error: "internal_error", Message: "Failed to create run",
@@ -127,21 +257,168 @@ func (h *RunHandler) WebhookTrigger(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Enqueue
-	_, err = h.db.Pool.Exec(r.Context(), `
-		INSERT INTO job_queue (job_id, run_id) VALUES ($1, $2)
-	`, job.ID, run.ID)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error", Message: "Failed to enqueue run",
+	writeRunCreated(w, run, created)
+}
+
+// idempotencyKeyFromRequest reads the optional Idempotency-Key header. It
+// writes a 400 and returns false if the key is too long.
+func idempotencyKeyFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := r.Header.Get("Idempotency-Key")
+	if len(key) > maxIdempotencyKeyLen {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Idempotency-Key must be at most 255 characters",
 		})
-		return
+		return "", false
 	}
+	return key, true
+}
 
+// validateLabels checks run labels supplied at trigger time and returns a
+// message describing the first problem, or "" if they are acceptable.
+func validateLabels(labels map[string]string) string {
+	if len(labels) > maxRunLabels {
+		return fmt.Sprintf("At most %d labels are allowed", maxRunLabels)
+	}
+	for k, v := range labels {
+		if k == "" || len(k) > 63 {
+			return "Label keys must be 1-63 characters"
+		}
+		if len(v) > 255 {
+			return fmt.Sprintf("Label %q value exceeds 255 characters", k)
+		}
+	}
+	return ""
+}
+
+// writeRunCreated responds to a trigger: 202 for a newly queued run, or 200
+// with Idempotent-Replayed set when an earlier run was returned for the key.
+func writeRunCreated(w http.ResponseWriter, run models.JobRun, created bool) {
+	if !created {
+		w.Header().Set("Idempotent-Replayed", "true")
+		writeJSON(w, http.StatusOK, run)
+		return
+	}
 	writeJSON(w, http.StatusAccepted, run)
 }
 
-// ListRuns returns all runs for a job.
+// runOverrides are per-run replacements for job settings given at trigger time.
+type runOverrides struct {
+	Env           map[string]string // Layered over the job's env
+	Stdin         *string
+	MemoryMB      *int
+	CPUMillicores *int
+}
+
+// validateRunOverrides checks a trigger's env keys and its memory/cpu
+// overrides against the operator limits, converting memory/cpu given in
+// units into memory_mb and cpu_millicores.
+func (h *RunHandler) validateRunOverrides(req *models.TriggerRunRequest) fieldErrors {
+	var errs fieldErrors
+	checkEnvKeys(&errs, req.Env, nil)
+	if req.Memory != nil {
+		if mb, err := parseMemoryMB(*req.Memory); err != nil || req.MemoryMB != nil {
+			errs.add("memory", "%s", resourceUnitError("memory", "memory_mb", err))
+		} else {
+			req.MemoryMB = &mb
+		}
+	}
+	if req.CPU != nil {
+		if millicores, err := parseCPUMillicores(*req.CPU); err != nil || req.CPUMillicores != nil {
+			errs.add("cpu", "%s", resourceUnitError("cpu", "cpu_millicores", err))
+		} else {
+			req.CPUMillicores = &millicores
+		}
+	}
+	if req.MemoryMB != nil {
+		if *req.MemoryMB <= 0 {
+			errs.add("memory_mb", "memory_mb must be positive")
+		} else if h.maxRunMemoryMB > 0 && *req.MemoryMB > h.maxRunMemoryMB {
+			errs.add("memory_mb", "memory_mb must be at most %d", h.maxRunMemoryMB)
+		}
+	}
+	if req.CPUMillicores != nil {
+		if *req.CPUMillicores <= 0 {
+			errs.add("cpu_millicores", "cpu_millicores must be positive")
+		} else if h.maxRunCPUMillicores > 0 && *req.CPUMillicores > h.maxRunCPUMillicores {
+			errs.add("cpu_millicores", "cpu_millicores must be at most %d", h.maxRunCPUMillicores)
+		}
+	}
+	return errs
+}
+
+// enqueueRun creates a pending run for a job and queues it in one transaction.
+// When idempotencyKey is set and the job already has a run created with that
+// key within idempotencyWindow, the existing run is returned with created=false.
+// Non-nil fields of overrides replace the job's settings for this run.
+func (h *RunHandler) enqueueRun(ctx context.Context, jobID, userID uuid.UUID, idempotencyKey string, source models.TriggerSource, labels map[string]string, overrides runOverrides) (run models.JobRun, created bool, err error) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		return run, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if idempotencyKey != "" {
+		// Serialize concurrent retries carrying the same key
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, jobID.String()+":"+idempotencyKey); err != nil {
+			return run, false, err
+		}
+		err = tx.QueryRow(ctx, `
+			SELECT id, job_id, user_id, status, labels, trigger_source, created_at
+			FROM job_runs
+			WHERE job_id = $1 AND idempotency_key = $2 AND created_at > $3
+			ORDER BY created_at DESC
+			LIMIT 1
+		`, jobID, idempotencyKey, time.Now().Add(-idempotencyWindow)).Scan(
+			&run.ID, &run.JobID, &run.UserID, &run.Status, &run.Labels, &run.TriggerSource, &run.CreatedAt,
+		)
+		if err == nil {
+			return run, false, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return run, false, err
+		}
+	}
+
+	// The ID is chosen up front so a sealed env can be bound to its run
+	runID := uuid.New()
+	var envJSON, envSealed []byte
+	var envKeyID *string
+	if len(overrides.Env) > 0 {
+		if envJSON, envSealed, envKeyID, err = h.envKeys.EncodeEnv(overrides.Env, runID); err != nil {
+			return run, false, err
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO job_runs (id, job_id, user_id, status, idempotency_key, labels, request_id, stdin, memory_mb, cpu_millicores, trigger_source, env, env_sealed, env_key_id)
+		VALUES ($1, $2, $3, 'pending'::run_status, NULLIF($4, ''), $5, NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, job_id, user_id, status, labels, request_id, memory_mb, cpu_millicores, trigger_source, created_at
+	`, runID, jobID, userID, idempotencyKey, labels, middleware.GetReqID(ctx), overrides.Stdin, overrides.MemoryMB, overrides.CPUMillicores, source, envJSON, envSealed, envKeyID).Scan(
+		&run.ID, &run.JobID, &run.UserID, &run.Status, &run.Labels, &run.RequestID, &run.MemoryMB, &run.CPUMillicores, &run.TriggerSource, &run.CreatedAt,
+	)
+	if err != nil {
+		return run, false, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO job_queue (job_id, run_id) VALUES ($1, $2)
+	`, jobID, run.ID); err != nil {
+		return run, false, err
+	}
+	if err := database.NotifyQueue(ctx, tx); err != nil {
+		return run, false, err
+	}
+
+	return run, true, tx.Commit(ctx)
+}
+
+// ListRuns returns a job's runs, newest first, listRunsLimit at a time. Page
+// with ?before=<next_cursor>; with ?envelope=true the response carries the
+// total and the cursor of the next page.
 func (h *RunHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
 	user := UserFromContext(r.Context())
 	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
@@ -152,14 +429,44 @@ func (h *RunHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ?label=key=value (repeatable) keeps runs carrying all the given labels
+	labelFilter := map[string]string{}
+	for _, l := range r.URL.Query()["label"] {
+		k, v, ok := strings.Cut(l, "=")
+		if !ok || k == "" {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid_request", Message: "label filter must be key=value",
+			})
+			return
+		}
+		labelFilter[k] = v
+	}
+	var before *time.Time
+	var beforeID uuid.UUID
+	if v := r.URL.Query().Get("before"); v != "" {
+		t, id, err := parseRunCursor(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid_request", Message: err.Error(),
+			})
+			return
+		}
+		before, beforeID = &t, id
+	}
+	if !requireOwnedJob(w, r, h.db, jobID, user.ID) {
+		return
+	}
+
 	rows, err := h.db.Pool.Query(r.Context(), `
 		SELECT id, job_id, user_id, status, container_id, exit_code, error_message,
-		       started_at, finished_at, paused_at, duration_ms, created_at
+		       started_at, finished_at, paused_at, duration_ms, stop_signal, failure_reason, labels,
+		       trigger_source, attempt, version, `+runWaitMsColumn+`, created_at
 		FROM job_runs
-		WHERE job_id = $1 AND user_id = $2
-		ORDER BY created_at DESC
-		LIMIT 50
-	`, jobID, user.ID)
+		WHERE job_id = $1 AND user_id = $2 AND labels @> $3::jsonb
+		  AND ($4::timestamptz IS NULL OR (created_at, id) < ($4, $5))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $6
+	`, jobID, user.ID, labelFilter, before, beforeID, listRunsLimit)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
 			Error: "internal_error", Message: "Failed to list runs",
@@ -174,7 +481,8 @@ func (h *RunHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
 		if err := rows.Scan(
 			&run.ID, &run.JobID, &run.UserID, &run.Status, &run.ContainerID,
 			&run.ExitCode, &run.ErrorMessage, &run.StartedAt, &run.FinishedAt,
-			&run.PausedAt, &run.DurationMs, &run.CreatedAt,
+			&run.PausedAt, &run.DurationMs, &run.StopSignal, &run.FailureReason, &run.Labels,
+			&run.TriggerSource, &run.Attempt, &run.Version, &run.WaitMs, &run.CreatedAt,
 		); err != nil {
 			continue
 		}
@@ -184,10 +492,154 @@ func (h *RunHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
 	if runs == nil {
 		runs = []models.JobRun{}
 	}
-	writeJSON(w, http.StatusOK, runs)
+
+	var page models.Pagination
+	if wantsEnvelope(r) {
+		if err := h.db.Pool.QueryRow(r.Context(), `
+			SELECT count(*) FROM job_runs WHERE job_id = $1 AND user_id = $2 AND labels @> $3::jsonb
+		`, jobID, user.ID, labelFilter).Scan(&page.Total); err != nil {
+			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+				Error: "internal_error", Message: "Failed to list runs",
+			})
+			return
+		}
+		if len(runs) == listRunsLimit {
+			page.NextCursor = keysetCursor(runs[len(runs)-1].CreatedAt, runs[len(runs)-1].ID)
+		}
+	}
+	writeList(w, r, runs, page)
+}
+
+// DeleteRuns deletes a job's finished runs matching ?older_than= (e.g. "30d",
+// "12h") and/or ?status= (succeeded, failed or cancelled) in one query, along
+// with their stored logs, and returns the number deleted. At least one filter
+// is required. Runs that are still pending, running or paused are never
+// deleted.
+func (h *RunHandler) DeleteRuns(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid job ID",
+		})
+		return
+	}
+
+	q := r.URL.Query()
+	if q.Get("older_than") == "" && q.Get("status") == "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "older_than (e.g. 30d or 12h) or status is required",
+		})
+		return
+	}
+	var cutoff *time.Time
+	if v := q.Get("older_than"); v != "" {
+		olderThan, err := parseAge(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid_request", Message: "older_than must be an age such as 30d or 12h",
+			})
+			return
+		}
+		t := time.Now().Add(-olderThan)
+		cutoff = &t
+	}
+	var status *models.RunStatus
+	if v := q.Get("status"); v != "" {
+		s := models.RunStatus(v)
+		if !s.IsTerminal() {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid_request", Message: "status must be one of: succeeded, failed, cancelled",
+			})
+			return
+		}
+		status = &s
+	}
+	if !requireOwnedJob(w, r, h.db, jobID, user.ID) {
+		return
+	}
+
+	rows, err := h.db.Pool.Query(r.Context(), `
+		DELETE FROM job_runs
+		WHERE job_id = $1 AND user_id = $2
+		  AND status IN ('succeeded'::run_status, 'failed'::run_status, 'cancelled'::run_status)
+		  AND ($3::timestamptz IS NULL OR created_at < $3)
+		  AND ($4::run_status IS NULL OR status = $4)
+		RETURNING id, full_logs_backend, logs_object_key
+	`, jobID, user.ID, cutoff, status)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to delete runs",
+		})
+		return
+	}
+	type deletedRun struct {
+		ID              uuid.UUID
+		FullLogsBackend *string
+		LogsObjectKey   *string
+	}
+	deleted, err := pgx.CollectRows(rows, pgx.RowToStructByPos[deletedRun])
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to delete runs",
+		})
+		return
+	}
+
+	// Full logs kept in the log store go with their runs. The rows are gone
+	// already, so this must finish even if the client hangs up: nothing else
+	// would find the logs again.
+	ctx := context.WithoutCancel(r.Context())
+	if h.logs != nil {
+		for _, run := range deleted {
+			if run.FullLogsBackend == nil {
+				continue
+			}
+			if err := h.logs.Delete(ctx, *run.FullLogsBackend, run.ID); err != nil {
+				log.Printf("[api] ERROR deleting full logs of run %s: %v", run.ID, err)
+			}
+		}
+	}
+	if h.storage != nil {
+		for _, run := range deleted {
+			if run.LogsObjectKey == nil {
+				continue
+			}
+			if err := h.storage.Delete(ctx, *run.LogsObjectKey); err != nil {
+				log.Printf("[api] ERROR deleting logs %s of run %s: %v", *run.LogsObjectKey, run.ID, err)
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"deleted": len(deleted)})
 }
 
-// GetRun returns details of a specific run.
+// liveLogs reads a running or paused run's logs from its container. When the
+// container is gone (the run is finishing or was cleaned up) it reports
+// ok=false so the caller falls back to the stored logs; any other Docker
+// failure is returned, since stored logs would be stale.
+func (h *RunHandler) liveLogs(ctx context.Context, runID uuid.UUID, dockerHost *string, containerID, tail, since string) (logs string, ok bool, err error) {
+	logs, err = h.dockerFor(dockerHost).GetLogs(ctx, containerID, tail, since)
+	switch {
+	case err == nil:
+		return logs, true, nil
+	case errors.Is(err, docker.ErrContainerNotFound):
+		log.Printf("[api] Container of run %s is gone, serving stored logs", runID)
+		return "", false, nil
+	default:
+		log.Printf("[api] ERROR reading live logs of run %s: %v", runID, err)
+		return "", false, err
+	}
+}
+
+// writeLiveLogsError reports a failure to read live logs from Docker.
+func writeLiveLogsError(w http.ResponseWriter, err error) {
+	writeJSON(w, http.StatusBadGateway, models.ErrorResponse{
+		Error: "docker_error", Message: fmt.Sprintf("Failed to read live logs from Docker: %v", err),
+	})
+}
+
+// GetRun returns details of a specific run, honoring If-None-Match.
 func (h *RunHandler) GetRun(w http.ResponseWriter, r *http.Request) {
 	user := UserFromContext(r.Context())
 	runID, err := uuid.Parse(chi.URLParam(r, "runID"))
@@ -198,28 +650,172 @@ func (h *RunHandler) GetRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	run, err := h.fetchRun(r.Context(), runID, user.ID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Run not found",
+		})
+		return
+	}
+	if run.Status == models.RunStatusPending {
+		run.QueuePosition = h.queuePosition(r.Context(), runID)
+	}
+
+	writeJSONWithETag(w, r, run)
+}
+
+// queuePosition returns where a pending run stands in the queue, counting
+// the items a worker would claim before it, in the claim query's order:
+// priority first, then oldest scheduled_at, skipping items whose run is no
+// longer pending. Items not yet due (retries waiting out their backoff) only
+// count if they come due by the time this run does. It returns nil if the
+// run isn't waiting in the queue.
+func (h *RunHandler) queuePosition(ctx context.Context, runID uuid.UUID) *int {
+	var pos int
+	err := h.db.Pool.QueryRow(ctx, `
+		SELECT 1 + (
+			SELECT count(*) FROM job_queue o
+			JOIN job_runs r ON r.id = o.run_id
+			WHERE o.picked_at IS NULL
+			  AND r.status = 'pending'::run_status
+			  AND o.scheduled_at <= GREATEST(now(), q.scheduled_at)
+			  AND (o.priority > q.priority
+			    OR (o.priority = q.priority AND o.scheduled_at < q.scheduled_at))
+		)
+		FROM job_queue q
+		WHERE q.run_id = $1 AND q.picked_at IS NULL
+	`, runID).Scan(&pos)
+	if err != nil {
+		return nil
+	}
+	return &pos
+}
+
+// fetchRun loads a single run (including its stored logs) owned by userID.
+func (h *RunHandler) fetchRun(ctx context.Context, runID, userID uuid.UUID) (models.JobRun, error) {
 	var run models.JobRun
-	err = h.db.Pool.QueryRow(r.Context(), `
+	err := h.db.Pool.QueryRow(ctx, `
 		SELECT id, job_id, user_id, status, container_id, exit_code, error_message,
-		       started_at, finished_at, paused_at, duration_ms, logs_tail, created_at
+		       started_at, finished_at, paused_at, pause_expires_at, pause_expiry_action, duration_ms, logs_tail, stop_signal, failure_reason, result, labels, request_id, image_digest,
+		       container_kept_until, attempt, version, status_detail, docker_host, memory_mb, cpu_millicores,
+		       trigger_source, `+runWaitMsColumn+`, created_at
 		FROM job_runs
 		WHERE id = $1 AND user_id = $2
-	`, runID, user.ID).Scan(
+	`, runID, userID).Scan(
 		&run.ID, &run.JobID, &run.UserID, &run.Status, &run.ContainerID,
 		&run.ExitCode, &run.ErrorMessage, &run.StartedAt, &run.FinishedAt,
-		&run.PausedAt, &run.DurationMs, &run.LogsTail, &run.CreatedAt,
+		&run.PausedAt, &run.PauseExpiresAt, &run.PauseExpiryAction, &run.DurationMs, &run.LogsTail, &run.StopSignal, &run.FailureReason, &run.Result, &run.Labels, &run.RequestID, &run.ImageDigest,
+		&run.ContainerKeptUntil, &run.Attempt, &run.Version, &run.StatusDetail, &run.DockerHost,
+		&run.MemoryMB, &run.CPUMillicores, &run.TriggerSource, &run.WaitMs, &run.CreatedAt,
 	)
+	return run, err
+}
+
+// InspectRun returns debugging details of the run's container: its state,
+// restart count, networks and mounts. The container has to still exist, so
+// this works for in-flight runs and for failed runs whose container was kept.
+func (h *RunHandler) InspectRun(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	runID, err := uuid.Parse(chi.URLParam(r, "runID"))
 	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid run ID",
+		})
+		return
+	}
+
+	var containerID, dockerHost *string
+	err = h.db.Pool.QueryRow(r.Context(), `
+		SELECT container_id, docker_host FROM job_runs WHERE id = $1 AND user_id = $2
+	`, runID, user.ID).Scan(&containerID, &dockerHost)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Run not found",
+		})
+		return
+	}
+	if containerID == nil {
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{
+			Error: "invalid_state", Message: "No container associated with this run",
+		})
+		return
+	}
+
+	details, err := h.dockerFor(dockerHost).InspectDetails(r.Context(), *containerID)
+	if errors.Is(err, docker.ErrContainerNotFound) {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "The run's container no longer exists",
+		})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to inspect container",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, details)
+}
+
+// UpdatePriority changes the queue priority of a run that no worker has
+// picked up yet. Workers claim runs in priority order, so raising it lets an
+// urgent run jump ahead of the rest of the queue.
+func (h *RunHandler) UpdatePriority(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	runID, err := uuid.Parse(chi.URLParam(r, "runID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid run ID",
+		})
+		return
+	}
+
+	var req models.UpdateRunPriorityRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Priority == nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "validation_error", Message: "priority is required",
+		})
+		return
+	}
+
+	var item models.QueueItem
+	err = h.db.Pool.QueryRow(r.Context(), `
+		UPDATE job_queue q SET priority = $1
+		FROM job_runs jr
+		WHERE q.run_id = jr.id AND jr.id = $2 AND jr.user_id = $3 AND q.picked_at IS NULL
+		RETURNING q.id, q.job_id, q.run_id, q.priority, q.scheduled_at, q.picked_at, q.created_at
+	`, *req.Priority, runID, user.ID).Scan(
+		&item.ID, &item.JobID, &item.RunID, &item.Priority, &item.ScheduledAt, &item.PickedAt, &item.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Distinguish a run that exists but has left the queue
+		if _, fetchErr := h.fetchRun(r.Context(), runID, user.ID); fetchErr == nil {
+			writeJSON(w, http.StatusConflict, models.ErrorResponse{
+				Error: "invalid_state", Message: "Run is no longer queued",
+			})
+			return
+		}
 		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
 			Error: "not_found", Message: "Run not found",
 		})
 		return
 	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to update priority",
+		})
+		return
+	}
 
-	writeJSON(w, http.StatusOK, run)
+	writeJSON(w, http.StatusOK, item)
 }
 
-// PauseRun pauses a running job.
+// PauseRun pauses a running job. The pause lasts timeout_seconds (default
+// and at most MAX_PAUSE_DURATION); the reaper then resumes or kills the run,
+// per on_expiry.
 func (h *RunHandler) PauseRun(w http.ResponseWriter, r *http.Request) {
 	user := UserFromContext(r.Context())
 	runID, err := uuid.Parse(chi.URLParam(r, "runID"))
@@ -229,12 +825,16 @@ func (h *RunHandler) PauseRun(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	pauseFor, onExpiry, ok := h.decodePause(w, r)
+	if !ok {
+		return
+	}
 
-	var containerID *string
+	var containerID, dockerHost *string
 	var status models.RunStatus
 	err = h.db.Pool.QueryRow(r.Context(), `
-		SELECT container_id, status FROM job_runs WHERE id = $1 AND user_id = $2
-	`, runID, user.ID).Scan(&containerID, &status)
+		SELECT container_id, docker_host, status FROM job_runs WHERE id = $1 AND user_id = $2
+	`, runID, user.ID).Scan(&containerID, &dockerHost, &status)
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
 			Error: "not_found", Message: "Run not found",
@@ -256,24 +856,67 @@ func (h *RunHandler) PauseRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.docker.PauseContainer(r.Context(), *containerID); err != nil {
+	if err := h.dockerFor(dockerHost).PauseContainer(r.Context(), *containerID); err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
 			Error: "internal_error", Message: "Failed to pause container",
 		})
 		return
 	}
 
+	// Pausing doesn't bump the run's version (the worker still owns the run),
+	// but it must not overwrite a status the run reached in the meantime.
 	now := time.Now()
-	_, _ = h.db.Pool.Exec(r.Context(), `
-		UPDATE job_runs SET status = 'paused'::run_status, paused_at = $1 WHERE id = $2
-	`, now, runID)
+	tag, err := h.db.Pool.Exec(r.Context(), `
+		UPDATE job_runs SET status = 'paused'::run_status, paused_at = $1,
+			pause_expires_at = $2, pause_expiry_action = $3
+		WHERE id = $4 AND status = 'running'::run_status
+	`, now, now.Add(pauseFor), onExpiry, runID)
+	if err == nil && tag.RowsAffected() == 0 {
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{
+			Error: "conflict", Message: "Run finished while it was being paused",
+		})
+		return
+	}
 
 	writeJSON(w, http.StatusOK, map[string]string{
-		"status":  "paused",
-		"message": "Container paused. Use resume to continue or kill to terminate.",
+		"status":           "paused",
+		"message":          "Container paused. Use resume to continue or kill to terminate.",
+		"pause_expires_at": now.Add(pauseFor).UTC().Format(time.RFC3339),
+		"on_expiry":        onExpiry,
 	})
 }
 
+// decodePause reads the optional PauseRunRequest body and returns how long
+// the pause lasts and what happens after. On a bad request it writes the
+// error and returns false.
+func (h *RunHandler) decodePause(w http.ResponseWriter, r *http.Request) (time.Duration, string, bool) {
+	var req models.PauseRunRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &req) {
+		return 0, "", false
+	}
+	var errs fieldErrors
+	pauseFor := h.maxPauseDuration
+	if req.TimeoutSeconds != nil {
+		d := time.Duration(*req.TimeoutSeconds) * time.Second
+		if d <= 0 || d > h.maxPauseDuration {
+			errs.add("timeout_seconds", "timeout_seconds must be between 1 and %d", int(h.maxPauseDuration.Seconds()))
+		}
+		pauseFor = d
+	}
+	onExpiry := h.pauseExpiryAction
+	if req.OnExpiry != nil {
+		if *req.OnExpiry != "resume" && *req.OnExpiry != "kill" {
+			errs.add("on_expiry", "on_expiry must be resume or kill")
+		}
+		onExpiry = *req.OnExpiry
+	}
+	if len(errs) > 0 {
+		errs.write(w)
+		return 0, "", false
+	}
+	return pauseFor, onExpiry, true
+}
+
 // ResumeRun resumes a paused job.
 func (h *RunHandler) ResumeRun(w http.ResponseWriter, r *http.Request) {
 	user := UserFromContext(r.Context())
@@ -285,11 +928,11 @@ func (h *RunHandler) ResumeRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var containerID *string
+	var containerID, dockerHost *string
 	var status models.RunStatus
 	err = h.db.Pool.QueryRow(r.Context(), `
-		SELECT container_id, status FROM job_runs WHERE id = $1 AND user_id = $2
-	`, runID, user.ID).Scan(&containerID, &status)
+		SELECT container_id, docker_host, status FROM job_runs WHERE id = $1 AND user_id = $2
+	`, runID, user.ID).Scan(&containerID, &dockerHost, &status)
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
 			Error: "not_found", Message: "Run not found",
@@ -311,16 +954,24 @@ func (h *RunHandler) ResumeRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.docker.UnpauseContainer(r.Context(), *containerID); err != nil {
+	if err := h.dockerFor(dockerHost).UnpauseContainer(r.Context(), *containerID); err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
 			Error: "internal_error", Message: "Failed to resume container",
 		})
 		return
 	}
 
-	_, _ = h.db.Pool.Exec(r.Context(), `
-		UPDATE job_runs SET status = 'running'::run_status, paused_at = NULL WHERE id = $1
+	tag, err := h.db.Pool.Exec(r.Context(), `
+		UPDATE job_runs SET status = 'running'::run_status, paused_at = NULL,
+			pause_expires_at = NULL, pause_expiry_action = NULL
+		WHERE id = $1 AND status = 'paused'::run_status
 	`, runID)
+	if err == nil && tag.RowsAffected() == 0 {
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{
+			Error: "conflict", Message: "Run finished while it was being resumed",
+		})
+		return
+	}
 
 	writeJSON(w, http.StatusOK, map[string]string{
 		"status":  "running",
@@ -339,12 +990,15 @@ func (h *RunHandler) KillRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var containerID *string
-	var status models.RunStatus
-	var startedAt *time.Time
+	stopTimeout, ok := decodeKillTimeout(w, r)
+	if !ok {
+		return
+	}
+
+	var target killTarget
 	err = h.db.Pool.QueryRow(r.Context(), `
-		SELECT container_id, status, started_at FROM job_runs WHERE id = $1 AND user_id = $2
-	`, runID, user.ID).Scan(&containerID, &status, &startedAt)
+		SELECT id, container_id, docker_host, status, started_at, version FROM job_runs WHERE id = $1 AND user_id = $2
+	`, runID, user.ID).Scan(&target.runID, &target.containerID, &target.dockerHost, &target.status, &target.startedAt, &target.version)
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
 			Error: "not_found", Message: "Run not found",
@@ -352,42 +1006,396 @@ func (h *RunHandler) KillRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if status != models.RunStatusRunning && status != models.RunStatusPaused {
+	if target.status != models.RunStatusRunning && target.status != models.RunStatusPaused {
 		writeJSON(w, http.StatusConflict, models.ErrorResponse{
 			Error: "invalid_state", Message: "Can only kill running or paused jobs",
 		})
 		return
 	}
 
-	if containerID != nil {
-		if status == models.RunStatusPaused {
-			_ = h.docker.UnpauseContainer(r.Context(), *containerID)
+	stopSignal, exitCode, err := h.killRun(r.Context(), target, stopTimeout)
+	if errors.Is(err, database.ErrVersionConflict) {
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{
+			Error: "conflict", Message: "Run finished before it could be killed",
+		})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to kill run",
+		})
+		return
+	}
+
+	resp := map[string]interface{}{
+		"status":  "cancelled",
+		"message": "Job killed.",
+	}
+	if stopSignal != nil {
+		resp["stop_signal"] = *stopSignal
+		resp["exit_code"] = *exitCode
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// KillJobRuns kills every running or paused run of a job, with the same
+// SIGTERM-then-SIGKILL sequence and optional timeout_seconds as KillRun.
+// Pending runs are left in the queue.
+func (h *RunHandler) KillJobRuns(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	jobID, err := uuid.Parse(chi.URLParam(r, "jobID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid job ID",
+		})
+		return
+	}
+
+	stopTimeout, ok := decodeKillTimeout(w, r)
+	if !ok {
+		return
+	}
+
+	if !requireOwnedJob(w, r, h.db, jobID, user.ID) {
+		return
+	}
+
+	rows, err := h.db.Pool.Query(r.Context(), `
+		SELECT id, container_id, docker_host, status, started_at, version FROM job_runs
+		WHERE job_id = $1 AND user_id = $2
+		  AND status IN ('running'::run_status, 'paused'::run_status)
+	`, jobID, user.ID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to list runs",
+		})
+		return
+	}
+	var targets []killTarget
+	for rows.Next() {
+		var t killTarget
+		if err := rows.Scan(&t.runID, &t.containerID, &t.dockerHost, &t.status, &t.startedAt, &t.version); err != nil {
+			continue
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+
+	// Stop the containers in parallel so the timeouts don't add up. Runs that
+	// finished in the meantime (version conflict) aren't counted.
+	killed := make([]bool, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := h.killRun(r.Context(), t, stopTimeout)
+			killed[i] = err == nil
+		}()
+	}
+	wg.Wait()
+
+	runIDs := make([]uuid.UUID, 0, len(targets))
+	for i, t := range targets {
+		if killed[i] {
+			runIDs = append(runIDs, t.runID)
 		}
-		_ = h.docker.StopContainer(r.Context(), *containerID, 10)
-		_ = h.docker.RemoveContainer(r.Context(), *containerID)
 	}
+	writeJSON(w, http.StatusOK, models.KillJobRunsResponse{
+		Killed: len(runIDs),
+		RunIDs: runIDs,
+	})
+}
+
+// killTarget is a running or paused run about to be killed.
+type killTarget struct {
+	runID       uuid.UUID
+	containerID *string
+	dockerHost  *string
+	status      models.RunStatus
+	startedAt   *time.Time
+	version     int
+}
+
+// decodeKillTimeout reads the optional KillRunRequest body and returns the
+// SIGTERM grace period to use. On a bad request it writes the error and
+// returns false.
+func decodeKillTimeout(w http.ResponseWriter, r *http.Request) (int, bool) {
+	var req models.KillRunRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &req) {
+		return 0, false
+	}
+	if req.TimeoutSeconds == nil {
+		return defaultKillTimeout, true
+	}
+	if *req.TimeoutSeconds < 0 || *req.TimeoutSeconds > maxKillTimeout {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "validation_error", Message: fmt.Sprintf("timeout_seconds must be between 0 and %d", maxKillTimeout),
+		})
+		return 0, false
+	}
+	return *req.TimeoutSeconds, true
+}
 
+// killRun marks a run cancelled and stops its container — SIGTERM first,
+// SIGKILL once stopTimeout expires. It reports which signal ended the
+// container and its exit code, or nils if that couldn't be determined.
+//
+// The run is marked cancelled before its container is stopped, guarded by
+// t.version, so the worker seeing its container exit can't record a failure
+// over the kill. If the run already finished, database.ErrVersionConflict is
+// returned and the container is left alone.
+func (h *RunHandler) killRun(ctx context.Context, t killTarget, stopTimeout int) (stopSignal *string, exitCode *int, err error) {
 	now := time.Now()
 	var durationMs int64
-	if startedAt != nil {
-		durationMs = now.Sub(*startedAt).Milliseconds()
+	if t.startedAt != nil {
+		durationMs = now.Sub(*t.startedAt).Milliseconds()
 	}
 
-	_, _ = h.db.Pool.Exec(r.Context(), `
+	var version int
+	err = h.db.Pool.QueryRow(ctx, `
 		UPDATE job_runs
-		SET status = 'cancelled'::run_status, finished_at = $1, duration_ms = $2, error_message = 'Killed by user'
-		WHERE id = $3
-	`, now, durationMs, runID)
+		SET status = 'cancelled'::run_status, finished_at = $1, duration_ms = $2, error_message = 'killed by user',
+		    failure_reason = 'killed', heartbeat_at = NULL, version = version + 1
+		WHERE id = $3 AND version = $4
+		RETURNING version
+	`, now, durationMs, t.runID, t.version).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, database.ErrVersionConflict
+	}
+	if err != nil {
+		return nil, nil, err
+	}
 
-	_, _ = h.db.Pool.Exec(r.Context(), `DELETE FROM job_queue WHERE run_id = $1`, runID)
+	if t.containerID != nil {
+		dc := h.dockerFor(t.dockerHost)
+		if t.status == models.RunStatusPaused {
+			_ = dc.UnpauseContainer(ctx, *t.containerID)
+		}
+		code, forced, err := dc.StopContainerReport(ctx, *t.containerID, stopTimeout)
+		if err == nil {
+			sig := "SIGTERM"
+			if forced {
+				sig = "SIGKILL"
+			}
+			stopSignal, exitCode = &sig, &code
+		}
+		_ = dc.RemoveContainer(ctx, *t.containerID)
+	}
 
-	writeJSON(w, http.StatusOK, map[string]string{
-		"status":  "cancelled",
-		"message": "Job killed.",
-	})
+	if stopSignal != nil {
+		_, _ = h.db.Pool.Exec(ctx, `
+			UPDATE job_runs SET stop_signal = $1, exit_code = $2
+			WHERE id = $3 AND version = $4
+		`, stopSignal, exitCode, t.runID, version)
+	}
+
+	_, _ = h.db.Pool.Exec(ctx, `DELETE FROM job_queue WHERE run_id = $1`, t.runID)
+	return stopSignal, exitCode, nil
+}
+
+// StreamRunEvents streams a run as server-sent "run" events until it reaches
+// a terminal state. An event is sent whenever the run's status or
+// status_detail changes, so clients can show progress such as image pulls.
+func (h *RunHandler) StreamRunEvents(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	runID, err := uuid.Parse(chi.URLParam(r, "runID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid run ID",
+		})
+		return
+	}
+
+	run, err := h.fetchRun(r.Context(), runID, user.ID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Run not found",
+		})
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(runWaitPollInterval)
+	defer ticker.Stop()
+
+	var lastStatus models.RunStatus
+	var lastDetail string
+	for {
+		var detail string
+		if run.StatusDetail != nil {
+			detail = *run.StatusDetail
+		}
+		if run.Status != lastStatus || detail != lastDetail {
+			run.LogsTail = nil // Logs have their own endpoint
+			data, _ := json.Marshal(run)
+			fmt.Fprintf(w, "event: run\ndata: %s\n\n", data)
+			if rc.Flush() != nil {
+				return
+			}
+			lastStatus, lastDetail = run.Status, detail
+		}
+		if run.Status.IsTerminal() {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		if run, err = h.fetchRun(r.Context(), runID, user.ID); err != nil {
+			return
+		}
+	}
 }
 
-// GetRunLogs returns the logs for a run.
+// StreamEvents streams lifecycle changes of all the user's runs as
+// server-sent "run" events: new runs, status changes and progress updates.
+// ?job_id= limits the stream to one job. Runs already in flight when the
+// stream opens are only reported once they change.
+func (h *RunHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	var jobID *uuid.UUID
+	if v := r.URL.Query().Get("job_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid_request", Message: "Invalid job ID",
+			})
+			return
+		}
+		if !requireOwnedJob(w, r, h.db, id, user.ID) {
+			return
+		}
+		jobID = &id
+	}
+
+	var since time.Time
+	if err := h.db.Pool.QueryRow(r.Context(), `SELECT now()`).Scan(&since); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to open event stream",
+		})
+		return
+	}
+	// Seed with the runs in flight so the first poll doesn't replay them
+	seen, _, err := h.pollRunEvents(r.Context(), user.ID, jobID, since, nil)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to open event stream",
+		})
+		return
+	}
+	last := make(map[uuid.UUID]string, len(seen))
+	for _, ev := range seen {
+		last[ev.ID] = runEventState(ev.JobRun)
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if rc.Flush() != nil {
+		return
+	}
+
+	ticker := time.NewTicker(runWaitPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		events, now, err := h.pollRunEvents(r.Context(), user.ID, jobID, since, slices.Collect(maps.Keys(last)))
+		if err != nil {
+			return
+		}
+		for _, ev := range events {
+			state := runEventState(ev.JobRun)
+			if last[ev.ID] == state {
+				continue
+			}
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "event: run\ndata: %s\n\n", data)
+			if ev.Status.IsTerminal() {
+				delete(last, ev.ID)
+			} else {
+				last[ev.ID] = state
+			}
+		}
+		if rc.Flush() != nil {
+			return
+		}
+		since = now
+	}
+}
+
+// pollRunEvents returns the user's runs that are in flight, were created or
+// finished after since, or are among tracked, along with the database time
+// of the query so the next poll can pick up where this one left off. Workers
+// stamp finished_at with their own clock, so a run that was in flight last
+// time is looked up by ID: its finish could otherwise fall before since.
+func (h *RunHandler) pollRunEvents(ctx context.Context, userID uuid.UUID, jobID *uuid.UUID, since time.Time, tracked []uuid.UUID) ([]models.RunEvent, time.Time, error) {
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT r.id, r.job_id, r.user_id, r.status, r.status_detail, r.exit_code, r.error_message,
+		       r.started_at, r.finished_at, r.duration_ms, r.failure_reason, r.created_at, j.name, now()
+		FROM job_runs r
+		JOIN jobs j ON j.id = r.job_id
+		WHERE r.user_id = $1
+		  AND ($2::uuid IS NULL OR r.job_id = $2)
+		  AND (r.status IN ('pending'::run_status, 'running'::run_status, 'paused'::run_status)
+		       OR r.created_at > $3 OR r.finished_at > $3 OR r.id = ANY($4))
+		ORDER BY r.created_at
+	`, userID, jobID, since, tracked)
+	if err != nil {
+		return nil, since, err
+	}
+	defer rows.Close()
+
+	var events []models.RunEvent
+	now := since
+	for rows.Next() {
+		var ev models.RunEvent
+		if err := rows.Scan(
+			&ev.ID, &ev.JobID, &ev.UserID, &ev.Status, &ev.StatusDetail, &ev.ExitCode, &ev.ErrorMessage,
+			&ev.StartedAt, &ev.FinishedAt, &ev.DurationMs, &ev.FailureReason, &ev.CreatedAt, &ev.JobName, &now,
+		); err != nil {
+			return nil, since, err
+		}
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, since, err
+	}
+	if len(events) == 0 {
+		// No rows to read the time from; keep the window where it was
+		return nil, since, nil
+	}
+	return events, now, nil
+}
+
+// runEventState is what StreamEvents compares to decide whether a run changed.
+func runEventState(run models.JobRun) string {
+	state := string(run.Status)
+	if run.StatusDetail != nil {
+		state += "|" + *run.StatusDetail
+	}
+	return state
+}
+
+// GetRunLogs returns the logs for a run. Stored logs are capped in size;
+// ?full=true streams the complete output as text/plain when it was kept in
+// the log store. ?since= (an RFC 3339 time or an age such as "10m") limits
+// live logs to lines written after it; stored logs carry no timestamps, so
+// they are returned whole unless the run finished before since.
+// ?format=json returns the output as lines, parsing those that are JSON
+// objects; ?level= then keeps only lines logged at that level.
 func (h *RunHandler) GetRunLogs(w http.ResponseWriter, r *http.Request) {
 	user := UserFromContext(r.Context())
 	runID, err := uuid.Parse(chi.URLParam(r, "runID"))
@@ -399,11 +1407,53 @@ func (h *RunHandler) GetRunLogs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var containerID *string
-	var logsTail *string
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		since, err = parseSince(s)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error: "invalid_request", Message: "since must be an RFC 3339 time or an age (e.g. 10m or 2d)",
+			})
+			return
+		}
+	}
+	format, level := r.URL.Query().Get("format"), r.URL.Query().Get("level")
+	if format != "" && format != "text" && format != "json" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "format must be text or json",
+		})
+		return
+	}
+	if level != "" && format != "json" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "level filtering requires format=json",
+		})
+		return
+	}
+	var logsTail, dockerHost, fullLogsBackend, logsObjectKey, logDriver *string
+	// writeLogs sends logs as {"logs": ...}, or with format=json as
+	// {"lines": [...]} with JSON lines parsed. For jobs whose output isn't
+	// kept it adds a message saying so.
+	writeLogs := func(logs string) {
+		body := map[string]any{}
+		if format == "json" {
+			body["lines"] = parseLogLines(logs, level)
+		} else {
+			body["logs"] = logs
+		}
+		if discardsLogs(logDriver) {
+			body["message"] = noLogsMessage
+		}
+		writeJSON(w, http.StatusOK, body)
+	}
+
+	var finishedAt *time.Time
 	var status models.RunStatus
 	err = h.db.Pool.QueryRow(r.Context(), `
-		SELECT container_id, logs_tail, status FROM job_runs WHERE id = $1 AND user_id = $2
-	`, runID, user.ID).Scan(&containerID, &logsTail, &status)
+		SELECT r.container_id, r.docker_host, r.logs_tail, r.full_logs_backend, r.logs_object_key, r.finished_at, r.status, j.log_driver
+		FROM job_runs r JOIN jobs j ON j.id = r.job_id
+		WHERE r.id = $1 AND r.user_id = $2
+	`, runID, user.ID).Scan(&containerID, &dockerHost, &logsTail, &fullLogsBackend, &logsObjectKey, &finishedAt, &status, &logDriver)
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
 			Error: "not_found", Message: "Run not found",
@@ -411,19 +1461,169 @@ func (h *RunHandler) GetRunLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If container is still alive, get live logs
-	if containerID != nil && (status == models.RunStatusRunning || status == models.RunStatusPaused) {
-		logs, err := h.docker.GetLogs(r.Context(), *containerID, "1000")
-		if err == nil {
-			writeJSON(w, http.StatusOK, map[string]string{"logs": logs})
+	if r.URL.Query().Get("full") == "true" && h.hasFullLogs(fullLogsBackend, logsObjectKey) {
+		reader, err := h.fullLogs(r.Context(), runID, fullLogsBackend, logsObjectKey)
+		if errors.Is(err, logstore.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+				Error: "not_found", Message: "Full logs not found",
+			})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+				Error: "internal_error", Message: "Failed to load full logs",
+			})
+			return
+		}
+		defer reader.Close()
+		if format == "json" {
+			full, err := io.ReadAll(io.LimitReader(reader, maxJSONFullLogBytes+1))
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+					Error: "internal_error", Message: "Failed to load full logs",
+				})
+				return
+			}
+			if len(full) > maxJSONFullLogBytes {
+				writeJSON(w, http.StatusRequestEntityTooLarge, models.ErrorResponse{
+					Error: "logs_too_large",
+					Message: fmt.Sprintf("Full logs exceed %d bytes, too large for format=json; fetch them without format or download them",
+						maxJSONFullLogBytes),
+				})
+				return
+			}
+			writeLogs(string(full))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if _, err := io.Copy(w, reader); err != nil {
+			// The status is already sent; the client sees a cut-off body
+			log.Printf("[api] ERROR streaming full logs of run %s: %v", runID, err)
+		}
+		return
+	}
+
+	// If container is still alive, get live logs. Docker has none to give
+	// for a container run with the "none" log driver.
+	if containerID != nil && (status == models.RunStatusRunning || status == models.RunStatusPaused) && !discardsLogs(logDriver) {
+		dockerSince := ""
+		if !since.IsZero() {
+			dockerSince = strconv.FormatInt(since.Unix(), 10)
+		}
+		logs, ok, err := h.liveLogs(r.Context(), runID, dockerHost, *containerID, "1000", dockerSince)
+		if err != nil {
+			writeLiveLogsError(w, err)
+			return
+		}
+		if ok {
+			writeLogs(logs)
 			return
 		}
 	}
 
 	// Otherwise return stored logs
 	logs := ""
-	if logsTail != nil {
+	if logsTail != nil && (finishedAt == nil || !finishedAt.Before(since)) {
 		logs = *logsTail
 	}
-	writeJSON(w, http.StatusOK, map[string]string{"logs": logs})
+	writeLogs(logs)
+}
+
+// noLogsMessage accompanies the (empty) logs of runs whose job uses the
+// "none" log driver.
+const noLogsMessage = "This job's log_driver is none, so its output is not kept"
+
+// discardsLogs reports whether logDriver discards container output.
+func discardsLogs(logDriver *string) bool {
+	return logDriver != nil && *logDriver == docker.LogDriverNone
+}
+
+// hasFullLogs reports whether a run's complete output can be served: it is in
+// the log store backend the run recorded, or, for runs from before the log
+// store, in object storage at logs_object_key.
+func (h *RunHandler) hasFullLogs(backend, objectKey *string) bool {
+	return (backend != nil && h.logs != nil && h.logs.Has(*backend)) || (objectKey != nil && h.storage != nil)
+}
+
+// fullLogs opens a run's complete output from wherever hasFullLogs found it.
+// A missing object is reported as logstore.ErrNotFound.
+func (h *RunHandler) fullLogs(ctx context.Context, runID uuid.UUID, backend, objectKey *string) (io.ReadCloser, error) {
+	if backend != nil && h.logs != nil && h.logs.Has(*backend) {
+		return h.logs.Get(ctx, *backend, runID)
+	}
+	if objectKey == nil || h.storage == nil {
+		return nil, logstore.ErrNotFound
+	}
+	r, err := h.storage.Download(ctx, *objectKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, logstore.ErrNotFound
+	}
+	return r, err
+}
+
+// DownloadRunLogs streams a run's logs as a text/plain attachment: the full
+// output when it was kept in the log store, otherwise the live container's
+// logs or the stored tail.
+func (h *RunHandler) DownloadRunLogs(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	runID, err := uuid.Parse(chi.URLParam(r, "runID"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid_request", Message: "Invalid run ID",
+		})
+		return
+	}
+
+	var containerID, dockerHost, logsTail, fullLogsBackend, logsObjectKey, logDriver *string
+	var status models.RunStatus
+	err = h.db.Pool.QueryRow(r.Context(), `
+		SELECT r.container_id, r.docker_host, r.logs_tail, r.full_logs_backend, r.logs_object_key, r.status, j.log_driver
+		FROM job_runs r JOIN jobs j ON j.id = r.job_id
+		WHERE r.id = $1 AND r.user_id = $2
+	`, runID, user.ID).Scan(&containerID, &dockerHost, &logsTail, &fullLogsBackend, &logsObjectKey, &status, &logDriver)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Run not found",
+		})
+		return
+	}
+
+	var body io.Reader = strings.NewReader("")
+	if logsTail != nil {
+		body = strings.NewReader(*logsTail)
+	}
+	switch {
+	case h.hasFullLogs(fullLogsBackend, logsObjectKey):
+		reader, err := h.fullLogs(r.Context(), runID, fullLogsBackend, logsObjectKey)
+		if errors.Is(err, logstore.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+				Error: "not_found", Message: "Full logs not found",
+			})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+				Error: "internal_error", Message: "Failed to load full logs",
+			})
+			return
+		}
+		defer reader.Close()
+		body = reader
+	case containerID != nil && (status == models.RunStatusRunning || status == models.RunStatusPaused) && !discardsLogs(logDriver):
+		logs, ok, err := h.liveLogs(r.Context(), runID, dockerHost, *containerID, "all", "")
+		if err != nil {
+			writeLiveLogsError(w, err)
+			return
+		}
+		if ok {
+			body = strings.NewReader(logs)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="run-%s.log"`, runID))
+	if _, err := io.Copy(w, body); err != nil {
+		// The status is already sent; the client sees a cut-off download
+		log.Printf("[api] ERROR streaming logs of run %s: %v", runID, err)
+	}
 }
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// TestQueuePosition checks that a run's queue position counts only the items
+// a worker would claim first: not runs that are no longer pending, nor retries
+// that won't come due before it.
+func TestQueuePosition(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	user := createUser(t, db)
+	h := &RunHandler{db: db}
+
+	var jobID uuid.UUID
+	if err := db.Pool.QueryRow(ctx, `
+		INSERT INTO jobs (user_id, name, image) VALUES ($1, 'queue', 'alpine') RETURNING id
+	`, user.ID).Scan(&jobID); err != nil {
+		t.Fatalf("creating job: %v", err)
+	}
+	// A priority well above the default keeps other tests' queue items out
+	// of the count
+	enqueue := func(status, scheduledAt string) uuid.UUID {
+		t.Helper()
+		var runID uuid.UUID
+		if err := db.Pool.QueryRow(ctx, `
+			INSERT INTO job_runs (job_id, user_id, status) VALUES ($1, $2, $3::run_status) RETURNING id
+		`, jobID, user.ID, status).Scan(&runID); err != nil {
+			t.Fatalf("creating run: %v", err)
+		}
+		if _, err := db.Pool.Exec(ctx, `
+			INSERT INTO job_queue (job_id, run_id, priority, scheduled_at) VALUES ($1, $2, 1000, now() + $3::interval)
+		`, jobID, runID, scheduledAt); err != nil {
+			t.Fatalf("queueing run: %v", err)
+		}
+		return runID
+	}
+
+	enqueue("pending", "-3 minutes")      // Ahead
+	enqueue("cancelled", "-2 minutes")    // No longer pending
+	retry := enqueue("pending", "1 hour") // Not due yet
+	run := enqueue("pending", "-1 minute")
+
+	if pos := h.queuePosition(ctx, run); pos == nil || *pos != 2 {
+		t.Errorf("queuePosition(run) = %v, want 2", pos)
+	}
+	// The retry comes due after everything already waiting
+	if pos := h.queuePosition(ctx, retry); pos == nil || *pos != 3 {
+		t.Errorf("queuePosition(retry) = %v, want 3", pos)
+	}
+}
@@ -37,13 +37,7 @@ func (h *UploadHandler) Upload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify job ownership
-	var ownerID uuid.UUID
-	err = h.db.Pool.QueryRow(r.Context(), `SELECT user_id FROM jobs WHERE id = $1`, jobID).Scan(&ownerID)
-	if err != nil || ownerID != user.ID {
-		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
-			Error: "not_found", Message: "Job not found",
-		})
+	if !requireOwnedJob(w, r, h.db, jobID, user.ID) {
 		return
 	}
 
@@ -110,6 +104,10 @@ func (h *UploadHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !requireOwnedJob(w, r, h.db, jobID, user.ID) {
+		return
+	}
+
 	prefix := fmt.Sprintf("uploads/%s/%s/", user.ID, jobID)
 	objects, err := h.storage.List(r.Context(), prefix)
 	if err != nil {
@@ -133,7 +131,7 @@ func (h *UploadHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
 		files = []map[string]interface{}{}
 	}
 
-	writeJSON(w, http.StatusOK, files)
+	writeList(w, r, files, models.Pagination{Total: len(files)})
 }
 
 // DeleteFile deletes a specific uploaded file.
@@ -155,6 +153,10 @@ func (h *UploadHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !requireOwnedJob(w, r, h.db, jobID, user.ID) {
+		return
+	}
+
 	key := fmt.Sprintf("uploads/%s/%s/%s", user.ID, jobID, filename)
 	if err := h.storage.Delete(r.Context(), key); err != nil {
 		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
@@ -1,20 +1,322 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/orbex-dev/orbex/internal/database"
+	"github.com/orbex-dev/orbex/internal/models"
 )
 
-// writeJSON writes a JSON response.
+// writeJSON writes a JSON response. Error responses carry the request ID set
+// by RequestIDHeader so a failure can be matched to the server logs.
 func writeJSON(w http.ResponseWriter, status int, v any) {
+	if e, ok := v.(models.ErrorResponse); ok && e.RequestID == "" {
+		e.RequestID = w.Header().Get(requestIDHeader)
+		v = e
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// writeJSONWithETag writes v as a 200 response tagged with an ETag derived
+// from its encoding. If the request's If-None-Match already names that tag,
+// it writes 304 Not Modified with no body instead, so polling clients don't
+// re-download unchanged resources.
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to encode response",
+		})
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// etagMatches reports whether an If-None-Match header value names etag,
+// comparing weakly as RFC 9110 requires for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// requireOwnedJob responds 404 and returns false unless the job exists and
+// belongs to userID.
+//
+// Every handler reports another user's job, run or channel exactly like a
+// missing one (404, never 403) so IDs can't be probed for existence. Handlers
+// that filter by user_id in their own query get this for free; job-scoped
+// listings, which would otherwise return an empty list, call this first.
+func requireOwnedJob(w http.ResponseWriter, r *http.Request, db *database.DB, jobID, userID uuid.UUID) bool {
+	var exists bool
+	err := db.Pool.QueryRow(r.Context(), `
+		SELECT EXISTS (SELECT 1 FROM jobs WHERE id = $1 AND user_id = $2)
+	`, jobID, userID).Scan(&exists)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to load job",
+		})
+		return false
+	}
+	if !exists {
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "not_found", Message: "Job not found",
+		})
+		return false
+	}
+	return true
+}
+
+// decodeJSON decodes the request body into v, rejecting fields v doesn't
+// have so typos don't go unnoticed. On failure it writes the error response
+// (413 for oversized bodies, 400 otherwise, naming the offending field or
+// byte offset where it can) and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil && dec.More() {
+		err = errors.New("request body must contain a single JSON value")
+	}
+	if err == nil {
+		return true
+	}
+
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Error: "request_too_large", Message: fmt.Sprintf("Request body exceeds %d bytes", maxErr.Limit),
+		})
+		return false
+	}
+
+	resp := models.ErrorResponse{Error: "invalid_request"}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		resp.Message = fmt.Sprintf("Invalid JSON at byte offset %d: %s", syntaxErr.Offset, syntaxErr)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		msg := fmt.Sprintf("%s must be %s, not %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value)
+		resp.Message = fmt.Sprintf("Invalid JSON body: %s (byte offset %d)", msg, typeErr.Offset)
+		resp.Details = []models.FieldError{{Field: typeErr.Field, Message: msg}}
+	case errors.As(err, &typeErr):
+		resp.Message = fmt.Sprintf("Request body must be %s, not %s", jsonTypeName(typeErr.Type), typeErr.Value)
+	case errors.Is(err, io.EOF):
+		resp.Message = "Request body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		resp.Message = "Request body ends in the middle of a JSON value"
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		resp.Message = fmt.Sprintf("Unknown field %q", field)
+		resp.Details = []models.FieldError{{Field: field, Message: "unknown field"}}
+	default:
+		resp.Message = "Invalid JSON body: " + err.Error()
+	}
+	writeJSON(w, http.StatusBadRequest, resp)
+	return false
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// jsonTypeName describes a Go type as the JSON value that decodes into it.
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "a string" // e.g. uuid.UUID, which is an array underneath
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return t.String()
+}
+
+// wantsEnvelope reports whether a list request asked for ?envelope=true.
+func wantsEnvelope(r *http.Request) bool {
+	return r.URL.Query().Get("envelope") == "true"
+}
+
+// writeList writes a list response: the bare items by default, or wrapped in
+// a models.ListEnvelope with page when the client asked for ?envelope=true.
+func writeList(w http.ResponseWriter, r *http.Request, items any, page models.Pagination) {
+	if wantsEnvelope(r) {
+		writeJSON(w, http.StatusOK, models.ListEnvelope{Data: items, Pagination: page})
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// keysetCursor returns the ?before= cursor of the page after the item with
+// the given timestamp and ID: "<RFC 3339 time>_<id>". Lists are ordered by
+// both, newest first, so items sharing a timestamp aren't skipped or repeated
+// across pages.
+func keysetCursor(at time.Time, id any) *string {
+	s := at.Format(time.RFC3339Nano) + "_" + fmt.Sprint(id)
+	return &s
+}
+
+// errInvalidCursor is returned for a ?before= value that isn't a cursor.
+var errInvalidCursor = errors.New("before must be a next_cursor value or an RFC 3339 timestamp")
+
+// parseCursor splits a ?before= cursor from keysetCursor into its timestamp
+// and ID. A bare RFC 3339 timestamp, as cursors used to be, is accepted with
+// an empty ID.
+func parseCursor(v string) (time.Time, string, error) {
+	at, id, _ := strings.Cut(v, "_")
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return time.Time{}, "", errInvalidCursor
+	}
+	return t, id, nil
+}
+
+// parseRunCursor parses a ?before= cursor over runs. A bare timestamp gets
+// the nil UUID, which sorts below every run ID, so it pages as
+// created_at < before did.
+func parseRunCursor(v string) (time.Time, uuid.UUID, error) {
+	at, id, err := parseCursor(v)
+	if err != nil || id == "" {
+		return at, uuid.Nil, err
+	}
+	runID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+	return at, runID, nil
+}
+
 // isDuplicateError checks if a Postgres error is a unique violation.
 func isDuplicateError(err error) bool {
-	return strings.Contains(err.Error(), "23505") ||
-		strings.Contains(err.Error(), "duplicate key")
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" // unique_violation
+}
+
+// parseAge parses an age such as "30d" or "12h". A "d" suffix means days;
+// anything else is handed to time.ParseDuration. Negative ages are rejected.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// parseSince parses a ?since= value: an RFC 3339 time, or an age such as
+// "10m" or "2d" counted back from now.
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	age, err := parseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since %q", s)
+	}
+	return time.Now().Add(-age), nil
+}
+
+// parseMemoryMB parses a memory size such as "512Mi", "1Gi" or "1.5G" into
+// megabytes. As with Docker, decimal and binary suffixes are both treated as
+// binary units; a bare number is taken as megabytes.
+func parseMemoryMB(s string) (int, error) {
+	num, unit := splitQuantity(s)
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid memory %q", s)
+	}
+	switch strings.ToLower(unit) {
+	case "", "m", "mi", "mb":
+	case "g", "gi", "gb":
+		v *= 1024
+	case "k", "ki", "kb":
+		v /= 1024
+	default:
+		return 0, fmt.Errorf("invalid memory unit in %q (use Mi or Gi)", s)
+	}
+	if v < 1 || v != float64(int(v)) {
+		return 0, fmt.Errorf("memory %q is not a whole number of megabytes", s)
+	}
+	return int(v), nil
+}
+
+// parseCPUMillicores parses a CPU amount in cores ("0.5", "2") or
+// millicores ("500m") into millicores.
+func parseCPUMillicores(s string) (int, error) {
+	num, unit := splitQuantity(s)
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid cpu %q", s)
+	}
+	switch unit {
+	case "":
+		v *= 1000
+	case "m":
+	default:
+		return 0, fmt.Errorf("invalid cpu unit in %q (use cores or m)", s)
+	}
+	if v < 1 || v != float64(int(v)) {
+		return 0, fmt.Errorf("cpu %q is not a whole number of millicores", s)
+	}
+	return int(v), nil
+}
+
+// splitQuantity splits "1.5Gi" into "1.5" and "Gi".
+func splitQuantity(s string) (num, unit string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
 }
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsDuplicateError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unique violation", &pgconn.PgError{Code: "23505"}, true},
+		{"wrapped unique violation", fmt.Errorf("creating user: %w", &pgconn.PgError{Code: "23505"}), true},
+		{"foreign key violation", &pgconn.PgError{Code: "23503"}, false},
+		{"message mentioning the code", errors.New("failed to connect: 23505"), false},
+		{"message mentioning duplicate key", errors.New("duplicate key value violates unique constraint"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDuplicateError(tt.err); got != tt.want {
+				t.Errorf("isDuplicateError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCursor(t *testing.T) {
+	at := time.Date(2026, 10, 16, 9, 30, 0, 123456000, time.UTC)
+	id := uuid.New()
+
+	gotAt, gotID, err := parseRunCursor(*keysetCursor(at, id))
+	if err != nil || !gotAt.Equal(at) || gotID != id {
+		t.Errorf("parseRunCursor(keysetCursor(%v, %v)) = %v, %v, %v", at, id, gotAt, gotID, err)
+	}
+
+	// A bare timestamp, as older clients send, pages by time alone
+	gotAt, gotID, err = parseRunCursor(at.Format(time.RFC3339Nano))
+	if err != nil || !gotAt.Equal(at) || gotID != uuid.Nil {
+		t.Errorf("parseRunCursor(bare timestamp) = %v, %v, %v; want %v, nil UUID", gotAt, gotID, err, at)
+	}
+
+	for _, v := range []string{"yesterday", at.Format(time.RFC3339Nano) + "_not-a-uuid"} {
+		if _, _, err := parseRunCursor(v); !errors.Is(err, errInvalidCursor) {
+			t.Errorf("parseRunCursor(%q) err = %v, want errInvalidCursor", v, err)
+		}
+	}
+}
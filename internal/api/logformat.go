@@ -0,0 +1,58 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/orbex-dev/orbex/internal/models"
+)
+
+// logLevelKeys and logMessageKeys are the field names structured loggers
+// commonly use for a line's level and message, in order of preference.
+var (
+	logLevelKeys   = []string{"level", "lvl", "severity"}
+	logMessageKeys = []string{"message", "msg"}
+)
+
+// parseLogLines splits logs into lines, parsing each one that is a JSON
+// object. Lines that aren't JSON are kept as plain text. If level is set,
+// only lines with that level (case-insensitively) are returned.
+func parseLogLines(logs, level string) []models.LogLine {
+	lines := []models.LogLine{}
+	for text := range strings.Lines(logs) {
+		line := parseLogLine(strings.TrimRight(text, "\r\n"))
+		if level != "" && !strings.EqualFold(line.Level, level) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseLogLine parses a single line of output.
+func parseLogLine(text string) models.LogLine {
+	line := models.LogLine{Text: text}
+	raw := bytes.TrimSpace([]byte(text))
+	if len(raw) == 0 || raw[0] != '{' {
+		return line
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return line
+	}
+	line.Fields = raw
+	line.Level = firstString(fields, logLevelKeys)
+	line.Message = firstString(fields, logMessageKeys)
+	return line
+}
+
+// firstString returns the first of keys whose value in fields is a string.
+func firstString(fields map[string]any, keys []string) string {
+	for _, k := range keys {
+		if v, ok := fields[k].(string); ok {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/orbex-dev/orbex/internal/metrics"
+)
+
+// Requests are labelled with the chi route pattern rather than the path, so
+// run and job IDs don't create a series each.
+var (
+	httpRequests = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "orbex_http_requests_total",
+		Help: "HTTP requests served, by route and status code.",
+	}, []string{"method", "route", "status"})
+	httpRequestDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "orbex_http_request_duration_seconds",
+		Help:    "Time taken to serve HTTP requests, by route.",
+		Buckets: metrics.DefaultBuckets,
+	}, []string{"method", "route"})
+)
+
+// unmatchedRoute labels requests no route matched, such as 404s for unknown
+// paths, which would otherwise carry arbitrary client-chosen paths.
+const unmatchedRoute = "unmatched"
+
+// otherMethod labels requests with a method outside the standard set, so a
+// client can't mint a series per made-up method.
+const otherMethod = "OTHER"
+
+// methodLabel returns the method as-is if it is a standard HTTP method and
+// otherMethod otherwise.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return otherMethod
+}
+
+// RequestMetrics records the count, latency and status code of each request.
+// It must run before Recoverer so panics are counted as the 500 they become.
+func RequestMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		method := methodLabel(r.Method)
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+		httpRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	})
+}
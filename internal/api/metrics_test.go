@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRequestMetricsMethodLabel(t *testing.T) {
+	h := RequestMetrics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	before := testutil.ToFloat64(httpRequests.WithLabelValues(otherMethod, unmatchedRoute, "200"))
+	for _, method := range []string{"FOO", "BAR", "get"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/nowhere", nil))
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues(otherMethod, unmatchedRoute, "200")) - before; got != 3 {
+		t.Errorf("OTHER count grew by %v, want 3", got)
+	}
+	if methodLabel(http.MethodPatch) != http.MethodPatch {
+		t.Errorf("methodLabel(PATCH) = %q, want PATCH", methodLabel(http.MethodPatch))
+	}
+}
@@ -3,11 +3,18 @@ package api
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"log"
+	"mime"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
 	"github.com/orbex-dev/orbex/internal/database"
 	"github.com/orbex-dev/orbex/internal/models"
 )
@@ -16,9 +23,24 @@ type contextKey string
 
 const userContextKey contextKey = "user"
 
-// AuthMiddleware validates authentication via API key OR session cookie.
-// Priority: Bearer token (for CLI/API) → session cookie (for dashboard).
-func AuthMiddleware(db *database.DB) func(http.Handler) http.Handler {
+// requestIDHeader carries the request ID back to the client.
+const requestIDHeader = "X-Request-ID"
+
+// RequestIDHeader echoes the ID assigned by chi's RequestID middleware (which
+// must run first) in the response, so clients can quote it to support.
+func RequestIDHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			w.Header().Set(requestIDHeader, id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AuthMiddleware validates authentication via API key, JWT access token OR
+// session cookie.
+// Priority: Bearer token (API key for CLI/API, JWT for dashboard) → session cookie.
+func AuthMiddleware(db *database.DB, jwt *JWTSigner) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			var user *models.User
@@ -28,8 +50,10 @@ func AuthMiddleware(db *database.DB) func(http.Handler) http.Handler {
 			if authHeader != "" {
 				key := strings.TrimPrefix(authHeader, "Bearer ")
 				key = strings.TrimSpace(key)
-				if key != "" {
-					user = authenticateByAPIKey(r.Context(), db, key)
+				if looksLikeJWT(key) {
+					user = authenticateByJWT(r.Context(), db, jwt, key)
+				} else if key != "" {
+					user = authenticateByAPIKey(r, db, key)
 				}
 			}
 
@@ -43,7 +67,7 @@ func AuthMiddleware(db *database.DB) func(http.Handler) http.Handler {
 			if user == nil {
 				writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{
 					Error:   "unauthorized",
-					Message: "Missing or invalid authentication. Use Authorization: Bearer <api_key> or login via the dashboard.",
+					Message: "Missing or invalid authentication. Use Authorization: Bearer <api_key or access_token> or login via the dashboard.",
 				})
 				return
 			}
@@ -54,32 +78,119 @@ func AuthMiddleware(db *database.DB) func(http.Handler) http.Handler {
 	}
 }
 
-// authenticateByAPIKey validates a Bearer API key.
-func authenticateByAPIKey(ctx context.Context, db *database.DB, key string) *models.User {
+// authenticateByAPIKey validates a Bearer API key and records where it was used from.
+func authenticateByAPIKey(r *http.Request, db *database.DB, key string) *models.User {
 	hash := sha256.Sum256([]byte(key))
 	keyHash := hex.EncodeToString(hash[:])
 
 	var user models.User
-	err := db.Pool.QueryRow(ctx, `
-		SELECT u.id, u.email, u.created_at, u.updated_at
+	var keyID uuid.UUID
+	err := db.Pool.QueryRow(r.Context(), `
+		SELECT ak.id, u.id, u.email, u.created_at, u.updated_at
 		FROM api_keys ak
 		JOIN users u ON u.id = ak.user_id
 		WHERE ak.key_hash = $1
-	`, keyHash).Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	`, keyHash).Scan(&keyID, &user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return nil
 	}
 
-	// Update last_used (fire and forget)
+	ip := clientIP(r)
+	record := keyUsage.sample(keyID, ip)
+	method, path, userAgent := r.Method, r.URL.Path, r.UserAgent()
+
+	// Update last_used and the usage log (fire and forget)
 	go func() {
-		_, _ = db.Pool.Exec(context.Background(),
-			"UPDATE api_keys SET last_used = now() WHERE key_hash = $1", keyHash)
+		ctx := context.Background()
+		_, _ = db.Pool.Exec(ctx,
+			"UPDATE api_keys SET last_used = now(), last_used_ip = $1 WHERE id = $2", ip, keyID)
+		if !record {
+			return
+		}
+		_, _ = db.Pool.Exec(ctx, `
+			INSERT INTO api_key_usage (key_id, ip, method, path, user_agent)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		`, keyID, ip, method, path, userAgent)
+		_, _ = db.Pool.Exec(ctx, `
+			DELETE FROM api_key_usage WHERE key_id = $1 AND used_at < now() - $2::interval
+		`, keyID, keyUsageRetention.String())
 	}()
 
 	return &user
 }
 
+// keyUsageInterval is how often a key's usage is logged from the same IP.
+// Requests in between only bump last_used, which keeps the log from growing
+// with every call a busy CLI or CI job makes.
+const keyUsageInterval = time.Minute
+
+// keyUsageRetention is how long api_key_usage entries are kept.
+const keyUsageRetention = 30 * 24 * time.Hour
+
+// keyUsage is the process-wide sampler for api_key_usage writes.
+var keyUsage = &usageSampler{last: make(map[usageKey]time.Time)}
+
+type usageKey struct {
+	keyID uuid.UUID
+	ip    string
+}
+
+// usageSampler rate-limits usage log entries per key and source IP.
+type usageSampler struct {
+	mu   sync.Mutex
+	last map[usageKey]time.Time
+}
+
+// sample reports whether a request from ip using keyID should be logged.
+func (s *usageSampler) sample(keyID uuid.UUID, ip string) bool {
+	now := time.Now()
+	k := usageKey{keyID: keyID, ip: ip}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.last[k]; ok && now.Sub(last) < keyUsageInterval {
+		return false
+	}
+	s.last[k] = now
+
+	// Drop stale entries so the map doesn't grow without bound
+	if len(s.last) > 10000 {
+		for key, t := range s.last {
+			if now.Sub(t) >= keyUsageInterval {
+				delete(s.last, key)
+			}
+		}
+	}
+	return true
+}
+
+// clientIP returns the request's source address without the port.
+// RemoteAddr has already been rewritten by chi's RealIP middleware.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// authenticateByJWT validates a Bearer JWT access token.
+func authenticateByJWT(ctx context.Context, db *database.DB, jwt *JWTSigner, token string) *models.User {
+	userID, err := jwt.Verify(token)
+	if err != nil {
+		return nil
+	}
+
+	var user models.User
+	err = db.Pool.QueryRow(ctx, `
+		SELECT id, email, created_at, updated_at FROM users WHERE id = $1
+	`, userID).Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil
+	}
+	return &user
+}
+
 // authenticateBySession validates a session cookie token.
 func authenticateBySession(ctx context.Context, db *database.DB, token string) *models.User {
 	hash := sha256.Sum256([]byte(token))
@@ -108,8 +219,94 @@ func authenticateBySession(ctx context.Context, db *database.DB, token string) *
 	return &user
 }
 
+// AdminMiddleware guards operator endpoints with the static ADMIN_TOKEN,
+// sent as a Bearer token. With no token configured the endpoints are disabled.
+func AdminMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+					Error: "not_found", Message: "Admin endpoints are disabled (ADMIN_TOKEN is not set)",
+				})
+				return
+			}
+			got := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{
+					Error: "unauthorized", Message: "Missing or invalid admin token",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RejectDuringMaintenance refuses requests with 503 while maintenance mode is
+// on, or can't be read. It guards the endpoints that create runs.
+func RejectDuringMaintenance(wc WorkerControl) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			on, err := wc.Maintenance(r.Context())
+			if err != nil {
+				log.Printf("[api] ERROR reading maintenance mode: %v", err)
+				writeJSON(w, http.StatusServiceUnavailable, models.ErrorResponse{
+					Error: "unavailable", Message: "Could not check maintenance mode; try again",
+				})
+				return
+			}
+			if on {
+				writeJSON(w, http.StatusServiceUnavailable, models.ErrorResponse{
+					Error: "maintenance", Message: "Orbex is in maintenance mode; new runs are not being accepted",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // UserFromContext extracts the authenticated user from the request context.
 func UserFromContext(ctx context.Context) *models.User {
 	user, _ := ctx.Value(userContextKey).(*models.User)
 	return user
 }
+
+// MaxBodySize caps request bodies at limit bytes. Handlers that read past the
+// limit get an *http.MaxBytesError, which decodeJSON turns into a 413.
+// Multipart uploads are skipped — the upload handler enforces its own limit.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit > 0 && r.Body != nil {
+				mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if mediaType != "multipart/form-data" {
+					r.Body = http.MaxBytesReader(w, r.Body, limit)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LongRequestTimeout replaces middleware.Timeout for long-lived responses such
+// as log streams and exec sessions. Besides bounding the request context it
+// pushes back the connection's write deadline, which would otherwise cut the
+// response off at http.Server.WriteTimeout. A zero timeout removes both limits.
+func LongRequestTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rc := http.NewResponseController(w)
+			ctx := r.Context()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+				_ = rc.SetWriteDeadline(time.Now().Add(timeout))
+			} else {
+				_ = rc.SetWriteDeadline(time.Time{})
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
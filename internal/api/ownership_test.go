@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/orbex-dev/orbex/internal/database"
+	"github.com/orbex-dev/orbex/internal/models"
+)
+
+// testDB connects to ORBEX_TEST_DATABASE_URL and applies the migrations,
+// skipping the test when no database is configured.
+func testDB(t *testing.T) *database.DB {
+	t.Helper()
+	url := os.Getenv("ORBEX_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("ORBEX_TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	db, err := database.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	if err := db.Migrate(ctx, filepath.Join("..", "database", "migrations")); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	return db
+}
+
+// createUser inserts a throwaway user and removes it (and, by cascade,
+// everything it owns) when the test ends.
+func createUser(t *testing.T, db *database.DB) *models.User {
+	t.Helper()
+	ctx := context.Background()
+	user := &models.User{Email: uuid.NewString() + "@example.com"}
+	err := db.Pool.QueryRow(ctx, `
+		INSERT INTO users (email, password) VALUES ($1, 'x') RETURNING id
+	`, user.Email).Scan(&user.ID)
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Pool.Exec(context.Background(), "DELETE FROM users WHERE id = $1", user.ID)
+	})
+	return user
+}
+
+// TestCrossUserAccessIsNotFound checks that every resource owned by one user
+// answers 404 — never 403 — to another user, so IDs can't be probed.
+func TestCrossUserAccessIsNotFound(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	owner := createUser(t, db)
+	other := createUser(t, db)
+
+	var jobID, runID, channelID uuid.UUID
+	if err := db.Pool.QueryRow(ctx, `
+		INSERT INTO jobs (user_id, name, image) VALUES ($1, $2, 'alpine') RETURNING id
+	`, owner.ID, "owned-"+uuid.NewString()).Scan(&jobID); err != nil {
+		t.Fatalf("creating job: %v", err)
+	}
+	if err := db.Pool.QueryRow(ctx, `
+		INSERT INTO job_runs (job_id, user_id) VALUES ($1, $2) RETURNING id
+	`, jobID, owner.ID).Scan(&runID); err != nil {
+		t.Fatalf("creating run: %v", err)
+	}
+	if err := db.Pool.QueryRow(ctx, `
+		INSERT INTO notification_channels (job_id, type, target)
+		VALUES ($1, 'webhook', 'https://example.com/hook') RETURNING id
+	`, jobID).Scan(&channelID); err != nil {
+		t.Fatalf("creating notification channel: %v", err)
+	}
+
+	jobs := &JobHandler{db: db}
+	runs := &RunHandler{db: db}
+	notifications := NewNotificationHandler(db)
+	uploads := NewUploadHandler(db, nil)
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), userContextKey, other)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	r.Get("/jobs/{jobID}", jobs.Get)
+	r.Delete("/jobs/{jobID}", jobs.Delete)
+	r.Get("/jobs/{jobID}/runs", runs.ListRuns)
+	r.Get("/runs/{runID}", runs.GetRun)
+	r.Post("/runs/{runID}/kill", runs.KillRun)
+	r.Get("/runs/{runID}/logs", runs.GetRunLogs)
+	r.Get("/jobs/{jobID}/notifications", notifications.List)
+	r.Post("/jobs/{jobID}/notifications", notifications.Create)
+	r.Delete("/jobs/{jobID}/notifications/{channelID}", notifications.Delete)
+	r.Post("/jobs/{jobID}/upload", uploads.Upload)
+	r.Get("/jobs/{jobID}/files", uploads.ListFiles)
+	r.Delete("/jobs/{jobID}/files/{filename}", uploads.DeleteFile)
+
+	tests := []struct {
+		name, method, path, body string
+	}{
+		{"get job", http.MethodGet, "/jobs/" + jobID.String(), ""},
+		{"delete job", http.MethodDelete, "/jobs/" + jobID.String(), ""},
+		{"list runs", http.MethodGet, "/jobs/" + jobID.String() + "/runs", ""},
+		{"get run", http.MethodGet, "/runs/" + runID.String(), ""},
+		{"kill run", http.MethodPost, "/runs/" + runID.String() + "/kill", ""},
+		{"get run logs", http.MethodGet, "/runs/" + runID.String() + "/logs", ""},
+		{"list notifications", http.MethodGet, "/jobs/" + jobID.String() + "/notifications", ""},
+		{"create notification", http.MethodPost, "/jobs/" + jobID.String() + "/notifications",
+			`{"type":"webhook","target":"https://example.com/other"}`},
+		{"delete notification", http.MethodDelete,
+			"/jobs/" + jobID.String() + "/notifications/" + channelID.String(), ""},
+		{"upload file", http.MethodPost, "/jobs/" + jobID.String() + "/upload", ""},
+		{"list files", http.MethodGet, "/jobs/" + jobID.String() + "/files", ""},
+		{"delete file", http.MethodDelete, "/jobs/" + jobID.String() + "/files/main.py", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != http.StatusNotFound {
+				t.Fatalf("%s %s: status = %d, want 404; body: %s",
+					tt.method, tt.path, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// Nothing the other user attempted may have touched the owner's data.
+	var channels int
+	if err := db.Pool.QueryRow(ctx,
+		"SELECT count(*) FROM notification_channels WHERE job_id = $1", jobID,
+	).Scan(&channels); err != nil {
+		t.Fatalf("counting channels: %v", err)
+	}
+	if channels != 1 {
+		t.Fatalf("job has %d notification channels after cross-user requests, want 1", channels)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicy describes the rules new passwords must satisfy.
+type PasswordPolicy struct {
+	MinLength      int
+	MinCharClasses int // Of: lowercase, uppercase, digits, symbols
+}
+
+// commonPasswords are rejected regardless of the policy. The list covers the
+// most frequent entries in public breach corpora that could still pass the
+// length and character-class checks.
+var commonPasswords = map[string]bool{
+	"password": true, "password1": true, "password12": true, "password123": true,
+	"password!": true, "passw0rd": true, "p@ssw0rd": true, "p@ssword": true,
+	"12345678": true, "123456789": true, "1234567890": true, "12341234": true,
+	"qwertyuiop": true, "qwerty123": true, "qwerty12": true, "1q2w3e4r": true,
+	"1q2w3e4r5t": true, "zaq12wsx": true, "iloveyou": true, "iloveyou1": true,
+	"letmein1": true, "letmein!": true, "welcome1": true, "welcome123": true,
+	"admin123": true, "administrator": true, "changeme": true, "changeme1": true,
+	"sunshine1": true, "football1": true, "baseball1": true, "princess1": true,
+	"monkey123": true, "dragon123": true, "master123": true, "abc12345": true,
+	"abcd1234": true, "aa123456": true, "trustno1": true, "superman1": true,
+	"starwars1": true, "whatever1": true, "computer1": true, "michael1": true,
+	"11111111": true, "00000000": true, "88888888": true, "asdfghjkl": true,
+	"asdf1234": true, "qwer1234": true, "orbex123": true,
+}
+
+// Validate returns a message describing why password violates the policy,
+// or "" if it is acceptable.
+func (p PasswordPolicy) Validate(password string) string {
+	if len(password) < p.MinLength {
+		return fmt.Sprintf("Password must be at least %d characters", p.MinLength)
+	}
+	if commonPasswords[strings.ToLower(password)] {
+		return "Password is too common; choose something harder to guess"
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, has := range []bool{lower, upper, digit, symbol} {
+		if has {
+			classes++
+		}
+	}
+	if classes < p.MinCharClasses {
+		return fmt.Sprintf("Password must mix at least %d of: lowercase letters, uppercase letters, digits, symbols", p.MinCharClasses)
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/orbex-dev/orbex/internal/models"
+)
+
+// Quotas caps how many jobs a single user may own (0 = unlimited).
+type Quotas struct {
+	MaxJobs          int
+	MaxScheduledJobs int
+}
+
+// rowQuerier is satisfied by both the pool and a transaction.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// jobCounts returns how many jobs the user owns, and how many of them have a
+// schedule.
+func jobCounts(ctx context.Context, q rowQuerier, userID uuid.UUID) (jobs, scheduled int, err error) {
+	err = q.QueryRow(ctx, `
+		SELECT count(*), count(*) FILTER (WHERE schedule IS NOT NULL)
+		FROM jobs WHERE user_id = $1
+	`, userID).Scan(&jobs, &scheduled)
+	return jobs, scheduled, err
+}
+
+// checkQuota writes a 403 and returns false if the user is out of room for
+// newJobs more jobs, newScheduled of which carry a schedule.
+//
+// tx must be the transaction that then creates or schedules the jobs: it
+// takes a per-user lock held until commit, so concurrent requests can't each
+// count the same free slot.
+func (h *JobHandler) checkQuota(w http.ResponseWriter, r *http.Request, tx pgx.Tx, userID uuid.UUID, newJobs, newScheduled int) bool {
+	if h.quotas.MaxJobs <= 0 && h.quotas.MaxScheduledJobs <= 0 {
+		return true
+	}
+
+	if _, err := tx.Exec(r.Context(), `SELECT pg_advisory_xact_lock(hashtext($1))`, "quota:"+userID.String()); err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to check quota",
+		})
+		return false
+	}
+	jobs, scheduled, err := jobCounts(r.Context(), tx, userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to check quota",
+		})
+		return false
+	}
+
+	var msg string
+	switch {
+	case h.quotas.MaxJobs > 0 && jobs+newJobs > h.quotas.MaxJobs:
+		msg = fmt.Sprintf("Job quota exceeded: %d of %d jobs in use", jobs, h.quotas.MaxJobs)
+	case h.quotas.MaxScheduledJobs > 0 && newScheduled > 0 && scheduled+newScheduled > h.quotas.MaxScheduledJobs:
+		msg = fmt.Sprintf("Scheduled job quota exceeded: %d of %d scheduled jobs in use", scheduled, h.quotas.MaxScheduledJobs)
+	default:
+		return true
+	}
+	writeJSON(w, http.StatusForbidden, models.ErrorResponse{
+		Error: "quota_exceeded", Message: msg,
+	})
+	return false
+}
+
+// checkScheduleQuota is checkQuota for giving an existing job a schedule; a
+// job that is already scheduled doesn't take up another slot.
+func (h *JobHandler) checkScheduleQuota(w http.ResponseWriter, r *http.Request, tx pgx.Tx, userID, jobID uuid.UUID) bool {
+	if h.quotas.MaxScheduledJobs <= 0 {
+		return true
+	}
+	var scheduled bool
+	err := tx.QueryRow(r.Context(), `
+		SELECT schedule IS NOT NULL FROM jobs WHERE id = $1 AND user_id = $2
+	`, jobID, userID).Scan(&scheduled)
+	if err != nil || scheduled {
+		return true // A missing job is reported as not found by the update itself
+	}
+	return h.checkQuota(w, r, tx, userID, 0, 1)
+}
+
+// Quota reports the user's job usage against their quotas.
+func (h *JobHandler) Quota(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+
+	jobs, scheduled, err := jobCounts(r.Context(), h.db.Pool, user.ID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error: "internal_error", Message: "Failed to load quota",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.Quota{
+		Jobs:          quotaUsage(jobs, h.quotas.MaxJobs),
+		ScheduledJobs: quotaUsage(scheduled, h.quotas.MaxScheduledJobs),
+	})
+}
+
+// quotaUsage pairs a count with its limit, leaving the limit out if unlimited.
+func quotaUsage(used, limit int) models.QuotaUsage {
+	u := models.QuotaUsage{Used: used}
+	if limit > 0 {
+		u.Limit = &limit
+	}
+	return u
+}
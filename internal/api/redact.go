@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// redactedValue replaces sensitive env values in API responses.
+const redactedValue = "***"
+
+// sensitiveEnvPattern matches env names that are treated as secrets even
+// when the job doesn't list them in sensitive_env.
+var sensitiveEnvPattern = regexp.MustCompile(`(?i)(SECRET|PASSWORD|PASSWD|TOKEN|API_?KEY|PRIVATE_?KEY|CREDENTIAL|AUTH)`)
+
+// isSensitiveEnv reports whether an env key's value should be hidden.
+func isSensitiveEnv(key string, explicit []string) bool {
+	return slices.Contains(explicit, key) || sensitiveEnvPattern.MatchString(key)
+}
+
+// redactEnv returns env with the values of sensitive keys replaced by
+// redactedValue. The worker reads env straight from the database, so runs
+// still see the real values.
+func redactEnv(env map[string]string, explicit []string) map[string]string {
+	if len(env) == 0 {
+		return env
+	}
+	out := make(map[string]string, len(env))
+	for k, v := range env {
+		if isSensitiveEnv(k, explicit) {
+			v = redactedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// restoreRedactedEnv puts the stored values back for sensitive keys that an
+// update sends as redactedValue, so a job read from the API can be written
+// back unchanged without clobbering its secrets. explicit holds any
+// sensitive_env keys sent with the same update.
+func (h *JobHandler) restoreRedactedEnv(ctx context.Context, jobID, userID uuid.UUID, env map[string]string, explicit []string) error {
+	if !slices.Contains(slices.Collect(maps.Values(env)), redactedValue) {
+		return nil
+	}
+
+	var storedJSON, storedSealed []byte
+	var storedKeyID *string
+	var storedSensitive []string
+	if err := h.db.Pool.QueryRow(ctx, `
+		SELECT env, env_sealed, env_key_id, sensitive_env FROM jobs WHERE id = $1 AND user_id = $2
+	`, jobID, userID).Scan(&storedJSON, &storedSealed, &storedKeyID, &storedSensitive); err != nil {
+		return err
+	}
+	stored, err := h.envKeys.DecodeEnv(storedJSON, storedSealed, storedKeyID, jobID)
+	if err != nil {
+		return err
+	}
+
+	explicit = append(explicit, storedSensitive...)
+	for k, v := range env {
+		if old, ok := stored[k]; ok && v == redactedValue && isSensitiveEnv(k, explicit) {
+			env[k] = old
+		}
+	}
+	return nil
+}
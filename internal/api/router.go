@@ -3,103 +3,198 @@ package api
 import (
 	"log"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/orbex-dev/orbex/internal/config"
 	"github.com/orbex-dev/orbex/internal/database"
 	"github.com/orbex-dev/orbex/internal/docker"
+	"github.com/orbex-dev/orbex/internal/logstore"
+	"github.com/orbex-dev/orbex/internal/metrics"
 	"github.com/orbex-dev/orbex/internal/storage"
+	"github.com/orbex-dev/orbex/internal/version"
 )
 
 // NewRouter creates and configures the HTTP router with all routes.
-func NewRouter(db *database.DB, dockerClient *docker.Client, storageClient *storage.Client, cfg *config.Config) http.Handler {
+func NewRouter(db *database.DB, dockerHosts *docker.Hosts, storageClient *storage.Client, logStore *logstore.Stores, workerControl WorkerControl, cfg *config.Config) http.Handler {
 	r := chi.NewRouter()
 
 	// Global middleware
 	r.Use(middleware.RequestID)
+	r.Use(RequestIDHeader)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
+	r.Use(RequestMetrics)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
-	r.Use(corsMiddleware)
-
-	// Health check (no auth)
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]string{
-			"status":  "ok",
-			"service": "orbex",
-		})
-	})
+	r.Use(corsMiddleware(cfg.CORSAllowedOrigins))
+
+	jwtSigner := NewJWTSigner(cfg.JWTSecret, cfg.JWTAccessTTL)
 
 	// Handlers
-	authHandler := NewAuthHandler(db)
-	jobHandler := NewJobHandler(db)
-	runHandler := NewRunHandler(db, dockerClient)
+	authHandler := NewAuthHandler(db, cfg, jwtSigner)
+	jobHandler := NewJobHandler(db, dockerHosts, cfg)
+	runHandler := NewRunHandler(db, dockerHosts, logStore, storageClient, cfg)
 	uploadHandler := NewUploadHandler(db, storageClient)
 	githubHandler := NewGithubHandler(db, storageClient, cfg)
+	notificationHandler := NewNotificationHandler(db)
+	adminHandler := NewAdminHandler(db, workerControl)
+
+	// Regular request/response routes share the standard timeout
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(cfg.RequestTimeout))
+
+		// Health check (no auth)
+		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]string{
+				"status":  "ok",
+				"service": "orbex",
+			})
+		})
 
-	// Webhook trigger (no auth — uses webhook token in URL)
-	r.Post("/api/v1/webhooks/{token}/trigger", runHandler.WebhookTrigger)
+		// Readiness: the database is reachable, as shown by reading the
+		// maintenance mode setting. Maintenance mode is reported but doesn't
+		// fail the check, since reads keep working.
+		r.Get("/health/ready", func(w http.ResponseWriter, r *http.Request) {
+			maintenance, err := workerControl.Maintenance(r.Context())
+			if err != nil {
+				writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+					"status":      "unavailable",
+					"database":    "unreachable",
+					"maintenance": maintenance,
+				})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{
+				"status":      "ready",
+				"database":    "ok",
+				"maintenance": maintenance,
+			})
+		})
 
-	// GitHub webhook (no auth — uses GitHub signature)
-	r.Post("/api/v1/webhooks/github", githubHandler.GithubWebhook)
+		// Build info (no auth)
+		r.Get("/api/v1/version", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, version.Get())
+		})
+
+		// Webhook trigger (no auth — uses webhook token in URL)
+		r.With(RejectDuringMaintenance(workerControl)).Post("/api/v1/webhooks/{token}/trigger", runHandler.WebhookTrigger)
+
+		// GitHub webhook (no auth — uses GitHub signature)
+		r.Post("/api/v1/webhooks/github", githubHandler.GithubWebhook)
+	})
+
+	// Prometheus scrape endpoint, guarded like the other operator endpoints
+	r.With(AdminMiddleware(cfg.AdminToken)).Get("/metrics", metrics.Handler().ServeHTTP)
 
 	// API v1 routes
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(MaxBodySize(cfg.MaxRequestBodyBytes))
 
-		// Public auth routes (no API key or session needed)
-		r.Post("/auth/register", authHandler.Register)
-		r.Post("/auth/api-keys", authHandler.GenerateBootstrapKey)
-		r.Post("/auth/login", authHandler.Login)
-		r.Post("/auth/logout", authHandler.Logout)
-
-		// GitHub OAuth (public — starts OAuth flow)
-		r.Get("/auth/github", githubHandler.StartOAuth)
-		r.Get("/auth/github/callback", githubHandler.OAuthCallback)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(cfg.RequestTimeout))
+			// gzip JSON responses for clients that accept it. Streaming routes
+			// live in the group below so their output isn't held in the encoder.
+			r.Use(middleware.Compress(5))
+
+			// Public auth routes (no API key or session needed)
+			r.Post("/auth/register", authHandler.Register)
+			r.Post("/auth/api-keys", authHandler.GenerateBootstrapKey)
+			r.Post("/auth/login", authHandler.Login)
+			r.Post("/auth/refresh", authHandler.Refresh)
+			r.Post("/auth/logout", authHandler.Logout)
+
+			// Operator endpoints (require ADMIN_TOKEN)
+			r.Group(func(r chi.Router) {
+				r.Use(AdminMiddleware(cfg.AdminToken))
+
+				r.Get("/admin/worker", adminHandler.WorkerStatus)
+				r.Get("/admin/runs", adminHandler.ListRuns)
+				r.Post("/admin/worker/drain", adminHandler.Drain)
+				r.Post("/admin/worker/resume", adminHandler.Resume)
+				r.Post("/admin/maintenance/enable", adminHandler.EnableMaintenance)
+				r.Post("/admin/maintenance/disable", adminHandler.DisableMaintenance)
+				r.Post("/admin/queue/{queueID}/unlock", adminHandler.UnlockQueueItem)
+			})
+
+			// GitHub OAuth (public — starts OAuth flow)
+			r.Get("/auth/github", githubHandler.StartOAuth)
+			r.Get("/auth/github/callback", githubHandler.OAuthCallback)
+
+			// Protected routes (require API key OR session cookie)
+			r.Group(func(r chi.Router) {
+				r.Use(AuthMiddleware(db, jwtSigner))
+
+				// Session info
+				r.Get("/auth/me", authHandler.GetMe)
+
+				// Password change
+				r.Post("/auth/change-password", authHandler.ChangePassword)
+
+				// API key management
+				r.Post("/auth/keys", authHandler.CreateAPIKey)
+				r.Get("/auth/keys/{keyID}/usage", authHandler.KeyUsage)
+
+				// Quota usage
+				r.Get("/quota", jobHandler.Quota)
+
+				// GitHub status & repos
+				r.Get("/github/status", githubHandler.GetGithubStatus)
+				r.Get("/github/repos", githubHandler.ListRepos)
+				r.Get("/github/repos/{owner}/{repo}/branches", githubHandler.ListBranches)
+
+				// Jobs CRUD
+				r.Post("/jobs", jobHandler.Create)
+				r.Post("/jobs/validate", jobHandler.Validate)
+				r.Get("/jobs", jobHandler.List)
+				r.Get("/jobs/{jobID}", jobHandler.Get)
+				r.Patch("/jobs/{jobID}", jobHandler.Update)
+				r.Delete("/jobs/{jobID}", jobHandler.Delete)
+				r.Post("/jobs/{jobID}/enable", jobHandler.Enable)
+				r.Post("/jobs/{jobID}/disable", jobHandler.Disable)
+				r.Get("/jobs/{jobID}/usage", jobHandler.Usage)
+				r.Get("/jobs/{jobID}/stats", jobHandler.Stats)
+				r.Get("/jobs/{jobID}/schedule/preview", jobHandler.SchedulePreview)
+				r.Post("/jobs/{jobID}/clone", jobHandler.Clone)
+
+				// File uploads
+				r.Post("/jobs/{jobID}/upload", uploadHandler.Upload)
+				r.Get("/jobs/{jobID}/files", uploadHandler.ListFiles)
+				r.Delete("/jobs/{jobID}/files/{filename}", uploadHandler.DeleteFile)
+
+				// Notification channels
+				r.Get("/jobs/{jobID}/notifications", notificationHandler.List)
+				r.Post("/jobs/{jobID}/notifications", notificationHandler.Create)
+				r.Delete("/jobs/{jobID}/notifications/{channelID}", notificationHandler.Delete)
+
+				// Job runs
+				r.Post("/jobs/{jobID}/webhook", jobHandler.GenerateWebhookToken)
+				r.Get("/jobs/{jobID}/runs", runHandler.ListRuns)
+				r.Delete("/jobs/{jobID}/runs", runHandler.DeleteRuns)
+				r.Post("/jobs/{jobID}/runs/kill", runHandler.KillJobRuns)
+
+				// Run management
+				r.Get("/runs/{runID}", runHandler.GetRun)
+				r.Get("/runs/{runID}/inspect", runHandler.InspectRun)
+				r.Post("/runs/{runID}/pause", runHandler.PauseRun)
+				r.Post("/runs/{runID}/resume", runHandler.ResumeRun)
+				r.Post("/runs/{runID}/kill", runHandler.KillRun)
+				r.Patch("/runs/{runID}/priority", runHandler.UpdatePriority)
+			})
+		})
 
-		// Protected routes (require API key OR session cookie)
+		// Long-lived routes (log streams, exec) get their own, longer deadline
 		r.Group(func(r chi.Router) {
-			r.Use(AuthMiddleware(db))
-
-			// Session info
-			r.Get("/auth/me", authHandler.GetMe)
-
-			// Password change
-			r.Post("/auth/change-password", authHandler.ChangePassword)
-
-			// API key management
-			r.Post("/auth/keys", authHandler.CreateAPIKey)
-
-			// GitHub status & repos
-			r.Get("/github/status", githubHandler.GetGithubStatus)
-			r.Get("/github/repos", githubHandler.ListRepos)
-			r.Get("/github/repos/{owner}/{repo}/branches", githubHandler.ListBranches)
-
-			// Jobs CRUD
-			r.Post("/jobs", jobHandler.Create)
-			r.Get("/jobs", jobHandler.List)
-			r.Get("/jobs/{jobID}", jobHandler.Get)
-			r.Patch("/jobs/{jobID}", jobHandler.Update)
-			r.Delete("/jobs/{jobID}", jobHandler.Delete)
-
-			// File uploads
-			r.Post("/jobs/{jobID}/upload", uploadHandler.Upload)
-			r.Get("/jobs/{jobID}/files", uploadHandler.ListFiles)
-			r.Delete("/jobs/{jobID}/files/{filename}", uploadHandler.DeleteFile)
-
-			// Job runs
-			r.Post("/jobs/{jobID}/run", runHandler.TriggerRun)
-			r.Post("/jobs/{jobID}/webhook", jobHandler.GenerateWebhookToken)
-			r.Get("/jobs/{jobID}/runs", runHandler.ListRuns)
-
-			// Run management
-			r.Get("/runs/{runID}", runHandler.GetRun)
-			r.Post("/runs/{runID}/pause", runHandler.PauseRun)
-			r.Post("/runs/{runID}/resume", runHandler.ResumeRun)
-			r.Post("/runs/{runID}/kill", runHandler.KillRun)
+			r.Use(LongRequestTimeout(cfg.StreamTimeout))
+			r.Use(AuthMiddleware(db, jwtSigner))
+
 			r.Get("/runs/{runID}/logs", runHandler.GetRunLogs)
+			r.Get("/runs/{runID}/logs/download", runHandler.DownloadRunLogs)
+			r.Get("/runs/{runID}/events", runHandler.StreamRunEvents)
+			r.Get("/events", runHandler.StreamEvents)
+
+			// Trigger may block until the run finishes (?wait=true)
+			r.With(RejectDuringMaintenance(workerControl)).Post("/jobs/{jobID}/run", runHandler.TriggerRun)
 		})
 	})
 
@@ -112,25 +207,38 @@ func NewRouter(db *database.DB, dockerClient *docker.Client, storageClient *stor
 	return r
 }
 
-// corsMiddleware adds CORS headers for development.
-// Supports credentials (cookies) with specific origin instead of wildcard.
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		if origin == "" {
-			origin = "http://localhost:3000"
-		}
-
-		w.Header().Set("Access-Control-Allow-Origin", origin)
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+// corsMiddleware adds CORS headers for requests from allowed origins.
+// The request origin is echoed back (never "*") so cookies keep working;
+// an allowlist entry of "*" accepts any origin and is meant for development.
+func corsMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, o := range allowedOrigins {
+		if o == "*" {
+			allowAll = true
 		}
-
-		next.ServeHTTP(w, r)
-	})
+		allowed[strings.TrimSuffix(o, "/")] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+
+			origin := r.Header.Get("Origin")
+			if origin != "" && (allowAll || allowed[origin]) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, Idempotency-Key, If-None-Match, X-Request-ID")
+				w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Idempotent-Replayed, ETag")
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
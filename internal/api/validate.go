@@ -0,0 +1,417 @@
+package api
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/orbex-dev/orbex/internal/docker"
+	"github.com/orbex-dev/orbex/internal/models"
+	"github.com/orbex-dev/orbex/internal/runvars"
+	"github.com/orbex-dev/orbex/internal/schedule"
+)
+
+// envKeyPattern matches the environment variable names a shell accepts.
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// hostnamePattern matches the host names accepted in extra_hosts.
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?$`)
+
+// capabilityPattern matches Linux capability names such as NET_ADMIN or
+// CAP_SYS_PTRACE, and "ALL".
+var capabilityPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z_]*$`)
+
+// tagPattern matches job tags; maxJobTags and maxTagLen bound them.
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+const (
+	maxJobTags = 32
+	maxTagLen  = 63
+)
+
+// maxJobNameLen caps job names so they stay usable in container names and UIs.
+const maxJobNameLen = 128
+
+// SpecLimits caps the size of a job's command and env (0 = unlimited), so a
+// definition too large for the container runtime is refused up front rather
+// than failing every run at container create.
+type SpecLimits struct {
+	MaxCommandBytes int
+	MaxEnvVars      int
+	MaxEnvBytes     int
+}
+
+// validSourceType reports whether v is a job source type the worker can run.
+func validSourceType(v string) bool {
+	switch v {
+	case "image", "github", "upload", "compose":
+		return true
+	}
+	return false
+}
+
+// fieldErrors collects every problem with a request so they can be reported
+// together rather than one round trip per mistake.
+type fieldErrors []models.FieldError
+
+func (e *fieldErrors) add(field, format string, args ...any) {
+	*e = append(*e, models.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// write responds 400 with every collected problem. Message joins them for
+// clients that only read that.
+func (e fieldErrors) write(w http.ResponseWriter) {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+		Error: "validation_error", Message: strings.Join(msgs, "; "), Details: e,
+	})
+}
+
+// validateCreateJob checks a create request, converting memory/cpu given in
+// units into memory_mb/cpu_millicores and dropping an empty docker_host.
+// Checks that need the database (depends_on) are left to the caller.
+func (h *JobHandler) validateCreateJob(req *models.CreateJobRequest) fieldErrors {
+	var errs fieldErrors
+
+	if req.Name == "" {
+		errs.add("name", "name is required")
+	} else if len(req.Name) > maxJobNameLen {
+		errs.add("name", "name must be at most %d characters", maxJobNameLen)
+	}
+	sourceType := req.SourceType
+	if sourceType == "" {
+		sourceType = "image"
+	}
+	if !validSourceType(sourceType) {
+		errs.add("source_type", "source_type must be one of: image, github, upload, compose")
+	}
+	if sourceType == "image" && req.Image == "" {
+		errs.add("image", "image is required for docker image source type")
+	}
+
+	if req.Memory != "" {
+		if mb, err := parseMemoryMB(req.Memory); err != nil || req.MemoryMB != 0 {
+			errs.add("memory", "%s", resourceUnitError("memory", "memory_mb", err))
+		} else {
+			req.MemoryMB = mb
+		}
+	}
+	if req.CPU != "" {
+		if millicores, err := parseCPUMillicores(req.CPU); err != nil || req.CPUMillicores != 0 {
+			errs.add("cpu", "%s", resourceUnitError("cpu", "cpu_millicores", err))
+		} else {
+			req.CPUMillicores = millicores
+		}
+	}
+	checkNonNegative(&errs, "memory_mb", &req.MemoryMB)
+	checkNonNegative(&errs, "cpu_millicores", &req.CPUMillicores)
+	checkNonNegative(&errs, "timeout_seconds", &req.TimeoutSeconds)
+	checkNonNegative(&errs, "start_timeout_seconds", req.StartTimeoutSeconds)
+	checkNonNegative(&errs, "max_concurrent_runs", req.MaxConcurrentRuns)
+	checkNonNegative(&errs, "retention_days", req.RetentionDays)
+	checkNonNegative(&errs, "retention_max_runs", req.RetentionMaxRuns)
+	checkNonNegative(&errs, "log_retention_days", req.LogRetentionDays)
+	checkNonNegative(&errs, "daily_runtime_budget_seconds", req.DailyRuntimeBudgetSeconds)
+
+	if req.Schedule != nil && *req.Schedule != "" {
+		checkSchedule(&errs, *req.Schedule)
+	}
+	checkEnvKeys(&errs, req.Env, req.SensitiveEnv)
+	h.checkSpecSize(&errs, req.Command, req.Env)
+	if req.TemplateVars {
+		checkTemplates(&errs, req.Command, req.Env)
+	}
+	if req.NotifyOn != "" && !validNotifyOn(req.NotifyOn) {
+		errs.add("notify_on", "notify_on must be one of: all, failure, success, failure_and_recovery")
+	}
+	if req.ImageDigest != nil && !imageDigestPattern.MatchString(*req.ImageDigest) {
+		errs.add("image_digest", "image_digest must look like sha256:<64 hex characters>")
+	}
+	if req.RestartPolicy != nil {
+		if _, err := docker.ParseRestartPolicy(*req.RestartPolicy); err != nil {
+			errs.add("restart_policy", "%s", err)
+		}
+	}
+	if req.LogDriver != nil && *req.LogDriver == "" {
+		req.LogDriver = nil
+	}
+	if req.LogDriver != nil && !docker.ValidLogDriver(*req.LogDriver) {
+		errs.add("log_driver", "log_driver must be one of: json-file, local, none")
+	}
+	if req.GPUs != nil && *req.GPUs == "" {
+		req.GPUs = nil
+	}
+	if req.GPUs != nil {
+		host := ""
+		if req.DockerHost != nil {
+			host = *req.DockerHost
+		}
+		h.checkGPUs(&errs, *req.GPUs, host)
+	}
+	checkContainerDNS(&errs, req.DNS, req.ExtraHosts)
+	h.checkCapabilities(&errs, req.CapAdd, req.CapDrop)
+	checkTags(&errs, req.Tags)
+	if req.DockerHost != nil && *req.DockerHost == "" {
+		req.DockerHost = nil
+	}
+	if req.DockerHost != nil && !h.knownDockerHost(*req.DockerHost) {
+		errs.add("docker_host", "docker_host %q is not one of the configured DOCKER_HOSTS", *req.DockerHost)
+	}
+	return errs
+}
+
+// validateUpdateJob checks the fields present in an update request, with the
+// same rules as validateCreateJob. Fields that accept "" to clear a value are
+// only checked when non-empty.
+func (h *JobHandler) validateUpdateJob(req *models.UpdateJobRequest) fieldErrors {
+	var errs fieldErrors
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			errs.add("name", "name must not be empty")
+		} else if len(*req.Name) > maxJobNameLen {
+			errs.add("name", "name must be at most %d characters", maxJobNameLen)
+		}
+	}
+	if req.Image != nil && *req.Image == "" {
+		errs.add("image", "image must not be empty")
+	}
+	if req.SourceType != nil && !validSourceType(*req.SourceType) {
+		errs.add("source_type", "source_type must be one of: image, github, upload, compose")
+	}
+
+	if req.Memory != nil {
+		if mb, err := parseMemoryMB(*req.Memory); err != nil || req.MemoryMB != nil {
+			errs.add("memory", "%s", resourceUnitError("memory", "memory_mb", err))
+		} else {
+			req.MemoryMB = &mb
+		}
+	}
+	if req.CPU != nil {
+		if millicores, err := parseCPUMillicores(*req.CPU); err != nil || req.CPUMillicores != nil {
+			errs.add("cpu", "%s", resourceUnitError("cpu", "cpu_millicores", err))
+		} else {
+			req.CPUMillicores = &millicores
+		}
+	}
+	checkNonNegative(&errs, "memory_mb", req.MemoryMB)
+	checkNonNegative(&errs, "cpu_millicores", req.CPUMillicores)
+	checkNonNegative(&errs, "timeout_seconds", req.TimeoutSeconds)
+	checkNonNegative(&errs, "start_timeout_seconds", req.StartTimeoutSeconds)
+	checkNonNegative(&errs, "max_concurrent_runs", req.MaxConcurrentRuns)
+	checkNonNegative(&errs, "retention_days", req.RetentionDays)
+	checkNonNegative(&errs, "retention_max_runs", req.RetentionMaxRuns)
+	checkNonNegative(&errs, "log_retention_days", req.LogRetentionDays)
+	checkNonNegative(&errs, "daily_runtime_budget_seconds", req.DailyRuntimeBudgetSeconds)
+
+	if req.Schedule != nil && *req.Schedule != "" {
+		checkSchedule(&errs, *req.Schedule)
+	}
+	var env map[string]string
+	if req.Env != nil {
+		env = *req.Env
+	}
+	var sensitive []string
+	if req.SensitiveEnv != nil {
+		sensitive = *req.SensitiveEnv
+	}
+	checkEnvKeys(&errs, env, sensitive)
+	var command []string
+	if req.Command != nil {
+		command = *req.Command
+	}
+	h.checkSpecSize(&errs, command, env)
+	if req.NotifyOn != nil && !validNotifyOn(*req.NotifyOn) {
+		errs.add("notify_on", "notify_on must be one of: all, failure, success, failure_and_recovery")
+	}
+	if req.ImageDigest != nil && *req.ImageDigest != "" && !imageDigestPattern.MatchString(*req.ImageDigest) {
+		errs.add("image_digest", "image_digest must look like sha256:<64 hex characters>")
+	}
+	if req.DependsOn != nil && *req.DependsOn != "" {
+		if _, err := uuid.Parse(*req.DependsOn); err != nil {
+			errs.add("depends_on", "depends_on must be a job ID")
+		}
+	}
+	if req.RestartPolicy != nil {
+		if _, err := docker.ParseRestartPolicy(*req.RestartPolicy); err != nil {
+			errs.add("restart_policy", "%s", err)
+		}
+	}
+	if req.LogDriver != nil && *req.LogDriver != "" && !docker.ValidLogDriver(*req.LogDriver) {
+		errs.add("log_driver", "log_driver must be one of: json-file, local, none")
+	}
+	if req.GPUs != nil && *req.GPUs != "" {
+		// Whether the daemon supports them is checked in Update, against
+		// the job's docker_host
+		if _, err := docker.ParseGPUs(*req.GPUs); err != nil {
+			errs.add("gpus", "%s", err)
+		}
+	}
+	var dns, extraHosts []string
+	if req.DNS != nil {
+		dns = *req.DNS
+	}
+	if req.ExtraHosts != nil {
+		extraHosts = *req.ExtraHosts
+	}
+	checkContainerDNS(&errs, dns, extraHosts)
+	var capAdd, capDrop []string
+	if req.CapAdd != nil {
+		capAdd = *req.CapAdd
+	}
+	if req.CapDrop != nil {
+		capDrop = *req.CapDrop
+	}
+	h.checkCapabilities(&errs, capAdd, capDrop)
+	if req.Tags != nil {
+		checkTags(&errs, *req.Tags)
+	}
+	if req.DockerHost != nil && *req.DockerHost != "" && !h.knownDockerHost(*req.DockerHost) {
+		errs.add("docker_host", "docker_host %q is not one of the configured DOCKER_HOSTS", *req.DockerHost)
+	}
+	return errs
+}
+
+// checkNonNegative flags a negative value; nil means the field was omitted.
+func checkNonNegative(errs *fieldErrors, field string, v *int) {
+	if v != nil && *v < 0 {
+		errs.add(field, "%s must not be negative", field)
+	}
+}
+
+// checkSchedule flags a cron expression the scheduler couldn't parse.
+func checkSchedule(errs *fieldErrors, expr string) {
+	if _, err := schedule.Parse(expr); err != nil {
+		errs.add("schedule", "schedule %q is not a valid cron expression: %v", expr, err)
+	}
+}
+
+// checkSpecSize flags a command or env larger than the configured limits.
+func (h *JobHandler) checkSpecSize(errs *fieldErrors, command []string, env map[string]string) {
+	if limit := h.limits.MaxCommandBytes; limit > 0 {
+		size := 0
+		for _, arg := range command {
+			size += len(arg)
+		}
+		if size > limit {
+			errs.add("command", "command is %d bytes; the limit is %d", size, limit)
+		}
+	}
+	if limit := h.limits.MaxEnvVars; limit > 0 && len(env) > limit {
+		errs.add("env", "env has %d variables; the limit is %d", len(env), limit)
+	}
+	if limit := h.limits.MaxEnvBytes; limit > 0 {
+		size := 0
+		for k, v := range env {
+			size += len(k) + len(v)
+		}
+		if size > limit {
+			errs.add("env", "env is %d bytes; the limit is %d", size, limit)
+		}
+	}
+}
+
+// checkTemplates flags command args and env values of a template_vars job
+// that aren't valid run variable templates, so they fail here rather than at
+// every run.
+func checkTemplates(errs *fieldErrors, command []string, env map[string]string) {
+	for i, arg := range command {
+		if err := runvars.Check(arg); err != nil {
+			errs.add("command", "command[%d] is not a valid template: %v", i, err)
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		if err := runvars.Check(env[k]); err != nil {
+			errs.add("env", "env %s is not a valid template: %v", k, err)
+		}
+	}
+}
+
+// checkContainerDNS flags dns entries that aren't IP addresses and
+// extra_hosts entries that aren't "host:ip". As with docker run --add-host,
+// the IP may also be "host-gateway".
+func checkContainerDNS(errs *fieldErrors, dns, extraHosts []string) {
+	for _, s := range dns {
+		if _, err := netip.ParseAddr(s); err != nil {
+			errs.add("dns", "dns server %q must be an IP address", s)
+		}
+	}
+	for _, entry := range extraHosts {
+		host, ip, ok := strings.Cut(entry, ":")
+		if !ok || !hostnamePattern.MatchString(host) {
+			errs.add("extra_hosts", "extra_hosts entry %q must look like host:ip", entry)
+			continue
+		}
+		if _, err := netip.ParseAddr(ip); err != nil && ip != "host-gateway" {
+			errs.add("extra_hosts", "extra_hosts entry %q has an invalid IP address", entry)
+		}
+	}
+}
+
+// checkGPUs flags a malformed gpus setting, or one the job's daemon can't
+// honor. host names the daemon ("" = default).
+func (h *JobHandler) checkGPUs(errs *fieldErrors, gpus string, host string) {
+	if _, err := docker.ParseGPUs(gpus); err != nil {
+		errs.add("gpus", "%s", err)
+		return
+	}
+	if !h.gpuHosts.get()[host] {
+		if host == "" {
+			errs.add("gpus", "the default Docker daemon doesn't support GPUs (no NVIDIA runtime)")
+		} else {
+			errs.add("gpus", "docker_host %q doesn't support GPUs (no NVIDIA runtime)", host)
+		}
+	}
+}
+
+// checkCapabilities flags malformed capability names, and capabilities to
+// add that the operator hasn't allowed (see docker.SameCapability).
+func (h *JobHandler) checkCapabilities(errs *fieldErrors, capAdd, capDrop []string) {
+	for _, c := range capAdd {
+		switch {
+		case !capabilityPattern.MatchString(c):
+			errs.add("cap_add", "cap_add entry %q is not a capability name", c)
+		case !slices.ContainsFunc(h.allowedCaps, func(a string) bool { return docker.SameCapability(a, c) }):
+			errs.add("cap_add", "capability %q is not in ALLOWED_CAPABILITIES", c)
+		}
+	}
+	for _, c := range capDrop {
+		if !capabilityPattern.MatchString(c) {
+			errs.add("cap_drop", "cap_drop entry %q is not a capability name", c)
+		}
+	}
+}
+
+// checkTags flags too many tags and tags that aren't short plain words.
+func checkTags(errs *fieldErrors, tags []string) {
+	if len(tags) > maxJobTags {
+		errs.add("tags", "at most %d tags are allowed", maxJobTags)
+	}
+	for _, t := range tags {
+		if len(t) > maxTagLen || !tagPattern.MatchString(t) {
+			errs.add("tags", "tag %q must be at most %d letters, digits, '.', '_' or '-'", t, maxTagLen)
+		}
+	}
+}
+
+// checkEnvKeys flags env and sensitive_env keys that aren't valid variable names.
+func checkEnvKeys(errs *fieldErrors, env map[string]string, sensitive []string) {
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		if !envKeyPattern.MatchString(k) {
+			errs.add("env", "env key %q must start with a letter or underscore and contain only letters, digits and underscores", k)
+		}
+	}
+	for _, k := range sensitive {
+		if !envKeyPattern.MatchString(k) {
+			errs.add("sensitive_env", "sensitive_env key %q is not a valid variable name", k)
+		}
+	}
+}
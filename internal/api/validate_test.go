@@ -0,0 +1,23 @@
+package api
+
+import "testing"
+
+func TestCheckTemplates(t *testing.T) {
+	var errs fieldErrors
+	checkTemplates(&errs, []string{"ok", "{{.RunID}}", "{{.RunId}}"}, map[string]string{
+		"GOOD": "{{.Labels.day}}",
+		"BAD":  "{{.JobName",
+	})
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %+v", len(errs), errs)
+	}
+	if errs[0].Field != "command" || errs[1].Field != "env" {
+		t.Errorf("fields = %q, %q; want command, env", errs[0].Field, errs[1].Field)
+	}
+
+	errs = nil
+	checkTemplates(&errs, []string{"docker", "inspect"}, map[string]string{"A": "b"})
+	if len(errs) != 0 {
+		t.Errorf("got errors for plain values: %+v", errs)
+	}
+}
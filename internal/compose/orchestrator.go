@@ -44,6 +44,9 @@ func (o *Orchestrator) Run(ctx context.Context, cf *ComposeFile, jobID uuid.UUID
 		return result
 	}
 
+	// Teardown must still run when ctx is cancelled mid-deployment
+	cleanupCtx := context.WithoutCancel(ctx)
+
 	mainService := cf.MainService()
 	result.MainService = mainService
 	log.Printf("[compose] Main service: %s, start order: %v", mainService, order)
@@ -56,7 +59,7 @@ func (o *Orchestrator) Run(ctx context.Context, cf *ComposeFile, jobID uuid.UUID
 		return result
 	}
 	defer func() {
-		if err := o.docker.RemoveNetwork(ctx, networkID); err != nil {
+		if err := o.docker.RemoveNetwork(cleanupCtx, networkID); err != nil {
 			log.Printf("[compose] Warning: failed to remove network %s: %v", networkName, err)
 		}
 	}()
@@ -65,7 +68,7 @@ func (o *Orchestrator) Run(ctx context.Context, cf *ComposeFile, jobID uuid.UUID
 	var containers []string
 	defer func() {
 		for _, cid := range containers {
-			_ = o.docker.RemoveContainer(ctx, cid)
+			_ = o.docker.RemoveContainer(cleanupCtx, cid)
 		}
 	}()
 
@@ -158,7 +161,7 @@ func (o *Orchestrator) Run(ctx context.Context, cf *ComposeFile, jobID uuid.UUID
 		wg.Add(1)
 		go func(name, containerID string) {
 			defer wg.Done()
-			logs, err := o.docker.GetLogs(ctx, containerID, "500")
+			logs, err := o.docker.GetLogs(ctx, containerID, "500", "")
 			if err != nil {
 				logs = fmt.Sprintf("[error getting logs: %v]", err)
 			}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	pgxUUID "github.com/vgarvardt/pgx-google-uuid/v5"
 )
@@ -126,3 +128,37 @@ func (db *DB) Migrate(ctx context.Context, migrationsDir string) error {
 
 	return nil
 }
+
+// QueueChannel is the Postgres NOTIFY channel signalled whenever a run is
+// added to job_queue, so idle workers can pick it up without waiting for
+// their next poll.
+const QueueChannel = "orbex_queue"
+
+// execer is satisfied by *pgxpool.Pool, *pgxpool.Conn and pgx.Tx.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// ErrVersionConflict is returned by CheckVersion when a versioned update
+// matched no row: another writer changed the row since it was read.
+var ErrVersionConflict = errors.New("version conflict: row was modified concurrently")
+
+// CheckVersion interprets the result of an optimistic-concurrency UPDATE
+// (one guarded by "WHERE version = $old"), returning ErrVersionConflict when
+// no row matched.
+func CheckVersion(tag pgconn.CommandTag, err error) error {
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// NotifyQueue signals QueueChannel. Inside a transaction the notification is
+// delivered on commit, so listeners never wake before the row is visible.
+func NotifyQueue(ctx context.Context, db execer) error {
+	_, err := db.Exec(ctx, "NOTIFY "+QueueChannel)
+	return err
+}
@@ -0,0 +1,6 @@
+-- Per-job run retention overrides (NULL = use the global policy)
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS retention_days INT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS retention_max_runs INT;
+
+-- Speeds up the per-job "newest first" scans done by the retention sweeper
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_created ON job_runs (job_id, created_at DESC);
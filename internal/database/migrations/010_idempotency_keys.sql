@@ -0,0 +1,6 @@
+-- Idempotency keys let clients safely retry run triggers
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS idempotency_key TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_idempotency
+    ON job_runs (job_id, idempotency_key, created_at DESC)
+    WHERE idempotency_key IS NOT NULL;
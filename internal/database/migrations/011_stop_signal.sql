@@ -0,0 +1,3 @@
+-- Records how a killed run's container exited: 'SIGTERM' (stopped within the
+-- grace period) or 'SIGKILL' (forced after the stop timeout)
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS stop_signal TEXT;
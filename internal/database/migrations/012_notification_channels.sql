@@ -0,0 +1,16 @@
+-- Notification channels: each job may notify several destinations
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_id      UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    type        TEXT NOT NULL,                -- 'webhook', 'slack', 'email'
+    target      TEXT NOT NULL,                -- URL or email address
+    events      TEXT NOT NULL DEFAULT 'all',  -- 'all', 'success', 'failure'
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_channels_job_id ON notification_channels (job_id);
+
+-- Carry over existing raw webhooks as webhook channels
+INSERT INTO notification_channels (job_id, type, target)
+SELECT id, 'webhook', notify_webhook FROM jobs
+WHERE notify_webhook IS NOT NULL AND notify_webhook <> '';
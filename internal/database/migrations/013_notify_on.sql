@@ -0,0 +1,3 @@
+-- Per-job notification policy: all, failure, success, or failure_and_recovery
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS notify_on TEXT NOT NULL DEFAULT 'all'
+    CHECK (notify_on IN ('all', 'failure', 'success', 'failure_and_recovery'));
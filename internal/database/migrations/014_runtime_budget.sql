@@ -0,0 +1,2 @@
+-- Per-job cap on total run time per day (NULL = unlimited)
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS daily_runtime_budget_seconds INT;
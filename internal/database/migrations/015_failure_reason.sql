@@ -0,0 +1,14 @@
+-- Structured cause for failed runs, alongside the free-text error_message
+CREATE TYPE failure_reason AS ENUM (
+    'image_pull',
+    'oom',
+    'timeout',
+    'nonzero_exit',
+    'worker_crash',
+    'create_error',
+    'budget_exhausted'
+);
+
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS failure_reason failure_reason;
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_failure_reason ON job_runs (failure_reason) WHERE failure_reason IS NOT NULL;
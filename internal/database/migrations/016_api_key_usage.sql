@@ -0,0 +1,15 @@
+-- Where API keys are used from: the latest source IP on the key itself,
+-- plus a sampled rolling log of recent requests
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS last_used_ip TEXT;
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    id          BIGSERIAL PRIMARY KEY,
+    key_id      UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    ip          TEXT NOT NULL,
+    method      TEXT NOT NULL,
+    path        TEXT NOT NULL,
+    user_agent  TEXT,
+    used_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_key_usage_key_used ON api_key_usage (key_id, used_at DESC);
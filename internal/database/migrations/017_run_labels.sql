@@ -0,0 +1,4 @@
+-- Arbitrary key/value metadata attached to a run at trigger time
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_labels ON job_runs USING GIN (labels);
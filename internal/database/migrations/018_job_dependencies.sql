@@ -0,0 +1,4 @@
+-- Single-parent job chains: a job runs after each successful run of depends_on
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS depends_on UUID REFERENCES jobs(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_depends_on ON jobs (depends_on) WHERE depends_on IS NOT NULL;
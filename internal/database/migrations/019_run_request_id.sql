@@ -0,0 +1,2 @@
+-- ID of the API request that triggered a run, for correlating API and worker logs
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS request_id TEXT;
@@ -0,0 +1,3 @@
+-- The exact image each run used, and an optional per-job digest pin
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS image_digest TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS image_digest TEXT;
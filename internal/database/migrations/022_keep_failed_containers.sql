@@ -0,0 +1,9 @@
+-- Per-job override for keeping failed containers around for debugging
+-- (NULL = use the global KEEP_FAILED_CONTAINERS setting)
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS keep_failed_containers BOOLEAN;
+
+-- Set while a failed run's container is kept; the sweeper removes it after this time
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS container_kept_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_container_kept ON job_runs (container_kept_until)
+    WHERE container_kept_until IS NOT NULL;
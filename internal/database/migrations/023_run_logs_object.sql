@@ -0,0 +1,2 @@
+-- Object storage key of a run's full logs, set when logs_tail was truncated
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS logs_object_key TEXT;
@@ -0,0 +1,3 @@
+-- Data piped to a run's stdin: a job default, optionally overridden per run
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS stdin TEXT;
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS stdin TEXT;
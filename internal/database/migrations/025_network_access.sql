@@ -0,0 +1,3 @@
+-- Per-job opt in/out of container networking (NULL = use the global
+-- BLOCK_NETWORK_BY_DEFAULT setting)
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS network_access BOOLEAN;
@@ -0,0 +1,2 @@
+-- Env keys to redact in API responses, on top of the built-in name patterns
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS sensitive_env TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+-- Docker restart policy for a job's containers ("no" or "on-failure:N"; NULL = no)
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS restart_policy TEXT;
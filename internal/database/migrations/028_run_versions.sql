@@ -0,0 +1,7 @@
+-- Optimistic concurrency for run status transitions. version is bumped when a
+-- run starts and when it reaches a terminal state; writers update with
+-- "WHERE version = <the version they read>" so a stale writer (e.g. the reaper
+-- racing the worker) can't overwrite a terminal status recorded by another.
+-- attempt counts how many times a worker has started the run.
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS attempt INT NOT NULL DEFAULT 0;
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Runs abandoned because the Docker daemon stopped responding mid-run
+ALTER TYPE failure_reason ADD VALUE IF NOT EXISTS 'daemon_unavailable';
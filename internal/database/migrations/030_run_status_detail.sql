@@ -0,0 +1,2 @@
+-- Human-readable progress of a run's current step, e.g. "pulling image (layer 3/7)"
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS status_detail TEXT;
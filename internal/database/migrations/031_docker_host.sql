@@ -0,0 +1,4 @@
+-- Named Docker daemon (from DOCKER_HOSTS) a job's runs are placed on; NULL = default.
+-- Runs record the host they started on so kill/pause/logs reach the right daemon.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS docker_host TEXT;
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS docker_host TEXT;
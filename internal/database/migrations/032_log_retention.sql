@@ -0,0 +1,2 @@
+-- Days a job's run logs are kept before being cleared (NULL = LOG_RETENTION_DAYS)
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS log_retention_days INT;
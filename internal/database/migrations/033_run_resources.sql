@@ -0,0 +1,3 @@
+-- Per-run resource overrides set at trigger time (NULL = use the job's)
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS memory_mb INT;
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS cpu_millicores INT;
@@ -0,0 +1,11 @@
+-- Full run logs for LOG_STORE=db
+CREATE TABLE IF NOT EXISTS run_logs (
+    run_id      UUID PRIMARY KEY REFERENCES job_runs(id) ON DELETE CASCADE,
+    content     TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- The log store finds full logs by run ID, so runs only record that they
+-- have them. logs_object_key is kept for full logs uploaded before this
+-- (under logs/<user>/<run>.log), which are still served from there.
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS full_logs_stored BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+-- Custom DNS servers and /etc/hosts entries ("host:ip") for a job's containers
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS dns TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS extra_hosts TEXT[] NOT NULL DEFAULT '{}';
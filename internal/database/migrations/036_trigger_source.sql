@@ -0,0 +1,10 @@
+-- What created each run. Runs from before this column stay NULL (unknown).
+CREATE TYPE trigger_source AS ENUM (
+    'api',
+    'cli',
+    'webhook',
+    'schedule',
+    'dependency'
+);
+
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS trigger_source trigger_source;
@@ -0,0 +1,5 @@
+-- Job env sealed with ENV_ENCRYPTION_KEYS. When env_key_id is set, env holds
+-- '{}' and the real env is the AES-GCM ciphertext in env_sealed; when NULL,
+-- env is plaintext (encryption off, or written before it was turned on).
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS env_sealed BYTEA;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS env_key_id TEXT;
@@ -0,0 +1,5 @@
+-- Deadline from enqueue to the container running, separate from timeout_seconds
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS start_timeout_seconds INT;
+
+-- Runs that never reached running within start_timeout_seconds
+ALTER TYPE failure_reason ADD VALUE IF NOT EXISTS 'start_timeout';
@@ -0,0 +1,5 @@
+-- Reasons recorded on runs ended by a user kill, a server shutdown, or the
+-- reaper's pause limit (these runs are cancelled rather than failed)
+ALTER TYPE failure_reason ADD VALUE IF NOT EXISTS 'killed';
+ALTER TYPE failure_reason ADD VALUE IF NOT EXISTS 'server_shutdown';
+ALTER TYPE failure_reason ADD VALUE IF NOT EXISTS 'pause_limit';
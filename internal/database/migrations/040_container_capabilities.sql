@@ -0,0 +1,3 @@
+-- Linux capabilities added to or dropped from a job's containers
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS cap_add TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS cap_drop TEXT[] NOT NULL DEFAULT '{}';
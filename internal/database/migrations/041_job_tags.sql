@@ -0,0 +1,3 @@
+-- Free-form tags for grouping jobs (e.g. "nightly"); GET /jobs?tag= filters on them
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_jobs_tags ON jobs USING GIN (tags);
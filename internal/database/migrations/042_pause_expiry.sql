@@ -0,0 +1,3 @@
+-- When a paused run's pause runs out, and whether it is then resumed or killed
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS pause_expires_at TIMESTAMPTZ;
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS pause_expiry_action TEXT;
@@ -0,0 +1,2 @@
+-- A small JSON value a run reports by printing "::orbex-result::<json>" on its output
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS result JSONB;
@@ -0,0 +1,2 @@
+-- Docker log driver for a job's containers ("json-file", "local" or "none"); NULL = daemon default
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS log_driver TEXT;
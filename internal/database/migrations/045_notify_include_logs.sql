@@ -0,0 +1,2 @@
+-- Attach the run's log tail to failure/success notifications for this job
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS notify_include_logs BOOLEAN NOT NULL DEFAULT false;
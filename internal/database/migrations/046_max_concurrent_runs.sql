@@ -0,0 +1,6 @@
+-- Cap on a job's runs in flight at once; NULL = no per-job limit
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS max_concurrent_runs INTEGER;
+
+-- Picked queue items are the runs in flight, counted per job when claiming
+CREATE INDEX IF NOT EXISTS idx_job_queue_picked_job ON job_queue (job_id)
+    WHERE picked_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- NVIDIA GPUs exposed to a job's containers: "all" or a count; NULL = none
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS gpus TEXT;
@@ -0,0 +1,4 @@
+-- Run variables ({{.RunID}}, {{.Labels.key}}, ...) in a job's command and env
+-- are only resolved for jobs that opt in, so existing jobs whose commands
+-- contain "{{" for other tools keep running unchanged.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS template_vars BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,5 @@
+-- Env given when a run is triggered, layered over the job's env for that run
+-- only. Stored like jobs.env: sealed in env_sealed when env_key_id is set.
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS env JSONB;
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS env_sealed BYTEA;
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS env_key_id TEXT;
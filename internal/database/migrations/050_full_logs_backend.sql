@@ -0,0 +1,11 @@
+-- Runs record which log store backend holds their full logs, so logs put
+-- there before LOG_STORE changed are still found (and deleted). Earlier runs
+-- only recorded that they had full logs; a run_logs row means the db backend.
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS full_logs_backend TEXT;
+UPDATE job_runs r SET full_logs_backend =
+    CASE WHEN EXISTS (SELECT 1 FROM run_logs l WHERE l.run_id = r.id) THEN 'db' ELSE 's3' END
+WHERE r.full_logs_stored;
+ALTER TABLE job_runs DROP COLUMN IF EXISTS full_logs_stored;
+
+-- Container output is bytes, not necessarily valid UTF-8 (or free of NULs)
+ALTER TABLE run_logs ALTER COLUMN content TYPE BYTEA USING convert_to(content, 'UTF8');
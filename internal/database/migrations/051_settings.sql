@@ -0,0 +1,7 @@
+-- Server-wide settings shared by every orbex process on the database, such
+-- as maintenance mode. One row per setting.
+CREATE TABLE IF NOT EXISTS settings (
+    key         TEXT PRIMARY KEY,
+    value       TEXT NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -4,15 +4,26 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/moby/moby/api/pkg/stdcopy"
 	"github.com/moby/moby/api/types/container"
 	"github.com/moby/moby/api/types/network"
 	"github.com/moby/moby/client"
+	"golang.org/x/sync/singleflight"
 )
 
 // ContainerConfig holds the parameters for creating a container.
@@ -26,19 +37,160 @@ type ContainerConfig struct {
 	Binds         []string // Host:Container bind mounts
 	NetworkID     string   // Optional Docker network to connect to
 	NetworkAlias  string   // Optional alias for the container on the network
+	Stdin         bool     // Keep stdin open for AttachStdin; it closes after the first attach
+	NoNetwork     bool     // Run with networking disabled (network mode "none")
+	RestartPolicy string   // "no" (default), "on-failure" or "on-failure:N"; see ParseRestartPolicy
+	DNS           []string // DNS servers, instead of the daemon's
+	ExtraHosts    []string // Extra /etc/hosts entries as "host:ip"
+	CapAdd        []string // Linux capabilities to add
+	CapDrop       []string // Linux capabilities to drop
+	LogDriver     string   // Container log driver; "" = the daemon's default. See ValidLogDriver
+	GPUs          string   // NVIDIA GPUs to expose: "all" or a count; "" = none. See ParseGPUs
+}
+
+// LogDriverNone is the log driver that discards container output, so there
+// are no logs to read back.
+const LogDriverNone = "none"
+
+// ValidLogDriver reports whether s is a log driver jobs may choose. Only
+// drivers whose output ContainerLogs can read back are offered, plus "none"
+// for jobs whose logs aren't wanted.
+func ValidLogDriver(s string) bool {
+	switch s {
+	case "json-file", "local", LogDriverNone:
+		return true
+	}
+	return false
+}
+
+// ParseGPUs parses a job's gpus setting, "all" or a positive count, into a
+// device request count (-1 = all).
+func ParseGPUs(s string) (int, error) {
+	if s == "all" {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("gpus must be \"all\" or a positive count, got %q", s)
+	}
+	return n, nil
+}
+
+// SupportsGPUs reports whether the daemon can give containers NVIDIA GPUs:
+// it has the nvidia runtime registered, or has discovered NVIDIA devices
+// through CDI.
+func (c *Client) SupportsGPUs(ctx context.Context) (bool, error) {
+	res, err := c.cli.Info(ctx, client.InfoOptions{})
+	if err != nil {
+		return false, fmt.Errorf("querying docker info: %w", err)
+	}
+	if _, ok := res.Info.Runtimes["nvidia"]; ok {
+		return true, nil
+	}
+	for _, d := range res.Info.DiscoveredDevices {
+		if strings.HasPrefix(d.ID, "nvidia.com/gpu") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// maxRestartRetries caps N in an "on-failure:N" restart policy.
+const maxRestartRetries = 10
+
+// ParseRestartPolicy parses a job's restart policy: "no", or "on-failure:N"
+// to let Docker restart a crashed container up to N times (1-10; a bare
+// "on-failure" means 1). Policies that restart forever are not accepted, as
+// a run must eventually finish.
+func ParseRestartPolicy(s string) (container.RestartPolicy, error) {
+	if s == "" || s == "no" {
+		return container.RestartPolicy{Name: container.RestartPolicyDisabled}, nil
+	}
+	mode, count, hasCount := strings.Cut(s, ":")
+	if mode != string(container.RestartPolicyOnFailure) {
+		return container.RestartPolicy{}, fmt.Errorf("restart_policy must be \"no\" or \"on-failure:N\"")
+	}
+	retries := 1
+	if hasCount {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 || n > maxRestartRetries {
+			return container.RestartPolicy{}, fmt.Errorf("restart_policy retry count must be between 1 and %d", maxRestartRetries)
+		}
+		retries = n
+	}
+	return container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: retries}, nil
 }
 
 // Client wraps the Docker Engine API client.
 type Client struct {
 	cli *client.Client
+
+	// PullTimeout bounds each PullImage call so a hung registry can't stall
+	// a run forever (0 = defaultPullTimeout).
+	PullTimeout time.Duration
+
+	// MaxConcurrentPulls caps the pulls in progress on this daemon; further
+	// pulls wait for a slot (0 = no limit). Set it before the first pull.
+	MaxConcurrentPulls int
+
+	pullSlotsOnce sync.Once
+	pullSlots     chan struct{} // Semaphore sized MaxConcurrentPulls; nil = no limit
+
+	pulls      singleflight.Group // In-progress pulls, keyed by image reference
+	pullSubsMu sync.Mutex
+	pullSubs   map[string][]*pullSubscriber // Progress listeners per image reference
+
+	// closing is cancelled by Close, aborting shared pulls that no caller's
+	// ctx governs
+	closing context.Context
+	stop    context.CancelFunc
 }
 
-// New creates a new Docker client.
-func New() (*Client, error) {
-	cli, err := client.NewClientWithOpts(
-		client.FromEnv,
-		client.WithAPIVersionNegotiation(),
-	)
+// ErrPullTimeout is returned by PullImage when PullTimeout is exceeded.
+var ErrPullTimeout = errors.New("image pull timed out")
+
+// defaultPullTimeout bounds pulls when PullTimeout is unset: a shared pull
+// outlives the callers waiting on it, so it needs a limit of its own.
+const defaultPullTimeout = time.Hour
+
+// TLSFiles locates the PEM files used to reach a daemon over TLS. A CA alone
+// verifies the daemon; a certificate and key also authenticate the client.
+type TLSFiles struct {
+	CACertFile string
+	CertFile   string
+	KeyFile    string
+}
+
+// New creates a Docker client for the daemon at host, or the one configured
+// in the environment (DOCKER_HOST etc.) when host is empty. Non-empty tlsFiles
+// replace any TLS settings taken from the environment when the daemon is
+// reached over tcp://; other schemes don't speak TLS and ignore them.
+func New(host string, tlsFiles TLSFiles) (*Client, error) {
+	opts := []client.Opt{client.FromEnv}
+	if host != "" {
+		opts = append(opts, client.WithHost(host))
+	} else {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if tlsFiles != (TLSFiles{}) && strings.HasPrefix(host, "tcp://") {
+		opts = append(opts, client.WithTLSClientConfig(tlsFiles.CACertFile, tlsFiles.CertFile, tlsFiles.KeyFile))
+	}
+	return newClient(opts...)
+}
+
+// NewRemote creates a Docker client for the daemon at addr, ignoring the
+// DOCKER_* environment (which describes the default daemon). It uses TLS
+// only when tlsFiles is non-empty.
+func NewRemote(addr string, tlsFiles TLSFiles) (*Client, error) {
+	opts := []client.Opt{client.WithHost(addr)}
+	if tlsFiles != (TLSFiles{}) {
+		opts = append(opts, client.WithTLSClientConfig(tlsFiles.CACertFile, tlsFiles.CertFile, tlsFiles.KeyFile))
+	}
+	return newClient(opts...)
+}
+
+func newClient(opts ...client.Opt) (*Client, error) {
+	cli, err := client.NewClientWithOpts(append(opts, client.WithAPIVersionNegotiation())...)
 	if err != nil {
 		return nil, fmt.Errorf("creating docker client: %w", err)
 	}
@@ -48,28 +200,281 @@ func New() (*Client, error) {
 		return nil, fmt.Errorf("connecting to docker daemon: %w", err)
 	}
 
-	return &Client{cli: cli}, nil
+	closing, stop := context.WithCancel(context.Background())
+	return &Client{cli: cli, closing: closing, stop: stop}, nil
 }
 
 // Close closes the Docker client.
 func (c *Client) Close() error {
+	c.stop()
 	return c.cli.Close()
 }
 
-// PullImage pulls a Docker image if not already present.
+// EffectivePullTimeout is the limit pulls are held to: PullTimeout, or
+// defaultPullTimeout if that is unset.
+func (c *Client) EffectivePullTimeout() time.Duration {
+	if c.PullTimeout > 0 {
+		return c.PullTimeout
+	}
+	return defaultPullTimeout
+}
+
+// PullImage pulls a Docker image if not already present (see
+// PullImageProgress). It gives up after PullTimeout with an error wrapping
+// ErrPullTimeout.
 func (c *Client) PullImage(ctx context.Context, imageName string) error {
+	return c.PullImageProgress(ctx, imageName, nil)
+}
+
+// PullProgress summarizes an in-progress image pull by layer.
+type PullProgress struct {
+	Layers     int // Layers the image has, as far as the daemon has reported
+	LayersDone int // Layers downloaded and extracted, or already present
+}
+
+// String formats the progress as a run status detail.
+func (p PullProgress) String() string {
+	return fmt.Sprintf("pulling image (layer %d/%d)", p.LayersDone, p.Layers)
+}
+
+// PullImageProgress pulls an image like PullImage, calling onProgress (if
+// non-nil) each time a layer is discovered or finishes. Images pinned by
+// digest or tag that are already present aren't pulled again; "latest" (or
+// no tag) is always re-pulled so it stays current. Pulls beyond
+// MaxConcurrentPulls wait for one to finish, and PullTimeout only starts
+// once the pull does.
+//
+// The wait for a pull slot is bound to ctx. Concurrent calls for the same
+// reference then share one pull, and each caller gets its progress. The
+// shared pull isn't tied to any caller's ctx, so a caller giving up doesn't
+// fail the others; it runs on until done, EffectivePullTimeout or Close.
+func (c *Client) PullImageProgress(ctx context.Context, imageName string, onProgress func(PullProgress)) error {
+	if !isLatestRef(imageName) {
+		if _, err := c.cli.ImageInspect(ctx, imageName); err == nil {
+			return nil
+		}
+	}
+
+	release, err := c.acquirePullSlot(ctx)
+	if err != nil {
+		return fmt.Errorf("waiting to pull image %s: %w", imageName, err)
+	}
+	defer release()
+
+	if onProgress != nil {
+		defer c.subscribePull(imageName, onProgress)()
+	}
+	result := c.pulls.DoChan(imageName, func() (any, error) {
+		return nil, c.pull(imageName)
+	})
+	select {
+	case res := <-result:
+		return res.Err
+	case <-ctx.Done():
+		return fmt.Errorf("pulling image %s: %w", imageName, ctx.Err())
+	}
+}
+
+// pullSubscriber receives the progress of a shared pull.
+type pullSubscriber struct {
+	onProgress func(PullProgress)
+}
+
+// subscribePull registers onProgress for pulls of imageName and returns a
+// func that unregisters it.
+func (c *Client) subscribePull(imageName string, onProgress func(PullProgress)) func() {
+	sub := &pullSubscriber{onProgress: onProgress}
+	c.pullSubsMu.Lock()
+	if c.pullSubs == nil {
+		c.pullSubs = map[string][]*pullSubscriber{}
+	}
+	c.pullSubs[imageName] = append(c.pullSubs[imageName], sub)
+	c.pullSubsMu.Unlock()
+
+	return func() {
+		c.pullSubsMu.Lock()
+		defer c.pullSubsMu.Unlock()
+		subs := slices.DeleteFunc(c.pullSubs[imageName], func(s *pullSubscriber) bool { return s == sub })
+		if len(subs) == 0 {
+			delete(c.pullSubs, imageName)
+		} else {
+			c.pullSubs[imageName] = subs
+		}
+	}
+}
+
+// broadcastPull passes a pull's progress to everyone waiting on it.
+func (c *Client) broadcastPull(imageName string, p PullProgress) {
+	c.pullSubsMu.Lock()
+	subs := slices.Clone(c.pullSubs[imageName])
+	c.pullSubsMu.Unlock()
+	for _, sub := range subs {
+		sub.onProgress(p)
+	}
+}
+
+// pull performs one image pull, bounded by EffectivePullTimeout and
+// aborted by Close.
+func (c *Client) pull(imageName string) error {
 	log.Printf("[docker] Pulling image: %s", imageName)
-	resp, err := c.cli.ImagePull(ctx, imageName, client.ImagePullOptions{})
+	timeout := c.EffectivePullTimeout()
+	pullCtx, cancel := context.WithTimeout(c.closing, timeout)
+	defer cancel()
+	resp, err := c.cli.ImagePull(pullCtx, imageName, client.ImagePullOptions{})
+	if err == nil {
+		err = followPull(pullCtx, resp, func(p PullProgress) { c.broadcastPull(imageName, p) })
+	}
 	if err != nil {
+		if c.closing.Err() == nil && errors.Is(pullCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("pulling image %s: %w after %s", imageName, ErrPullTimeout, timeout)
+		}
 		return fmt.Errorf("pulling image %s: %w", imageName, err)
 	}
-	if err := resp.Wait(ctx); err != nil {
-		return fmt.Errorf("waiting for image pull %s: %w", imageName, err)
-	}
 	log.Printf("[docker] Image ready: %s", imageName)
 	return nil
 }
 
+// acquirePullSlot blocks until fewer than MaxConcurrentPulls pulls are in
+// progress, or ctx is done. The returned func frees the slot.
+func (c *Client) acquirePullSlot(ctx context.Context) (func(), error) {
+	c.pullSlotsOnce.Do(func() {
+		if c.MaxConcurrentPulls > 0 {
+			c.pullSlots = make(chan struct{}, c.MaxConcurrentPulls)
+		}
+	})
+	if c.pullSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case c.pullSlots <- struct{}{}:
+		return func() { <-c.pullSlots }, nil
+	default:
+	}
+
+	log.Printf("[docker] Waiting for a pull slot (%d pulls in progress)", c.MaxConcurrentPulls)
+	select {
+	case c.pullSlots <- struct{}{}:
+		return func() { <-c.pullSlots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// isLatestRef reports whether an image reference uses the mutable "latest"
+// tag, explicitly or by omitting the tag.
+func isLatestRef(ref string) bool {
+	if strings.Contains(ref, "@") {
+		return false
+	}
+	tag := ""
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		tag = ref[i+1:]
+	}
+	return tag == "" || tag == "latest"
+}
+
+// followPull reads a pull's JSON message stream to the end, tracking layer
+// states for onProgress and surfacing errors the daemon reports in-stream.
+func followPull(ctx context.Context, resp client.ImagePullResponse, onProgress func(PullProgress)) error {
+	layers := map[string]bool{} // Layer ID -> done
+	var last PullProgress
+	for msg, err := range resp.JSONMessages(ctx) {
+		if err != nil {
+			return err
+		}
+		if msg.Error != nil {
+			return errors.New(msg.Error.Message)
+		}
+		switch msg.Status {
+		case "Pulling fs layer", "Waiting":
+			if _, seen := layers[msg.ID]; !seen {
+				layers[msg.ID] = false
+			}
+		case "Pull complete", "Already exists":
+			layers[msg.ID] = true
+		default:
+			continue
+		}
+		p := PullProgress{Layers: len(layers)}
+		for _, done := range layers {
+			if done {
+				p.LayersDone++
+			}
+		}
+		if onProgress != nil && p != last {
+			onProgress(p)
+			last = p
+		}
+	}
+	return nil
+}
+
+// ImageDigest returns the content digest ("sha256:...") of a local image.
+// It prefers the registry digest recorded for the image's repository, and
+// falls back to the image ID for images that were built locally.
+func (c *Client) ImageDigest(ctx context.Context, imageName string) (string, error) {
+	info, err := c.cli.ImageInspect(ctx, imageName)
+	if err != nil {
+		return "", fmt.Errorf("inspecting image %s: %w", imageName, err)
+	}
+	repo := imageRepo(imageName)
+	for _, rd := range info.RepoDigests {
+		if r, digest, ok := strings.Cut(rd, "@"); ok && r == repo {
+			return digest, nil
+		}
+	}
+	if len(info.RepoDigests) > 0 {
+		if _, digest, ok := strings.Cut(info.RepoDigests[0], "@"); ok {
+			return digest, nil
+		}
+	}
+	return info.ID, nil
+}
+
+// HasDefaultCommand reports whether a local image defines a CMD or
+// ENTRYPOINT, i.e. whether a container can be started from it without
+// giving a command.
+func (c *Client) HasDefaultCommand(ctx context.Context, imageName string) (bool, error) {
+	info, err := c.cli.ImageInspect(ctx, imageName)
+	if err != nil {
+		return false, fmt.Errorf("inspecting image %s: %w", imageName, err)
+	}
+	if info.Config == nil {
+		return false, nil
+	}
+	return len(info.Config.Cmd) > 0 || len(info.Config.Entrypoint) > 0, nil
+}
+
+// SameCapability reports whether a and b name the same Linux capability.
+// Names compare without their "CAP_" prefix and case-insensitively, as
+// Docker does.
+func SameCapability(a, b string) bool {
+	norm := func(c string) string { return strings.TrimPrefix(strings.ToUpper(c), "CAP_") }
+	return norm(a) == norm(b)
+}
+
+// PinnedRef returns the reference that pulls exactly digest from the
+// repository of imageName, e.g. "python:3.12" → "python@sha256:...".
+func PinnedRef(imageName, digest string) string {
+	return imageRepo(imageName) + "@" + digest
+}
+
+// imageRepo strips any tag or digest from an image reference. A colon only
+// marks a tag after the last slash; before it, it's a registry port.
+func imageRepo(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+	return ref
+}
+
+// ErrNameConflict is returned (wrapped) by CreateContainer when a container
+// with the requested name already exists.
+var ErrNameConflict = errors.New("container name already in use")
+
 // CreateContainer creates a new container with resource limits.
 func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (string, error) {
 	var envSlice []string
@@ -84,6 +489,11 @@ func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (stri
 	if len(cfg.Command) > 0 {
 		containerCfg.Cmd = cfg.Command
 	}
+	if cfg.Stdin {
+		containerCfg.OpenStdin = true
+		containerCfg.StdinOnce = true
+		containerCfg.AttachStdin = true
+	}
 
 	hostCfg := &container.HostConfig{
 		Resources: container.Resources{
@@ -92,6 +502,40 @@ func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (stri
 		},
 		SecurityOpt: []string{"no-new-privileges"},
 		Binds:       cfg.Binds,
+		ExtraHosts:  cfg.ExtraHosts,
+		CapAdd:      cfg.CapAdd,
+		CapDrop:     cfg.CapDrop,
+	}
+	for _, s := range cfg.DNS {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid dns server %q: %w", s, err)
+		}
+		hostCfg.DNS = append(hostCfg.DNS, addr)
+	}
+	if cfg.NoNetwork {
+		hostCfg.NetworkMode = "none"
+	}
+	if cfg.LogDriver != "" {
+		hostCfg.LogConfig = container.LogConfig{Type: cfg.LogDriver}
+	}
+	if cfg.GPUs != "" {
+		count, err := ParseGPUs(cfg.GPUs)
+		if err != nil {
+			return "", err
+		}
+		hostCfg.DeviceRequests = []container.DeviceRequest{{
+			Driver:       "nvidia",
+			Count:        count,
+			Capabilities: [][]string{{"gpu"}},
+		}}
+	}
+	if cfg.RestartPolicy != "" {
+		policy, err := ParseRestartPolicy(cfg.RestartPolicy)
+		if err != nil {
+			return "", err
+		}
+		hostCfg.RestartPolicy = policy
 	}
 
 	result, err := c.cli.ContainerCreate(ctx, client.ContainerCreateOptions{
@@ -100,6 +544,9 @@ func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (stri
 		Name:       cfg.Name,
 	})
 	if err != nil {
+		if cfg.Name != "" && cerrdefs.IsConflict(err) {
+			return "", fmt.Errorf("creating container: %w: %w", ErrNameConflict, err)
+		}
 		return "", fmt.Errorf("creating container: %w", err)
 	}
 
@@ -129,6 +576,40 @@ func (c *Client) StartContainer(ctx context.Context, containerID string) error {
 	return err
 }
 
+// Stdin is the stdin of a container, attached with AttachStdin.
+type Stdin struct {
+	resp client.ContainerAttachResult
+}
+
+// AttachStdin attaches to the stdin of a container created with Stdin set.
+// Attach before starting the container: the daemon wires the attachment up
+// as the process starts, so nothing it reads can race ahead of it. Write to
+// it once the container is started.
+func (c *Client) AttachStdin(ctx context.Context, containerID string) (*Stdin, error) {
+	resp, err := c.cli.ContainerAttach(ctx, containerID, client.ContainerAttachOptions{
+		Stream: true,
+		Stdin:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("attaching to container: %w", err)
+	}
+	return &Stdin{resp: resp}, nil
+}
+
+// Write writes data to the container's stdin and closes it, so the process
+// sees EOF once it has read the data.
+func (s *Stdin) Write(data string) error {
+	if _, err := io.WriteString(s.resp.Conn, data); err != nil {
+		return fmt.Errorf("writing stdin: %w", err)
+	}
+	return s.resp.CloseWrite()
+}
+
+// Close releases the attachment.
+func (s *Stdin) Close() {
+	s.resp.Close()
+}
+
 // StopContainer gracefully stops a container with a timeout.
 func (c *Client) StopContainer(ctx context.Context, containerID string, timeoutSeconds int) error {
 	_, err := c.cli.ContainerStop(ctx, containerID, client.ContainerStopOptions{
@@ -137,6 +618,45 @@ func (c *Client) StopContainer(ctx context.Context, containerID string, timeoutS
 	return err
 }
 
+// sigkillExitCode is the exit status of a process terminated by SIGKILL (128+9).
+const sigkillExitCode = 128 + 9
+
+// StopContainerReport stops a container like StopContainer, then inspects it
+// to report its exit code and whether Docker had to escalate to SIGKILL
+// because the container ignored SIGTERM for the whole timeout.
+func (c *Client) StopContainerReport(ctx context.Context, containerID string, timeoutSeconds int) (exitCode int, forced bool, err error) {
+	if err := c.StopContainer(ctx, containerID, timeoutSeconds); err != nil {
+		return -1, false, err
+	}
+	info, err := c.InspectContainer(ctx, containerID)
+	if err != nil {
+		return -1, false, err
+	}
+	if info.Container.State == nil {
+		return -1, false, fmt.Errorf("container %s has no state", containerID)
+	}
+	exitCode = info.Container.State.ExitCode
+	return exitCode, exitCode == sigkillExitCode, nil
+}
+
+// OOMKilled reports whether the kernel killed the container for exceeding its
+// memory limit.
+func (c *Client) OOMKilled(ctx context.Context, containerID string) (bool, error) {
+	info, err := c.InspectContainer(ctx, containerID)
+	if err != nil {
+		return false, err
+	}
+	return stateOOMKilled(containerID, info.Container.State)
+}
+
+// stateOOMKilled reads OOMKilled from an inspected container's state.
+func stateOOMKilled(containerID string, state *container.State) (bool, error) {
+	if state == nil {
+		return false, fmt.Errorf("container %s has no state", containerID)
+	}
+	return state.OOMKilled, nil
+}
+
 // PauseContainer freezes a running container via cgroup freezer.
 func (c *Client) PauseContainer(ctx context.Context, containerID string) error {
 	_, err := c.cli.ContainerPause(ctx, containerID, client.ContainerPauseOptions{})
@@ -149,13 +669,19 @@ func (c *Client) UnpauseContainer(ctx context.Context, containerID string) error
 	return err
 }
 
-// GetLogs retrieves stdout and stderr from a container.
-func (c *Client) GetLogs(ctx context.Context, containerID string, tail string) (string, error) {
+// GetLogs retrieves stdout and stderr from a container. since limits the
+// output to lines written after it (a Unix timestamp or RFC 3339 time; empty
+// for all). The error wraps ErrContainerNotFound if the container is gone.
+func (c *Client) GetLogs(ctx context.Context, containerID string, tail string, since string) (string, error) {
 	result, err := c.cli.ContainerLogs(ctx, containerID, client.ContainerLogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
 		Tail:       tail,
+		Since:      since,
 	})
+	if cerrdefs.IsNotFound(err) {
+		return "", fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
 	if err != nil {
 		return "", fmt.Errorf("getting logs: %w", err)
 	}
@@ -171,10 +697,57 @@ func (c *Client) GetLogs(ctx context.Context, containerID string, tail string) (
 }
 
 // WaitContainer blocks until the container exits and returns the exit code.
+// It must be called before the container is started.
 func (c *Client) WaitContainer(ctx context.Context, containerID string) (int64, error) {
 	log.Printf("[docker] Waiting for container %s to exit", containerID[:12])
+	return c.waitContainer(ctx, containerID, container.WaitConditionNextExit)
+}
+
+// WaitRestarts follows a container that has exited once (with exitCode, as
+// returned by WaitContainer) through any restarts its restart policy
+// triggers, and returns the exit code of its final exit.
+func (c *Client) WaitRestarts(ctx context.Context, containerID string, exitCode int64) (int64, error) {
+	for {
+		info, err := c.InspectContainer(ctx, containerID)
+		if err != nil {
+			return exitCode, err
+		}
+		if info.Container.State == nil || !info.Container.State.Restarting {
+			return exitCode, nil
+		}
+		log.Printf("[docker] Container %s is restarting (restart %d)", containerID[:12], info.Container.RestartCount+1)
+		// A restarting container counts as running, so this returns at its next exit
+		if exitCode, err = c.waitContainer(ctx, containerID, container.WaitConditionNotRunning); err != nil {
+			return exitCode, err
+		}
+	}
+}
+
+// waitContainer waits for containerID to reach condition. A daemon restart
+// drops the wait mid-stream even though the container (with live-restore)
+// keeps running, so on a lost connection it reconnects and re-attaches
+// instead of reporting the container as failed. If the daemon doesn't come
+// back, the error wraps ErrDaemonUnavailable.
+func (c *Client) waitContainer(ctx context.Context, containerID string, condition container.WaitCondition) (int64, error) {
+	for {
+		exitCode, err := c.waitOnce(ctx, containerID, condition)
+		if err == nil || ctx.Err() != nil || !daemonGone(err) {
+			return exitCode, err
+		}
+		log.Printf("[docker] Lost the daemon while waiting for container %s (%v) — reconnecting", containerID[:12], err)
+		if err := c.reconnect(ctx); err != nil {
+			return -1, fmt.Errorf("waiting for container: %w", err)
+		}
+		log.Printf("[docker] Daemon is back — re-attaching to container %s", containerID[:12])
+		// The container may have exited while we were away; a next-exit
+		// wait would then block forever.
+		condition = container.WaitConditionNotRunning
+	}
+}
+
+func (c *Client) waitOnce(ctx context.Context, containerID string, condition container.WaitCondition) (int64, error) {
 	waitResult := c.cli.ContainerWait(ctx, containerID, client.ContainerWaitOptions{
-		Condition: container.WaitConditionNextExit,
+		Condition: condition,
 	})
 
 	select {
@@ -191,11 +764,52 @@ func (c *Client) WaitContainer(ctx context.Context, containerID string) (int64,
 	}
 }
 
-// RemoveContainer removes a container.
+// ErrDaemonUnavailable is returned when the Docker daemon stopped responding
+// and didn't come back within daemonReconnectTimeout.
+var ErrDaemonUnavailable = errors.New("docker daemon unavailable")
+
+// daemonReconnectTimeout is how long reconnect waits for the daemon to return.
+const daemonReconnectTimeout = 2 * time.Minute
+
+// daemonGone reports whether err means the connection to the daemon was lost,
+// as opposed to an error the daemon reported.
+func daemonGone(err error) bool {
+	return client.IsErrConnectionFailed(err) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// reconnect drops the client's pooled connections, which may be dead after a
+// daemon restart, and pings the daemon with backoff until it answers.
+func (c *Client) reconnect(ctx context.Context) error {
+	_ = c.cli.Close() // Only closes idle connections; the client stays usable
+	deadline := time.Now().Add(daemonReconnectTimeout)
+	backoff := time.Second
+	for {
+		if _, err := c.cli.Ping(ctx, client.PingOptions{}); err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrDaemonUnavailable
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 10*time.Second)
+	}
+}
+
+// RemoveContainer removes a container. The error wraps ErrContainerNotFound
+// if it was already gone.
 func (c *Client) RemoveContainer(ctx context.Context, containerID string) error {
 	_, err := c.cli.ContainerRemove(ctx, containerID, client.ContainerRemoveOptions{
 		Force: true,
 	})
+	if cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
 	return err
 }
 
@@ -208,6 +822,105 @@ func (c *Client) InspectContainer(ctx context.Context, containerID string) (*cli
 	return &resp, nil
 }
 
+// ErrContainerNotFound is returned (wrapped) by InspectDetails, GetLogs and
+// RemoveContainer when the container no longer exists.
+var ErrContainerNotFound = errors.New("container not found")
+
+// ContainerDetails is the subset of a container inspect that is useful for
+// debugging a run.
+type ContainerDetails struct {
+	ContainerID  string             `json:"container_id"`
+	Name         string             `json:"name"`
+	Image        string             `json:"image"`
+	Status       string             `json:"status"` // created, running, paused, restarting, exited, ...
+	Running      bool               `json:"running"`
+	Paused       bool               `json:"paused"`
+	OOMKilled    bool               `json:"oom_killed"`
+	Pid          int                `json:"pid,omitempty"`
+	ExitCode     int                `json:"exit_code"`
+	Error        string             `json:"error,omitempty"`
+	StartedAt    string             `json:"started_at,omitempty"`
+	FinishedAt   string             `json:"finished_at,omitempty"`
+	RestartCount int                `json:"restart_count"`
+	Networks     []ContainerNetwork `json:"networks"`
+	Mounts       []ContainerMount   `json:"mounts"`
+}
+
+// ContainerNetwork is a network a container is attached to.
+type ContainerNetwork struct {
+	Name       string `json:"name"`
+	IPAddress  string `json:"ip_address,omitempty"`
+	Gateway    string `json:"gateway,omitempty"`
+	MacAddress string `json:"mac_address,omitempty"`
+}
+
+// ContainerMount is a volume or bind mount of a container.
+type ContainerMount struct {
+	Type        string `json:"type"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	ReadWrite   bool   `json:"read_write"`
+}
+
+// InspectDetails inspects a container and returns its debugging details.
+func (c *Client) InspectDetails(ctx context.Context, containerID string) (*ContainerDetails, error) {
+	info, err := c.InspectContainer(ctx, containerID)
+	if cerrdefs.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctr := info.Container
+	d := &ContainerDetails{
+		ContainerID:  ctr.ID,
+		Name:         strings.TrimPrefix(ctr.Name, "/"),
+		Image:        ctr.Image,
+		RestartCount: ctr.RestartCount,
+		Networks:     []ContainerNetwork{},
+		Mounts:       []ContainerMount{},
+	}
+	if ctr.Config != nil {
+		d.Image = ctr.Config.Image // The reference the run asked for, not the image ID
+	}
+	if st := ctr.State; st != nil {
+		d.Status = string(st.Status)
+		d.Running = st.Running
+		d.Paused = st.Paused
+		d.OOMKilled = st.OOMKilled
+		d.Pid = st.Pid
+		d.ExitCode = st.ExitCode
+		d.Error = st.Error
+		d.StartedAt = st.StartedAt
+		d.FinishedAt = st.FinishedAt
+	}
+	if ctr.NetworkSettings != nil {
+		for _, name := range slices.Sorted(maps.Keys(ctr.NetworkSettings.Networks)) {
+			n := ContainerNetwork{Name: name}
+			if ep := ctr.NetworkSettings.Networks[name]; ep != nil {
+				if ep.IPAddress.IsValid() {
+					n.IPAddress = ep.IPAddress.String()
+				}
+				if ep.Gateway.IsValid() {
+					n.Gateway = ep.Gateway.String()
+				}
+				n.MacAddress = ep.MacAddress.String()
+			}
+			d.Networks = append(d.Networks, n)
+		}
+	}
+	for _, m := range ctr.Mounts {
+		d.Mounts = append(d.Mounts, ContainerMount{
+			Type:        string(m.Type),
+			Source:      m.Source,
+			Destination: m.Destination,
+			ReadWrite:   m.RW,
+		})
+	}
+	return d, nil
+}
+
 // BuildImage builds a Docker image from a tar build context.
 func (c *Client) BuildImage(ctx context.Context, buildContext io.Reader, imageTag, dockerfilePath string) (string, error) {
 	log.Printf("[docker] Building image: %s (Dockerfile: %s)", imageTag, dockerfilePath)
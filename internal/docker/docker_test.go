@@ -0,0 +1,19 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+func TestStateOOMKilled(t *testing.T) {
+	if oom, err := stateOOMKilled("c1", &container.State{OOMKilled: true, ExitCode: 137}); err != nil || !oom {
+		t.Errorf("OOM-killed state = %v, %v; want true, nil", oom, err)
+	}
+	if oom, err := stateOOMKilled("c1", &container.State{ExitCode: 137}); err != nil || oom {
+		t.Errorf("SIGKILLed state = %v, %v; want false, nil", oom, err)
+	}
+	if _, err := stateOOMKilled("c1", nil); err == nil {
+		t.Error("missing state: want an error")
+	}
+}
@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// Hosts is the set of Docker daemons runs can be placed on: the default
+// daemon from the environment plus named remote daemons. Jobs select a
+// named host with docker_host; jobs without one run on the default.
+type Hosts struct {
+	Default *Client
+	named   map[string]*Client
+}
+
+// RemoteHost is the address of a named daemon (e.g. "tcp://10.0.0.5:2376")
+// and whether to reach it over TLS.
+type RemoteHost struct {
+	Addr string
+	TLS  bool
+}
+
+// NewHosts connects to each named daemon alongside the default client. Hosts
+// marked TLS use tlsFiles; the others connect in plain text. Named clients
+// inherit the default's PullTimeout and MaxConcurrentPulls; the pull limit
+// applies per daemon.
+func NewHosts(defaultClient *Client, remotes map[string]RemoteHost, tlsFiles TLSFiles) (*Hosts, error) {
+	h := &Hosts{Default: defaultClient, named: make(map[string]*Client, len(remotes))}
+	for name, remote := range remotes {
+		var files TLSFiles
+		if remote.TLS {
+			files = tlsFiles
+		}
+		c, err := NewRemote(remote.Addr, files)
+		if err != nil {
+			h.Close()
+			return nil, fmt.Errorf("docker host %q: %w", name, err)
+		}
+		c.PullTimeout = defaultClient.PullTimeout
+		c.MaxConcurrentPulls = defaultClient.MaxConcurrentPulls
+		h.named[name] = c
+	}
+	return h, nil
+}
+
+// Get returns the client for a named host, or the default client for "".
+// It reports false if no host by that name is configured.
+func (h *Hosts) Get(name string) (*Client, bool) {
+	if name == "" {
+		return h.Default, true
+	}
+	c, ok := h.named[name]
+	return c, ok
+}
+
+// Names returns the configured named hosts in sorted order.
+func (h *Hosts) Names() []string {
+	names := make([]string, 0, len(h.named))
+	for name := range h.named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GPUHosts reports which daemons support GPU containers, keyed by host name
+// ("" for the default). Daemons that can't be queried are left out.
+func (h *Hosts) GPUHosts(ctx context.Context) map[string]bool {
+	gpu := map[string]bool{}
+	for _, name := range append([]string{""}, h.Names()...) {
+		c, _ := h.Get(name)
+		ok, err := c.SupportsGPUs(ctx)
+		if err != nil {
+			log.Printf("[docker] Warning: can't tell whether host %q supports GPUs: %v", name, err)
+			continue
+		}
+		if ok {
+			gpu[name] = true
+		}
+	}
+	return gpu
+}
+
+// Close closes the named clients; the default client is left to its owner.
+func (h *Hosts) Close() {
+	for _, c := range h.named {
+		_ = c.Close()
+	}
+}
@@ -0,0 +1,143 @@
+// Package envcrypt encrypts job env at rest with AES-256-GCM. Each sealed
+// env is stored with the ID of the key that sealed it, so keys can be
+// rotated: new writes use the active key while older ones stay readable
+// until Rotate re-seals them. An env is sealed with the ID of the job or run
+// it belongs to as additional data, so a sealed env copied onto another row
+// doesn't decrypt.
+package envcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoKey is returned when an env was sealed with a key that isn't configured.
+var ErrNoKey = errors.New("env encryption key not configured")
+
+// Keyring holds the configured keys. A nil Keyring means encryption is off:
+// envs are written in plaintext, and only plaintext envs can be read.
+type Keyring struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+// Parse parses ENV_ENCRYPTION_KEYS: comma-separated id:key pairs, where key
+// is 32 bytes in standard base64. The first key is the active one; the rest
+// only decrypt. An empty spec returns a nil Keyring.
+func Parse(spec string) (*Keyring, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	k := &Keyring{keys: map[string]cipher.AEAD{}}
+	for _, entry := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("key %q must be id:base64key", entry)
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes, base64-encoded", id)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+		if k.active == "" {
+			k.active = id
+		}
+	}
+	return k, nil
+}
+
+// ActiveID returns the ID of the key new envs are sealed with ("" when off).
+func (k *Keyring) ActiveID() string {
+	if k == nil {
+		return ""
+	}
+	return k.active
+}
+
+// EncodeEnv prepares env for the jobs table. With encryption off it returns
+// the JSON for the env column and no sealed value. Otherwise the env column
+// gets "{}" and the JSON is sealed with the active key, bound to owner: the
+// ID of the job or run the env is stored on.
+func (k *Keyring) EncodeEnv(env map[string]string, owner uuid.UUID) (plain, sealed []byte, keyID *string, err error) {
+	if env == nil {
+		env = map[string]string{}
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if k == nil {
+		return data, nil, nil, nil
+	}
+
+	aead := k.keys[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	id := k.active
+	return []byte("{}"), aead.Seal(nonce, nonce, data, owner[:]), &id, nil
+}
+
+// DecodeEnv reads an env stored by EncodeEnv for owner. Rows with no key ID
+// are plaintext, including those written before encryption was turned on.
+func (k *Keyring) DecodeEnv(plain, sealed []byte, keyID *string, owner uuid.UUID) (map[string]string, error) {
+	env := map[string]string{}
+	data := plain
+	if keyID != nil {
+		var err error
+		if data, _, err = k.open(sealed, *keyID, owner); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) == 0 {
+		return env, nil
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// open decrypts an env sealed with keyID for owner. Envs sealed before they
+// were bound to their owner are opened without it, and reported as legacy so
+// Rotate can re-seal them; a bound env never opens that way.
+func (k *Keyring) open(sealed []byte, keyID string, owner uuid.UUID) (data []byte, legacy bool, err error) {
+	var aead cipher.AEAD
+	if k != nil {
+		aead = k.keys[keyID]
+	}
+	if aead == nil {
+		return nil, false, fmt.Errorf("%w: %q", ErrNoKey, keyID)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, false, errors.New("sealed env is truncated")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	if data, err = aead.Open(nil, nonce, ciphertext, owner[:]); err == nil {
+		return data, false, nil
+	}
+	if data, err = aead.Open(nil, nonce, ciphertext, nil); err == nil {
+		return data, true, nil
+	}
+	return nil, false, fmt.Errorf("decrypting env with key %q: %w", keyID, err)
+}
@@ -0,0 +1,122 @@
+package envcrypt
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newKey(t *testing.T) string {
+	t.Helper()
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func mustParse(t *testing.T, spec string) *Keyring {
+	t.Helper()
+	k, err := Parse(spec)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", spec, err)
+	}
+	return k
+}
+
+func TestRoundTrip(t *testing.T) {
+	env := map[string]string{"TOKEN": "s3cret", "REGION": "eu"}
+	owner := uuid.New()
+
+	for name, k := range map[string]*Keyring{"off": nil, "on": mustParse(t, "a:"+newKey(t))} {
+		t.Run(name, func(t *testing.T) {
+			plain, sealed, keyID, err := k.EncodeEnv(env, owner)
+			if err != nil {
+				t.Fatalf("EncodeEnv: %v", err)
+			}
+			if (k == nil) != (keyID == nil) {
+				t.Fatalf("keyID = %v with keyring %v", keyID, k)
+			}
+			if k != nil && string(plain) != "{}" {
+				t.Errorf("plain = %s, want {} when encrypting", plain)
+			}
+			got, err := k.DecodeEnv(plain, sealed, keyID, owner)
+			if err != nil {
+				t.Fatalf("DecodeEnv: %v", err)
+			}
+			if !maps.Equal(got, env) {
+				t.Errorf("DecodeEnv = %v, want %v", got, env)
+			}
+		})
+	}
+}
+
+// TestBoundToOwner checks that a sealed env copied onto another job or run
+// doesn't decrypt.
+func TestBoundToOwner(t *testing.T) {
+	k := mustParse(t, "a:"+newKey(t))
+	plain, sealed, keyID, err := k.EncodeEnv(map[string]string{"TOKEN": "s3cret"}, uuid.New())
+	if err != nil {
+		t.Fatalf("EncodeEnv: %v", err)
+	}
+	if _, err := k.DecodeEnv(plain, sealed, keyID, uuid.New()); err == nil {
+		t.Fatal("DecodeEnv with another owner succeeded")
+	}
+}
+
+// TestLegacyEnv checks that envs sealed before they were bound to their owner
+// still decrypt, and are reported for re-sealing.
+func TestLegacyEnv(t *testing.T) {
+	k := mustParse(t, "a:"+newKey(t))
+	aead := k.keys["a"]
+	nonce := make([]byte, aead.NonceSize())
+	sealed := aead.Seal(nonce, nonce, []byte(`{"A":"1"}`), nil)
+	keyID := "a"
+
+	env, err := k.DecodeEnv([]byte("{}"), sealed, &keyID, uuid.New())
+	if err != nil || env["A"] != "1" {
+		t.Fatalf("DecodeEnv(legacy) = %v, %v; want A=1", env, err)
+	}
+	if _, legacy, err := k.open(sealed, keyID, uuid.New()); err != nil || !legacy {
+		t.Errorf("open(legacy) = legacy %v, %v; want true, nil", legacy, err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	keyA, keyB := newKey(t), newKey(t)
+	owner := uuid.New()
+	env := map[string]string{"TOKEN": "s3cret"}
+
+	old := mustParse(t, "a:"+keyA)
+	plain, sealed, keyID, err := old.EncodeEnv(env, owner)
+	if err != nil {
+		t.Fatalf("EncodeEnv: %v", err)
+	}
+
+	// After adding b as the active key, envs sealed with a still read
+	rotated := mustParse(t, "b:"+keyB+",a:"+keyA)
+	if rotated.ActiveID() != "b" {
+		t.Fatalf("ActiveID() = %q, want b", rotated.ActiveID())
+	}
+	got, err := rotated.DecodeEnv(plain, sealed, keyID, owner)
+	if err != nil || !maps.Equal(got, env) {
+		t.Fatalf("DecodeEnv with old key = %v, %v; want %v", got, err, env)
+	}
+	_, _, newKeyID, err := rotated.EncodeEnv(env, owner)
+	if err != nil || newKeyID == nil || *newKeyID != "b" {
+		t.Fatalf("EncodeEnv after rotation used key %v, %v; want b", newKeyID, err)
+	}
+
+	// Once a is dropped, its envs report the missing key
+	dropped := mustParse(t, "b:"+keyB)
+	if _, err := dropped.DecodeEnv(plain, sealed, keyID, owner); !errors.Is(err, ErrNoKey) {
+		t.Errorf("DecodeEnv with key removed: err = %v, want ErrNoKey", err)
+	}
+	if _, err := (*Keyring)(nil).DecodeEnv(plain, sealed, keyID, owner); !errors.Is(err, ErrNoKey) {
+		t.Errorf("DecodeEnv with encryption off: err = %v, want ErrNoKey", err)
+	}
+}
@@ -0,0 +1,92 @@
+package envcrypt
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/orbex-dev/orbex/internal/database"
+)
+
+// Rotate re-seals, with the active key, every job env that is still in
+// plaintext, sealed with another key, or sealed before envs were bound to
+// their job. Envs sealed with a key no longer configured are skipped and
+// logged. It returns the number of jobs updated. With encryption off it does
+// nothing.
+func (k *Keyring) Rotate(ctx context.Context, db *database.DB) (int, error) {
+	if k == nil {
+		return 0, nil
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, env_sealed, env_key_id FROM jobs
+		WHERE env_key_id IS NOT NULL OR env <> '{}'::jsonb
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("listing jobs to re-encrypt: %w", err)
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		var sealed []byte
+		var keyID *string
+		if err := rows.Scan(&id, &sealed, &keyID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if keyID != nil && *keyID == k.active {
+			// Already on the active key; only legacy, unbound envs need work
+			if _, legacy, err := k.open(sealed, *keyID, id); err == nil && !legacy {
+				continue
+			}
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, id := range ids {
+		if err := k.reseal(ctx, db, id); err != nil {
+			log.Printf("[envcrypt] Warning: could not re-encrypt env of job %s: %v", id, err)
+			continue
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// reseal re-seals one job's env with the active key, holding the row lock so
+// a concurrent update can't be overwritten with the old value.
+func (k *Keyring) reseal(ctx context.Context, db *database.DB, jobID uuid.UUID) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var plain, sealed []byte
+	var keyID *string
+	if err := tx.QueryRow(ctx, `
+		SELECT env, env_sealed, env_key_id FROM jobs WHERE id = $1 FOR UPDATE
+	`, jobID).Scan(&plain, &sealed, &keyID); err != nil {
+		return err
+	}
+	env, err := k.DecodeEnv(plain, sealed, keyID, jobID)
+	if err != nil {
+		return err
+	}
+	plain, sealed, keyID, err = k.EncodeEnv(env, jobID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE jobs SET env = $1, env_sealed = $2, env_key_id = $3 WHERE id = $4
+	`, plain, sealed, keyID, jobID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
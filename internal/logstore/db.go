@@ -0,0 +1,52 @@
+package logstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/orbex-dev/orbex/internal/database"
+)
+
+// DB stores full logs in the run_logs table, byte for byte. Rows go with
+// their run.
+type DB struct {
+	db *database.DB
+}
+
+// NewDB creates a database-backed store.
+func NewDB(db *database.DB) *DB {
+	return &DB{db: db}
+}
+
+func (s *DB) Put(ctx context.Context, runID uuid.UUID, r io.Reader) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Pool.Exec(ctx, `
+		INSERT INTO run_logs (run_id, content) VALUES ($1, $2)
+		ON CONFLICT (run_id) DO UPDATE SET content = EXCLUDED.content
+	`, runID, content)
+	return err
+}
+
+func (s *DB) Get(ctx context.Context, runID uuid.UUID) (io.ReadCloser, error) {
+	var content []byte
+	err := s.db.Pool.QueryRow(ctx, `SELECT content FROM run_logs WHERE run_id = $1`, runID).Scan(&content)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *DB) Delete(ctx context.Context, runID uuid.UUID) error {
+	_, err := s.db.Pool.Exec(ctx, `DELETE FROM run_logs WHERE run_id = $1`, runID)
+	return err
+}
@@ -0,0 +1,85 @@
+package logstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/orbex-dev/orbex/internal/database"
+)
+
+// testDB connects to ORBEX_TEST_DATABASE_URL and applies the migrations,
+// skipping the test when no database is configured.
+func testDB(t *testing.T) *database.DB {
+	t.Helper()
+	url := os.Getenv("ORBEX_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("ORBEX_TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	db, err := database.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	if err := db.Migrate(ctx, filepath.Join("..", "database", "migrations")); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	return db
+}
+
+// TestDBRoundTripsBytes stores output that isn't valid UTF-8 text, which a
+// TEXT column would reject, and checks it comes back byte for byte.
+func TestDBRoundTripsBytes(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	var userID, jobID, runID uuid.UUID
+	if err := db.Pool.QueryRow(ctx, `
+		INSERT INTO users (email, password) VALUES ($1, 'x') RETURNING id
+	`, uuid.NewString()+"@example.com").Scan(&userID); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Pool.Exec(context.Background(), "DELETE FROM users WHERE id = $1", userID)
+	})
+	if err := db.Pool.QueryRow(ctx, `
+		INSERT INTO jobs (user_id, name, image) VALUES ($1, 'test', 'alpine') RETURNING id
+	`, userID).Scan(&jobID); err != nil {
+		t.Fatalf("creating job: %v", err)
+	}
+	if err := db.Pool.QueryRow(ctx, `
+		INSERT INTO job_runs (job_id, user_id) VALUES ($1, $2) RETURNING id
+	`, jobID, userID).Scan(&runID); err != nil {
+		t.Fatalf("creating run: %v", err)
+	}
+
+	s := NewDB(db)
+	want := []byte("line 1\n\x00binary\xff\xfe\nh\xc3llo\n")
+	if err := s.Put(ctx, runID, bytes.NewReader(want)); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	r, err := s.Get(ctx, runID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(r)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Get = %q, want %q", got, want)
+	}
+
+	if err := s.Delete(ctx, runID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, runID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
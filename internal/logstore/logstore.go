@@ -0,0 +1,88 @@
+// Package logstore keeps the full output of runs whose logs are too long for
+// job_runs.logs_tail. The backend is chosen with LOG_STORE.
+package logstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/orbex-dev/orbex/internal/database"
+	"github.com/orbex-dev/orbex/internal/storage"
+)
+
+// ErrNotFound is returned by Get when no logs are stored for the run.
+var ErrNotFound = errors.New("logs not found")
+
+// ErrUnavailable is returned for logs kept in a backend this server can't
+// reach, e.g. s3 without object storage configured.
+var ErrUnavailable = errors.New("log store backend not available")
+
+// Store keeps a run's full logs, keyed by run ID.
+type Store interface {
+	Put(ctx context.Context, runID uuid.UUID, r io.Reader) error
+	Get(ctx context.Context, runID uuid.UUID) (io.ReadCloser, error)
+	Delete(ctx context.Context, runID uuid.UUID) error
+}
+
+// Stores puts new full logs in the LOG_STORE backend and finds earlier ones
+// in whichever backend the run recorded, so changing LOG_STORE doesn't lose
+// them.
+type Stores struct {
+	backend  string           // Name of the backend new logs are put in
+	backends map[string]Store // Every backend this server can reach, by name
+}
+
+// New returns the stores with backend ("s3" for object storage, or "db" for
+// the run_logs table) taking new logs. Both are available for reading when
+// storageClient is non-nil; otherwise only db is.
+func New(backend string, db *database.DB, storageClient *storage.Client) (*Stores, error) {
+	s := &Stores{backend: backend, backends: map[string]Store{"db": NewDB(db)}}
+	if storageClient != nil {
+		s.backends["s3"] = NewS3(storageClient)
+	}
+	switch backend {
+	case "s3", "db":
+		if _, ok := s.backends[backend]; !ok {
+			return nil, fmt.Errorf("log store %q needs object storage", backend)
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown log store %q (want s3 or db)", backend)
+}
+
+// Backend names the backend Put writes to, to be recorded on the run.
+func (s *Stores) Backend() string {
+	return s.backend
+}
+
+// Has reports whether logs recorded under backend can be read.
+func (s *Stores) Has(backend string) bool {
+	_, ok := s.backends[backend]
+	return ok
+}
+
+// Put stores a run's full logs in the current backend.
+func (s *Stores) Put(ctx context.Context, runID uuid.UUID, r io.Reader) error {
+	return s.backends[s.backend].Put(ctx, runID, r)
+}
+
+// Get opens a run's full logs from the backend they were put in.
+func (s *Stores) Get(ctx context.Context, backend string, runID uuid.UUID) (io.ReadCloser, error) {
+	store, ok := s.backends[backend]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnavailable, backend)
+	}
+	return store.Get(ctx, runID)
+}
+
+// Delete removes a run's full logs from the backend they were put in.
+func (s *Stores) Delete(ctx context.Context, backend string, runID uuid.UUID) error {
+	store, ok := s.backends[backend]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnavailable, backend)
+	}
+	return store.Delete(ctx, runID)
+}
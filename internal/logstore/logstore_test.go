@@ -0,0 +1,104 @@
+package logstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// memStore is an in-memory Store.
+type memStore map[uuid.UUID][]byte
+
+func (m memStore) Put(_ context.Context, runID uuid.UUID, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	m[runID] = b
+	return err
+}
+
+func (m memStore) Get(_ context.Context, runID uuid.UUID) (io.ReadCloser, error) {
+	b, ok := m[runID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m memStore) Delete(_ context.Context, runID uuid.UUID) error {
+	delete(m, runID)
+	return nil
+}
+
+// TestStoresRoutesByBackend checks that logs put before LOG_STORE changed
+// are still read and deleted from the backend that holds them.
+func TestStoresRoutesByBackend(t *testing.T) {
+	ctx := context.Background()
+	oldBackend, newBackend := memStore{}, memStore{}
+	runID := uuid.New()
+	oldBackend[runID] = []byte("old logs")
+
+	s := &Stores{backend: "new", backends: map[string]Store{"old": oldBackend, "new": newBackend}}
+	if got := s.Backend(); got != "new" {
+		t.Fatalf("Backend() = %q, want new", got)
+	}
+
+	r, err := s.Get(ctx, "old", runID)
+	if err != nil {
+		t.Fatalf("Get from old backend: %v", err)
+	}
+	b, _ := io.ReadAll(r)
+	if string(b) != "old logs" {
+		t.Fatalf("Get from old backend = %q, want %q", b, "old logs")
+	}
+	if _, err := s.Get(ctx, "new", runID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get from new backend: err = %v, want ErrNotFound", err)
+	}
+
+	other := uuid.New()
+	if err := s.Put(ctx, other, bytes.NewReader([]byte("new logs"))); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := newBackend[other]; !ok {
+		t.Fatal("Put didn't write to the current backend")
+	}
+
+	if err := s.Delete(ctx, "old", runID); err != nil {
+		t.Fatalf("Delete from old backend: %v", err)
+	}
+	if _, ok := oldBackend[runID]; ok {
+		t.Fatal("Delete left the logs in the old backend")
+	}
+}
+
+func TestStoresUnavailableBackend(t *testing.T) {
+	ctx := context.Background()
+	s := &Stores{backend: "db", backends: map[string]Store{"db": memStore{}}}
+	if s.Has("s3") {
+		t.Fatal("Has(s3) = true without an s3 backend")
+	}
+	if _, err := s.Get(ctx, "s3", uuid.New()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Get: err = %v, want ErrUnavailable", err)
+	}
+	if err := s.Delete(ctx, "s3", uuid.New()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Delete: err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestNewNeedsObjectStorageForS3(t *testing.T) {
+	if _, err := New("s3", nil, nil); err == nil {
+		t.Fatal("New(s3) without object storage succeeded")
+	}
+	if _, err := New("disk", nil, nil); err == nil {
+		t.Fatal("New(disk) succeeded")
+	}
+	s, err := New("db", nil, nil)
+	if err != nil {
+		t.Fatalf("New(db): %v", err)
+	}
+	if !s.Has("db") || s.Has("s3") {
+		t.Fatalf("New(db) without object storage: Has(db) = %v, Has(s3) = %v", s.Has("db"), s.Has("s3"))
+	}
+}
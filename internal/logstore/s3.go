@@ -0,0 +1,41 @@
+package logstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/orbex-dev/orbex/internal/storage"
+)
+
+// S3 stores full logs as objects under logs/ in the storage bucket.
+type S3 struct {
+	storage *storage.Client
+}
+
+// NewS3 creates an object-storage-backed store.
+func NewS3(storageClient *storage.Client) *S3 {
+	return &S3{storage: storageClient}
+}
+
+func logKey(runID uuid.UUID) string {
+	return fmt.Sprintf("logs/%s.log", runID)
+}
+
+func (s *S3) Put(ctx context.Context, runID uuid.UUID, r io.Reader) error {
+	return s.storage.Upload(ctx, logKey(runID), r, -1, "text/plain")
+}
+
+func (s *S3) Get(ctx context.Context, runID uuid.UUID) (io.ReadCloser, error) {
+	r, err := s.storage.Download(ctx, logKey(runID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return r, err
+}
+
+func (s *S3) Delete(ctx context.Context, runID uuid.UUID) error {
+	return s.storage.Delete(ctx, logKey(runID))
+}
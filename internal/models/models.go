@@ -19,6 +19,42 @@ const (
 	RunStatusCancelled RunStatus = "cancelled"
 )
 
+// IsTerminal reports whether a run in this status will never change again.
+func (s RunStatus) IsTerminal() bool {
+	return s == RunStatusSucceeded || s == RunStatusFailed || s == RunStatusCancelled
+}
+
+// FailureReason classifies why a run failed. The killed, server_shutdown and
+// pause_limit reasons are recorded on cancelled runs, to tell apart who
+// ended them.
+type FailureReason string
+
+const (
+	FailureImagePull         FailureReason = "image_pull"
+	FailureOOM               FailureReason = "oom"
+	FailureTimeout           FailureReason = "timeout"
+	FailureNonzeroExit       FailureReason = "nonzero_exit"
+	FailureWorkerCrash       FailureReason = "worker_crash"
+	FailureCreateError       FailureReason = "create_error"
+	FailureBudgetExhausted   FailureReason = "budget_exhausted"
+	FailureDaemonUnavailable FailureReason = "daemon_unavailable"
+	FailureStartTimeout      FailureReason = "start_timeout"
+	FailureKilled            FailureReason = "killed"
+	FailureServerShutdown    FailureReason = "server_shutdown"
+	FailurePauseLimit        FailureReason = "pause_limit"
+)
+
+// TriggerSource records what created a run.
+type TriggerSource string
+
+const (
+	TriggerAPI        TriggerSource = "api"
+	TriggerCLI        TriggerSource = "cli"
+	TriggerWebhook    TriggerSource = "webhook"
+	TriggerSchedule   TriggerSource = "schedule"
+	TriggerDependency TriggerSource = "dependency"
+)
+
 // User represents a registered user.
 type User struct {
 	ID        uuid.UUID `json:"id"`
@@ -30,57 +66,136 @@ type User struct {
 
 // APIKey represents an API key for authentication.
 type APIKey struct {
-	ID        uuid.UUID  `json:"id"`
-	UserID    uuid.UUID  `json:"user_id"`
-	Name      string     `json:"name"`
-	KeyHash   string     `json:"-"` // Never serialize hash
-	Prefix    string     `json:"prefix"`
-	LastUsed  *time.Time `json:"last_used,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	KeyHash    string     `json:"-"` // Never serialize hash
+	Prefix     string     `json:"prefix"`
+	LastUsed   *time.Time `json:"last_used,omitempty"`
+	LastUsedIP *string    `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyUsage is one sampled use of an API key.
+type APIKeyUsage struct {
+	ID        int64     `json:"id"`
+	KeyID     uuid.UUID `json:"key_id"`
+	IP        string    `json:"ip"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	UserAgent *string   `json:"user_agent,omitempty"`
+	UsedAt    time.Time `json:"used_at"`
 }
 
 // Job represents a job definition.
 type Job struct {
-	ID             uuid.UUID         `json:"id"`
-	UserID         uuid.UUID         `json:"user_id"`
-	Name           string            `json:"name"`
-	Image          string            `json:"image"`
-	Command        []string          `json:"command,omitempty"`
-	Env            map[string]string `json:"env,omitempty"`
-	MemoryMB       int               `json:"memory_mb"`
-	CPUMillicores  int               `json:"cpu_millicores"`
-	TimeoutSeconds int               `json:"timeout_seconds"`
-	Schedule       *string           `json:"schedule,omitempty"`
-	WebhookToken   *string           `json:"webhook_token,omitempty"`
-	Script         *string           `json:"script,omitempty"`
-	ScriptLang     *string           `json:"script_lang,omitempty"`
-	SourceType     string            `json:"source_type"`
-	GithubRepo     *string           `json:"github_repo,omitempty"`
-	GithubBranch   *string           `json:"github_branch,omitempty"`
-	GithubTokenID  *uuid.UUID        `json:"github_token_id,omitempty"`
-	DockerfilePath *string           `json:"dockerfile_path,omitempty"`
-	SourceConfig   json.RawMessage   `json:"source_config,omitempty"`
-	IsActive       bool              `json:"is_active"`
-	CreatedAt      time.Time         `json:"created_at"`
-	UpdatedAt      time.Time         `json:"updated_at"`
+	ID                        uuid.UUID         `json:"id"`
+	UserID                    uuid.UUID         `json:"user_id"`
+	Name                      string            `json:"name"`
+	Image                     string            `json:"image"`
+	Command                   []string          `json:"command,omitempty"`
+	Env                       map[string]string `json:"env,omitempty"` // Sensitive values read back as "***"
+	SensitiveEnv              []string          `json:"sensitive_env,omitempty"`
+	MemoryMB                  int               `json:"memory_mb"`
+	CPUMillicores             int               `json:"cpu_millicores"`
+	TimeoutSeconds            int               `json:"timeout_seconds"`
+	StartTimeoutSeconds       *int              `json:"start_timeout_seconds,omitempty"` // Enqueue to container running
+	MaxConcurrentRuns         *int              `json:"max_concurrent_runs,omitempty"`   // Runs of this job in flight at once; unset = no limit
+	Schedule                  *string           `json:"schedule,omitempty"`
+	WebhookToken              *string           `json:"webhook_token,omitempty"`
+	Script                    *string           `json:"script,omitempty"`
+	ScriptLang                *string           `json:"script_lang,omitempty"`
+	SourceType                string            `json:"source_type"`
+	GithubRepo                *string           `json:"github_repo,omitempty"`
+	GithubBranch              *string           `json:"github_branch,omitempty"`
+	GithubTokenID             *uuid.UUID        `json:"github_token_id,omitempty"`
+	DockerfilePath            *string           `json:"dockerfile_path,omitempty"`
+	SourceConfig              json.RawMessage   `json:"source_config,omitempty"`
+	RetentionDays             *int              `json:"retention_days,omitempty"`
+	RetentionMaxRuns          *int              `json:"retention_max_runs,omitempty"`
+	LogRetentionDays          *int              `json:"log_retention_days,omitempty"` // Clear run logs after this many days
+	NotifyOn                  string            `json:"notify_on"`
+	NotifyIncludeLogs         bool              `json:"notify_include_logs"`
+	TemplateVars              bool              `json:"template_vars"` // Resolve run variables in command and env
+	DailyRuntimeBudgetSeconds *int              `json:"daily_runtime_budget_seconds,omitempty"`
+	DependsOn                 *uuid.UUID        `json:"depends_on,omitempty"`
+	ImageDigest               *string           `json:"image_digest,omitempty"` // Pin every run to this digest of Image
+	KeepFailedContainers      *bool             `json:"keep_failed_containers,omitempty"`
+	Stdin                     *string           `json:"stdin,omitempty"`          // Written to the container's stdin, then closed
+	NetworkAccess             *bool             `json:"network_access,omitempty"` // Unset = server default
+	RestartPolicy             *string           `json:"restart_policy,omitempty"` // "no" or "on-failure:N"
+	LogDriver                 *string           `json:"log_driver,omitempty"`     // json-file, local or none; nil = daemon default
+	GPUs                      *string           `json:"gpus,omitempty"`           // "all" or a count of NVIDIA GPUs
+	DockerHost                *string           `json:"docker_host,omitempty"`    // Named daemon from DOCKER_HOSTS; nil = default
+	DNS                       []string          `json:"dns,omitempty"`            // DNS servers for the container
+	ExtraHosts                []string          `json:"extra_hosts,omitempty"`    // Extra /etc/hosts entries as "host:ip"
+	CapAdd                    []string          `json:"cap_add,omitempty"`        // Linux capabilities added to the container
+	CapDrop                   []string          `json:"cap_drop,omitempty"`       // Linux capabilities dropped from the container
+	Tags                      []string          `json:"tags,omitempty"`
+	IsActive                  bool              `json:"is_active"`
+	CreatedAt                 time.Time         `json:"created_at"`
+	UpdatedAt                 time.Time         `json:"updated_at"`
+	LastRun                   *RunSummary       `json:"last_run,omitempty"` // Only filled in by the jobs list
+}
+
+// RunSummary is the short form of a run shown alongside its job.
+type RunSummary struct {
+	ID         uuid.UUID  `json:"id"`
+	Status     RunStatus  `json:"status"`
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
 }
 
 // JobRun represents a single execution of a job.
 type JobRun struct {
-	ID           uuid.UUID  `json:"id"`
-	JobID        uuid.UUID  `json:"job_id"`
-	UserID       uuid.UUID  `json:"user_id"`
-	Status       RunStatus  `json:"status"`
-	ContainerID  *string    `json:"container_id,omitempty"`
-	ExitCode     *int       `json:"exit_code,omitempty"`
-	ErrorMessage *string    `json:"error_message,omitempty"`
-	StartedAt    *time.Time `json:"started_at,omitempty"`
-	FinishedAt   *time.Time `json:"finished_at,omitempty"`
-	PausedAt     *time.Time `json:"paused_at,omitempty"`
-	HeartbeatAt  *time.Time `json:"heartbeat_at,omitempty"`
-	DurationMs   *int64     `json:"duration_ms,omitempty"`
-	LogsTail     *string    `json:"logs_tail,omitempty"`
-	CreatedAt    time.Time  `json:"created_at"`
+	ID                 uuid.UUID         `json:"id"`
+	JobID              uuid.UUID         `json:"job_id"`
+	UserID             uuid.UUID         `json:"user_id"`
+	Status             RunStatus         `json:"status"`
+	StatusDetail       *string           `json:"status_detail,omitempty"` // Progress of the current step, e.g. an image pull
+	ContainerID        *string           `json:"container_id,omitempty"`
+	ExitCode           *int              `json:"exit_code,omitempty"`
+	ErrorMessage       *string           `json:"error_message,omitempty"`
+	StartedAt          *time.Time        `json:"started_at,omitempty"`
+	FinishedAt         *time.Time        `json:"finished_at,omitempty"`
+	PausedAt           *time.Time        `json:"paused_at,omitempty"`
+	PauseExpiresAt     *time.Time        `json:"pause_expires_at,omitempty"`    // When a paused run is resumed or killed
+	PauseExpiryAction  *string           `json:"pause_expiry_action,omitempty"` // "resume" or "kill"
+	HeartbeatAt        *time.Time        `json:"heartbeat_at,omitempty"`
+	DurationMs         *int64            `json:"duration_ms,omitempty"`
+	LogsTail           *string           `json:"logs_tail,omitempty"`
+	StopSignal         *string           `json:"stop_signal,omitempty"`    // SIGTERM or SIGKILL, set when killed
+	DockerHost         *string           `json:"docker_host,omitempty"`    // Daemon the run was placed on; nil = default
+	MemoryMB           *int              `json:"memory_mb,omitempty"`      // Set when the run overrode the job's limit
+	CPUMillicores      *int              `json:"cpu_millicores,omitempty"` // Set when the run overrode the job's limit
+	Labels             map[string]string `json:"labels,omitempty"`
+	RequestID          *string           `json:"request_id,omitempty"`   // API request that triggered the run
+	ImageDigest        *string           `json:"image_digest,omitempty"` // Digest of the image the run used
+	FailureReason      *FailureReason    `json:"failure_reason,omitempty"`
+	Result             json.RawMessage   `json:"result,omitempty"`               // Reported by the run as a "::orbex-result::<json>" output line
+	TriggerSource      *TriggerSource    `json:"trigger_source,omitempty"`       // nil for runs created before it was recorded
+	ContainerKeptUntil *time.Time        `json:"container_kept_until,omitempty"` // Failed container kept for debugging until then
+	QueuePosition      *int              `json:"queue_position,omitempty"`       // 1 = next to be picked; pending runs only
+	WaitMs             *int64            `json:"wait_ms,omitempty"`              // Time from being queued (created_at) to started_at
+	Attempt            int               `json:"attempt"`                        // Times a worker has started the run
+	Version            int               `json:"version"`                        // Bumped on start and on reaching a terminal state
+	CreatedAt          time.Time         `json:"created_at"`
+}
+
+// LogLine is one line of run output, as returned by the logs endpoint with
+// ?format=json. Lines that are JSON objects are parsed into Fields, with the
+// conventional level and message keys lifted out.
+type LogLine struct {
+	Text    string          `json:"text"`             // The line as written
+	Fields  json.RawMessage `json:"fields,omitempty"` // Set when the line is a JSON object
+	Level   string          `json:"level,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// RunEvent is a run lifecycle change sent on the account-wide event stream.
+type RunEvent struct {
+	JobRun
+	JobName string `json:"job_name"`
 }
 
 // QueueItem represents a job waiting to be executed.
@@ -98,50 +213,177 @@ type QueueItem struct {
 
 // CreateJobRequest is the payload for creating a new job.
 type CreateJobRequest struct {
-	Name           string            `json:"name"`
-	Image          string            `json:"image"`
-	Command        []string          `json:"command,omitempty"`
-	Env            map[string]string `json:"env,omitempty"`
-	MemoryMB       int               `json:"memory_mb,omitempty"`
-	CPUMillicores  int               `json:"cpu_millicores,omitempty"`
-	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
-	Schedule       *string           `json:"schedule,omitempty"`
-	Script         *string           `json:"script,omitempty"`
-	ScriptLang     *string           `json:"script_lang,omitempty"`
-	SourceType     string            `json:"source_type,omitempty"`
-	GithubRepo     *string           `json:"github_repo,omitempty"`
-	GithubBranch   *string           `json:"github_branch,omitempty"`
-	GithubTokenID  *uuid.UUID        `json:"github_token_id,omitempty"`
-	DockerfilePath *string           `json:"dockerfile_path,omitempty"`
-	SourceConfig   json.RawMessage   `json:"source_config,omitempty"`
+	Name                      string            `json:"name"`
+	Image                     string            `json:"image"`
+	Command                   []string          `json:"command,omitempty"`       // With template_vars, may reference run variables, e.g. {{.RunID}} or {{.Labels.key}}
+	Env                       map[string]string `json:"env,omitempty"`           // With template_vars, values may reference run variables too
+	SensitiveEnv              []string          `json:"sensitive_env,omitempty"` // Env keys to redact besides the built-in patterns
+	MemoryMB                  int               `json:"memory_mb,omitempty"`
+	CPUMillicores             int               `json:"cpu_millicores,omitempty"`
+	Memory                    string            `json:"memory,omitempty"` // e.g. "512Mi", "1Gi"; alternative to memory_mb
+	CPU                       string            `json:"cpu,omitempty"`    // e.g. "0.5", "500m"; alternative to cpu_millicores
+	TimeoutSeconds            int               `json:"timeout_seconds,omitempty"`
+	StartTimeoutSeconds       *int              `json:"start_timeout_seconds,omitempty"` // Fail runs whose container isn't running this long after enqueue
+	MaxConcurrentRuns         *int              `json:"max_concurrent_runs,omitempty"`   // Further runs wait in the queue; 0 = no limit
+	Schedule                  *string           `json:"schedule,omitempty"`
+	Script                    *string           `json:"script,omitempty"`
+	ScriptLang                *string           `json:"script_lang,omitempty"`
+	SourceType                string            `json:"source_type,omitempty"`
+	GithubRepo                *string           `json:"github_repo,omitempty"`
+	GithubBranch              *string           `json:"github_branch,omitempty"`
+	GithubTokenID             *uuid.UUID        `json:"github_token_id,omitempty"`
+	DockerfilePath            *string           `json:"dockerfile_path,omitempty"`
+	SourceConfig              json.RawMessage   `json:"source_config,omitempty"`
+	RetentionDays             *int              `json:"retention_days,omitempty"`
+	RetentionMaxRuns          *int              `json:"retention_max_runs,omitempty"`
+	LogRetentionDays          *int              `json:"log_retention_days,omitempty"` // Clear run logs after this many days
+	NotifyOn                  string            `json:"notify_on,omitempty"`
+	NotifyIncludeLogs         bool              `json:"notify_include_logs,omitempty"` // Attach the run's log tail to notifications
+	TemplateVars              bool              `json:"template_vars,omitempty"`       // Resolve run variables in command and env; off by default
+	DailyRuntimeBudgetSeconds *int              `json:"daily_runtime_budget_seconds,omitempty"`
+	DependsOn                 *uuid.UUID        `json:"depends_on,omitempty"` // Run after each successful run of this job
+	ImageDigest               *string           `json:"image_digest,omitempty"`
+	KeepFailedContainers      *bool             `json:"keep_failed_containers,omitempty"` // Overrides the server default
+	Stdin                     *string           `json:"stdin,omitempty"`
+	NetworkAccess             *bool             `json:"network_access,omitempty"` // Overrides BLOCK_NETWORK_BY_DEFAULT
+	RestartPolicy             *string           `json:"restart_policy,omitempty"` // Restart crashed containers in place; the timeout covers all attempts
+	LogDriver                 *string           `json:"log_driver,omitempty"`     // "json-file", "local", or "none" to keep no logs at all
+	GPUs                      *string           `json:"gpus,omitempty"`           // "all" or a count; needs a daemon with the NVIDIA runtime
+	DockerHost                *string           `json:"docker_host,omitempty"`    // Run on this DOCKER_HOSTS daemon instead of the default (not compose jobs)
+	DNS                       []string          `json:"dns,omitempty"`            // DNS server IPs, instead of the daemon's
+	ExtraHosts                []string          `json:"extra_hosts,omitempty"`    // Extra /etc/hosts entries as "host:ip"
+	CapAdd                    []string          `json:"cap_add,omitempty"`        // Capabilities to add; each must be in ALLOWED_CAPABILITIES
+	CapDrop                   []string          `json:"cap_drop,omitempty"`       // Capabilities to drop, or "ALL"
+	Tags                      []string          `json:"tags,omitempty"`           // For grouping; GET /jobs?tag= filters on them
 }
 
 // UpdateJobRequest is the payload for partially updating a job (PATCH).
 // Only non-nil fields are updated.
 type UpdateJobRequest struct {
-	Name           *string            `json:"name,omitempty"`
-	Image          *string            `json:"image,omitempty"`
-	Command        *[]string          `json:"command,omitempty"`
-	Env            *map[string]string `json:"env,omitempty"`
-	MemoryMB       *int               `json:"memory_mb,omitempty"`
-	CPUMillicores  *int               `json:"cpu_millicores,omitempty"`
-	TimeoutSeconds *int               `json:"timeout_seconds,omitempty"`
-	Schedule       *string            `json:"schedule,omitempty"`
-	IsActive       *bool              `json:"is_active,omitempty"`
-	Script         *string            `json:"script,omitempty"`
-	ScriptLang     *string            `json:"script_lang,omitempty"`
-	SourceType     *string            `json:"source_type,omitempty"`
-	GithubRepo     *string            `json:"github_repo,omitempty"`
-	GithubBranch   *string            `json:"github_branch,omitempty"`
-	DockerfilePath *string            `json:"dockerfile_path,omitempty"`
-	SourceConfig   *json.RawMessage   `json:"source_config,omitempty"`
+	Name                      *string            `json:"name,omitempty"`
+	Image                     *string            `json:"image,omitempty"`
+	Command                   *[]string          `json:"command,omitempty"`
+	Env                       *map[string]string `json:"env,omitempty"` // "***" keeps a sensitive key's stored value
+	SensitiveEnv              *[]string          `json:"sensitive_env,omitempty"`
+	MemoryMB                  *int               `json:"memory_mb,omitempty"`
+	CPUMillicores             *int               `json:"cpu_millicores,omitempty"`
+	Memory                    *string            `json:"memory,omitempty"`
+	CPU                       *string            `json:"cpu,omitempty"`
+	TimeoutSeconds            *int               `json:"timeout_seconds,omitempty"`
+	StartTimeoutSeconds       *int               `json:"start_timeout_seconds,omitempty"` // 0 removes it
+	MaxConcurrentRuns         *int               `json:"max_concurrent_runs,omitempty"`   // 0 removes it
+	Schedule                  *string            `json:"schedule,omitempty"`
+	IsActive                  *bool              `json:"is_active,omitempty"`
+	Script                    *string            `json:"script,omitempty"`
+	ScriptLang                *string            `json:"script_lang,omitempty"`
+	SourceType                *string            `json:"source_type,omitempty"`
+	GithubRepo                *string            `json:"github_repo,omitempty"`
+	GithubBranch              *string            `json:"github_branch,omitempty"`
+	DockerfilePath            *string            `json:"dockerfile_path,omitempty"`
+	SourceConfig              *json.RawMessage   `json:"source_config,omitempty"`
+	RetentionDays             *int               `json:"retention_days,omitempty"`
+	RetentionMaxRuns          *int               `json:"retention_max_runs,omitempty"`
+	LogRetentionDays          *int               `json:"log_retention_days,omitempty"` // 0 falls back to the global setting
+	NotifyOn                  *string            `json:"notify_on,omitempty"`
+	NotifyIncludeLogs         *bool              `json:"notify_include_logs,omitempty"`
+	TemplateVars              *bool              `json:"template_vars,omitempty"`
+	DailyRuntimeBudgetSeconds *int               `json:"daily_runtime_budget_seconds,omitempty"`
+	DependsOn                 *string            `json:"depends_on,omitempty"`   // Job ID, or "" to remove the dependency
+	ImageDigest               *string            `json:"image_digest,omitempty"` // "" removes the pin
+	KeepFailedContainers      *bool              `json:"keep_failed_containers,omitempty"`
+	Stdin                     *string            `json:"stdin,omitempty"` // "" removes it
+	NetworkAccess             *bool              `json:"network_access,omitempty"`
+	RestartPolicy             *string            `json:"restart_policy,omitempty"` // "" or "no" removes it
+	LogDriver                 *string            `json:"log_driver,omitempty"`     // "" removes it
+	GPUs                      *string            `json:"gpus,omitempty"`           // "" removes it
+	DockerHost                *string            `json:"docker_host,omitempty"`    // "" moves the job back to the default daemon
+	DNS                       *[]string          `json:"dns,omitempty"`            // [] removes them
+	ExtraHosts                *[]string          `json:"extra_hosts,omitempty"`    // [] removes them
+	CapAdd                    *[]string          `json:"cap_add,omitempty"`        // [] removes them
+	CapDrop                   *[]string          `json:"cap_drop,omitempty"`       // [] removes them
+	Tags                      *[]string          `json:"tags,omitempty"`           // Replaces the job's tags; [] removes them
+}
+
+// CloneJobRequest is the optional payload for cloning a job.
+type CloneJobRequest struct {
+	Name string `json:"name,omitempty"` // Defaults to "<name>-copy"
+}
+
+// JobUsage reports how much of a job's daily runtime budget has been consumed.
+type JobUsage struct {
+	JobID                     uuid.UUID `json:"job_id"`
+	DailyRuntimeBudgetSeconds *int      `json:"daily_runtime_budget_seconds,omitempty"`
+	RuntimeUsedTodaySeconds   int64     `json:"runtime_used_today_seconds"`
+	RuntimeRemainingSeconds   *int64    `json:"runtime_remaining_seconds,omitempty"`
+}
+
+// JobStats summarizes a job's runs created within a trailing window.
+type JobStats struct {
+	JobID         uuid.UUID `json:"job_id"`
+	Window        string    `json:"window"`
+	Since         time.Time `json:"since"`
+	TotalRuns     int       `json:"total_runs"`
+	Succeeded     int       `json:"succeeded"`
+	Failed        int       `json:"failed"`
+	Cancelled     int       `json:"cancelled"`
+	SuccessRate   *float64  `json:"success_rate,omitempty"`    // Succeeded / (succeeded + failed); omitted when neither
+	P50DurationMs *int64    `json:"p50_duration_ms,omitempty"` // Over runs with a recorded duration
+	P95DurationMs *int64    `json:"p95_duration_ms,omitempty"`
+	AvgDurationMs *int64    `json:"avg_duration_ms,omitempty"`
+	AvgWaitMs     *int64    `json:"avg_wait_ms,omitempty"` // Mean time from queued to started, over runs that started
+}
+
+// Quota reports a user's usage against each of their quotas.
+type Quota struct {
+	Jobs          QuotaUsage `json:"jobs"`
+	ScheduledJobs QuotaUsage `json:"scheduled_jobs"`
+}
+
+// QuotaUsage is the current count for one quota and its limit.
+type QuotaUsage struct {
+	Used  int  `json:"used"`
+	Limit *int `json:"limit,omitempty"` // Omitted when unlimited
+}
+
+// SchedulePreview lists the next times a job's schedule fires.
+type SchedulePreview struct {
+	Schedule string      `json:"schedule"`
+	Next     []time.Time `json:"next"`
 }
 
 // TriggerRunRequest is the optional payload for triggering a run with overrides.
 type TriggerRunRequest struct {
 	TimeoutSeconds *int              `json:"timeout_seconds,omitempty"`
-	Env            map[string]string `json:"env,omitempty"`
+	Env            map[string]string `json:"env,omitempty"` // Layered over the job's env for this run
 	Command        *[]string         `json:"command,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Stdin          *string           `json:"stdin,omitempty"`          // Replaces the job's stdin for this run
+	MemoryMB       *int              `json:"memory_mb,omitempty"`      // Replaces the job's memory limit for this run
+	CPUMillicores  *int              `json:"cpu_millicores,omitempty"` // Replaces the job's CPU limit for this run
+	Memory         *string           `json:"memory,omitempty"`         // e.g. "2Gi"; alternative to memory_mb
+	CPU            *string           `json:"cpu,omitempty"`            // e.g. "2" or "1500m"; alternative to cpu_millicores
+}
+
+// UpdateRunPriorityRequest is the payload for re-prioritizing a queued run.
+type UpdateRunPriorityRequest struct {
+	Priority *int `json:"priority"` // Higher is picked first; the default is 0
+}
+
+// PauseRunRequest is the optional payload for pausing a run.
+type PauseRunRequest struct {
+	TimeoutSeconds *int    `json:"timeout_seconds,omitempty"` // Pause length; defaults to (and is capped at) MAX_PAUSE_DURATION
+	OnExpiry       *string `json:"on_expiry,omitempty"`       // "resume" or "kill"; defaults to PAUSE_EXPIRY_ACTION
+}
+
+// KillRunRequest is the optional payload for killing a run.
+type KillRunRequest struct {
+	TimeoutSeconds *int `json:"timeout_seconds,omitempty"` // Grace period before SIGKILL
+}
+
+// KillJobRunsResponse summarizes a batch kill of a job's runs.
+type KillJobRunsResponse struct {
+	Killed int         `json:"killed"`
+	RunIDs []uuid.UUID `json:"run_ids"`
 }
 
 // RegisterRequest is the payload for user registration.
@@ -159,10 +401,50 @@ type APIKeyResponse struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// LoginResponse is the user plus a JWT access token and its refresh token.
+type LoginResponse struct {
+	User
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"` // When AccessToken expires
+}
+
+// RefreshRequest exchanges a refresh token for a new token pair.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// WorkerStatus is returned by the admin worker endpoints.
+type WorkerStatus struct {
+	Draining    bool `json:"draining"`    // No new runs are being claimed
+	Maintenance bool `json:"maintenance"` // No new runs are being created
+	ActiveRuns  int  `json:"active_runs"` // Runs still executing
+}
+
+// ListEnvelope wraps a list response when the client asks for ?envelope=true.
+type ListEnvelope struct {
+	Data       any        `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// Pagination describes where a page of a list sits in the whole.
+type Pagination struct {
+	Total      int     `json:"total"`       // Items matching the filters, across all pages
+	NextCursor *string `json:"next_cursor"` // Pass as ?before= for the next page; null on the last page
+}
+
 // ErrorResponse is the standard error format.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+	Error     string       `json:"error"`
+	Message   string       `json:"message,omitempty"`
+	Details   []FieldError `json:"details,omitempty"`    // Every problem found, for validation errors
+	RequestID string       `json:"request_id,omitempty"` // Filled in by writeJSON
+}
+
+// FieldError is one problem with a field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // GithubToken represents a stored GitHub OAuth token.
@@ -187,3 +469,20 @@ type BuildQueueItem struct {
 	StartedAt    *time.Time `json:"started_at,omitempty"`
 	FinishedAt   *time.Time `json:"finished_at,omitempty"`
 }
+
+// NotificationChannel is a destination notified when a job's runs finish.
+type NotificationChannel struct {
+	ID        uuid.UUID `json:"id"`
+	JobID     uuid.UUID `json:"job_id"`
+	Type      string    `json:"type"`   // webhook, slack, email
+	Target    string    `json:"target"` // URL or email address
+	Events    string    `json:"events"` // all, success, failure
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateNotificationChannelRequest is the payload for adding a notification channel.
+type CreateNotificationChannelRequest struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	Events string `json:"events,omitempty"`
+}
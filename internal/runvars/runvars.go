@@ -0,0 +1,104 @@
+// Package runvars resolves the run variables a job's command and env may
+// reference with Go template syntax, resolved when each run starts:
+//
+//	{{.RunID}}       the run's ID
+//	{{.JobID}}       the job's ID
+//	{{.JobName}}     the job's name
+//	{{.Date}}        the run's start date in UTC, e.g. 2026-03-01
+//	{{.Timestamp}}   the run's start time in UTC, RFC 3339
+//	{{.Labels.key}}  a label given when the run was triggered ("" if unset)
+//
+// This lets one job definition produce differently parameterized runs, e.g.
+// a command of ["report", "--day", "{{.Labels.day}}"]. Jobs opt in with
+// template_vars, so commands that contain "{{" for another tool (docker
+// inspect --format, Helm values) are passed through untouched by default.
+package runvars
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Vars are the values a template can reference.
+type Vars struct {
+	RunID     string
+	JobID     string
+	JobName   string
+	Date      string
+	Timestamp string
+	Labels    map[string]string
+}
+
+// New returns the variables for a run of the job started at startedAt.
+func New(jobID uuid.UUID, jobName string, runID uuid.UUID, startedAt time.Time, labels map[string]string) Vars {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	startedAt = startedAt.UTC()
+	return Vars{
+		RunID:     runID.String(),
+		JobID:     jobID.String(),
+		JobName:   jobName,
+		Date:      startedAt.Format(time.DateOnly),
+		Timestamp: startedAt.Format(time.RFC3339),
+		Labels:    labels,
+	}
+}
+
+// Expand resolves the variables in s. Strings without "{{" are returned as
+// is and never parsed.
+func Expand(s string, vars Vars) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	tmpl, err := template.New("").Option("missingkey=zero").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ExpandAll resolves the variables in each command arg and env value,
+// returning new slices and maps; the inputs are left alone.
+func ExpandAll(command []string, env map[string]string, vars Vars) ([]string, map[string]string, error) {
+	var outCommand []string
+	if len(command) > 0 {
+		outCommand = make([]string, len(command))
+		for i, arg := range command {
+			v, err := Expand(arg, vars)
+			if err != nil {
+				return nil, nil, fmt.Errorf("command[%d]: %w", i, err)
+			}
+			outCommand[i] = v
+		}
+	}
+	var outEnv map[string]string
+	if len(env) > 0 {
+		outEnv = make(map[string]string, len(env))
+		for k, val := range env {
+			v, err := Expand(val, vars)
+			if err != nil {
+				return nil, nil, fmt.Errorf("env %s: %w", k, err)
+			}
+			outEnv[k] = v
+		}
+	}
+	return outCommand, outEnv, nil
+}
+
+// Check reports whether s is a valid template, by expanding it against
+// sample variables: syntax errors and references to unknown variables (e.g.
+// {{.RunId}}) fail, so they are caught when the job is saved rather than
+// when it runs.
+func Check(s string) error {
+	_, err := Expand(s, New(uuid.Nil, "job", uuid.Nil, time.Time{}, nil))
+	return err
+}
@@ -0,0 +1,81 @@
+package runvars
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testVars() Vars {
+	runID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	jobID := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	startedAt := time.Date(2026, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+	return New(jobID, "nightly", runID, startedAt, map[string]string{"day": "monday"})
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"{{.RunID}}", "11111111-1111-1111-1111-111111111111"},
+		{"{{.JobID}}", "22222222-2222-2222-2222-222222222222"},
+		{"job={{.JobName}}", "job=nightly"},
+		{"{{.Date}}", "2026-03-01"},
+		{"{{.Timestamp}}", "2026-03-01T11:30:00Z"},
+		{"--day={{.Labels.day}}", "--day=monday"},
+		{"--region={{.Labels.region}}", "--region="},
+	}
+	for _, tt := range tests {
+		got, err := Expand(tt.in, testVars())
+		if err != nil {
+			t.Errorf("Expand(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr bool
+	}{
+		{"no template", false},
+		{"{{.RunID}}-{{.Labels.anything}}", false},
+		{"{{.RunId}}", true},
+		{"{{.RunID", true},
+		{"{{if}}", true},
+	}
+	for _, tt := range tests {
+		err := Check(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Check(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExpandAll(t *testing.T) {
+	command := []string{"report", "--day", "{{.Labels.day}}"}
+	env := map[string]string{"RUN": "{{.RunID}}", "PLAIN": "x"}
+	gotCommand, gotEnv, err := ExpandAll(command, env, testVars())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotCommand[2] != "monday" {
+		t.Errorf("command[2] = %q, want monday", gotCommand[2])
+	}
+	if gotEnv["RUN"] != "11111111-1111-1111-1111-111111111111" || gotEnv["PLAIN"] != "x" {
+		t.Errorf("env = %v", gotEnv)
+	}
+	if command[2] != "{{.Labels.day}}" || env["RUN"] != "{{.RunID}}" {
+		t.Error("ExpandAll modified its inputs")
+	}
+
+	if _, _, err := ExpandAll([]string{"{{.Nope}}"}, nil, testVars()); err == nil {
+		t.Error("ExpandAll accepted an unknown variable")
+	}
+}
@@ -0,0 +1,14 @@
+// Package schedule parses job cron schedules. The scheduler and the API share
+// it, so a schedule the API accepts is one the scheduler runs.
+package schedule
+
+import "github.com/robfig/cron/v3"
+
+// parser accepts standard five-field cron expressions. A "CRON_TZ=<zone> "
+// prefix evaluates the expression in that time zone.
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// Parse parses a job's cron schedule.
+func Parse(schedule string) (cron.Schedule, error) {
+	return parser.Parse(schedule)
+}
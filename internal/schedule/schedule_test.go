@@ -0,0 +1,35 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	from := time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		schedule string
+		wantNext time.Time
+	}{
+		{"0 * * * *", time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 1, 10, 45, 0, 0, time.UTC)},
+		{"CRON_TZ=Europe/Berlin 0 12 * * *", time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			sched, err := Parse(tt.schedule)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := sched.Next(from); !got.Equal(tt.wantNext) {
+				t.Errorf("Next(%s) = %s, want %s", from, got.UTC(), tt.wantNext)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "* * * *", "0 0 * * * *", "@every", "61 * * * *", "CRON_TZ=Nowhere/Land 0 * * * *"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", bad)
+		}
+	}
+}
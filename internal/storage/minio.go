@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -18,6 +19,9 @@ type ObjectInfo struct {
 	LastModified string
 }
 
+// ErrNotFound is returned by Download when no object exists at the key.
+var ErrNotFound = errors.New("object not found")
+
 // Client wraps the MinIO client for object storage operations.
 type Client struct {
 	minio  *minio.Client
@@ -71,12 +75,21 @@ func (c *Client) Upload(ctx context.Context, key string, reader io.Reader, size
 	return nil
 }
 
-// Download retrieves an object from MinIO.
+// Download retrieves an object from MinIO. GetObject doesn't contact the
+// server until the first read, so the object is stat'ed first to report a
+// missing key (as ErrNotFound) here rather than as a failed read later.
 func (c *Client) Download(ctx context.Context, key string) (io.ReadCloser, error) {
 	obj, err := c.minio.GetObject(ctx, c.bucket, key, minio.GetObjectOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("download %s: %w", key, err)
 	}
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, fmt.Errorf("download %s: %w", key, ErrNotFound)
+		}
+		return nil, fmt.Errorf("download %s: %w", key, err)
+	}
 	return obj, nil
 }
 
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/orbex-dev/orbex/internal/database"
+)
+
+// enqueueDependents queues a run of every active job that depends on jobID.
+// It is called after a run of jobID succeeds; the new runs are labelled with
+// the parent run so the chain can be traced.
+func (w *Worker) enqueueDependents(ctx context.Context, jobID, parentRunID uuid.UUID) {
+	if w.inMaintenance(ctx, "worker") {
+		log.Printf("[worker] Maintenance mode: not enqueuing dependents of job %s (after run %s)", jobID, parentRunID)
+		return
+	}
+	rows, err := w.db.Pool.Query(ctx, `
+		SELECT id, user_id FROM jobs WHERE depends_on = $1 AND is_active = true
+	`, jobID)
+	if err != nil {
+		log.Printf("[worker] ERROR loading dependents of job %s: %v", jobID, err)
+		return
+	}
+	type dependent struct{ id, userID uuid.UUID }
+	var deps []dependent
+	for rows.Next() {
+		var d dependent
+		if err := rows.Scan(&d.id, &d.userID); err != nil {
+			continue
+		}
+		deps = append(deps, d)
+	}
+	rows.Close()
+
+	labels := map[string]string{"source": "dependency", "parent_run": parentRunID.String()}
+	for _, d := range deps {
+		var runID uuid.UUID
+		err := w.db.Pool.QueryRow(ctx, `
+			WITH run AS (
+				INSERT INTO job_runs (job_id, user_id, status, labels, trigger_source)
+				VALUES ($1, $2, 'pending'::run_status, $3, 'dependency'::trigger_source)
+				RETURNING id, job_id
+			)
+			INSERT INTO job_queue (job_id, run_id)
+			SELECT job_id, id FROM run
+			RETURNING run_id
+		`, d.id, d.userID, labels).Scan(&runID)
+		if err != nil {
+			log.Printf("[worker] ERROR enqueuing dependent job %s: %v", d.id, err)
+			continue
+		}
+		log.Printf("[worker] Enqueued run %s of job %s (after run %s)", runID, d.id, parentRunID)
+	}
+
+	if len(deps) > 0 {
+		if err := database.NotifyQueue(ctx, w.db.Pool); err != nil {
+			log.Printf("[worker] Warning: failed to notify workers: %v", err)
+		}
+	}
+}
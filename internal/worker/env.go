@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/orbex-dev/orbex/internal/models"
+	"github.com/orbex-dev/orbex/internal/runvars"
+)
+
+// Every run's container gets these variables so jobs can identify
+// themselves, unless the job's env (or DEFAULT_RUN_ENV) sets them:
+//
+//	ORBEX_RUN_ID          the run's ID
+//	ORBEX_JOB_ID          the job's ID
+//	ORBEX_JOB_NAME        the job's name
+//	ORBEX_RUN_STARTED_AT  the run's start time in UTC, RFC 3339
+//	ORBEX_REQUEST_ID      the API request that triggered the run, for tracing
+//	                      (unset for scheduled and dependency runs)
+const (
+	envRunID        = "ORBEX_RUN_ID"
+	envJobID        = "ORBEX_JOB_ID"
+	envJobName      = "ORBEX_JOB_NAME"
+	envRunStartedAt = "ORBEX_RUN_STARTED_AT"
+	envRequestID    = "ORBEX_REQUEST_ID"
+)
+
+// resolveRunVars expands run variables in the job's command and env if the
+// job opted in with template_vars, then fills in DEFAULT_RUN_ENV under the
+// env. The defaults are always expanded: they come from the operator, who
+// can't have meant "{{" for anything else.
+func resolveRunVars(job *models.Job, vars runvars.Vars, defaults map[string]string) error {
+	if job.TemplateVars {
+		command, env, err := runvars.ExpandAll(job.Command, job.Env, vars)
+		if err != nil {
+			return err
+		}
+		job.Command, job.Env = command, env
+	}
+	_, defaults, err := runvars.ExpandAll(nil, defaults, vars)
+	if err != nil {
+		return fmt.Errorf("DEFAULT_RUN_ENV: %w", err)
+	}
+	job.Env = withDefaultEnv(job.Env, defaults)
+	return nil
+}
+
+// withDefaultEnv returns the job's env with the operator's DEFAULT_RUN_ENV
+// filled in under it: keys the job sets itself keep the job's value.
+func withDefaultEnv(env, defaults map[string]string) map[string]string {
+	merged := make(map[string]string, len(env)+len(defaults)+5)
+	maps.Copy(merged, defaults)
+	maps.Copy(merged, env)
+	return merged
+}
+
+// addRunIdentityEnv sets the ORBEX_* identity variables in env, leaving any
+// the job or operator already set. It runs after template expansion, so the
+// values are used verbatim.
+func addRunIdentityEnv(env map[string]string, vars runvars.Vars, requestID string) {
+	identity := map[string]string{
+		envRunID:        vars.RunID,
+		envJobID:        vars.JobID,
+		envJobName:      vars.JobName,
+		envRunStartedAt: vars.Timestamp,
+	}
+	if requestID != "" {
+		identity[envRequestID] = requestID
+	}
+	for k, v := range identity {
+		if _, set := env[k]; !set {
+			env[k] = v
+		}
+	}
+}
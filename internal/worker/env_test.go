@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/orbex-dev/orbex/internal/models"
+	"github.com/orbex-dev/orbex/internal/runvars"
+)
+
+func TestResolveRunVars(t *testing.T) {
+	runID := uuid.New()
+	vars := runvars.New(uuid.New(), "job", runID, time.Now(), nil)
+	defaults := map[string]string{"TRACE": "{{.RunID}}", "FORMAT": "kept"}
+
+	t.Run("not opted in", func(t *testing.T) {
+		job := models.Job{
+			Command: []string{"docker", "inspect", "--format", "{{.State.Status}}"},
+			Env:     map[string]string{"FORMAT": "{{json .}}"},
+		}
+		if err := resolveRunVars(&job, vars, defaults); err != nil {
+			t.Fatal(err)
+		}
+		if job.Command[3] != "{{.State.Status}}" {
+			t.Errorf("command[3] = %q, want it untouched", job.Command[3])
+		}
+		if job.Env["FORMAT"] != "{{json .}}" {
+			t.Errorf("env FORMAT = %q, want the job's value untouched", job.Env["FORMAT"])
+		}
+		if job.Env["TRACE"] != runID.String() {
+			t.Errorf("env TRACE = %q, want DEFAULT_RUN_ENV expanded", job.Env["TRACE"])
+		}
+	})
+
+	t.Run("opted in", func(t *testing.T) {
+		job := models.Job{
+			TemplateVars: true,
+			Command:      []string{"echo", "{{.RunID}}"},
+			Env:          map[string]string{"RUN": "{{.RunID}}"},
+		}
+		if err := resolveRunVars(&job, vars, defaults); err != nil {
+			t.Fatal(err)
+		}
+		if job.Command[1] != runID.String() || job.Env["RUN"] != runID.String() {
+			t.Errorf("command = %v, env = %v; want run ID expanded", job.Command, job.Env)
+		}
+		if job.Env["FORMAT"] != "kept" {
+			t.Errorf("env FORMAT = %q, want the default", job.Env["FORMAT"])
+		}
+	})
+
+	t.Run("opted in, invalid template", func(t *testing.T) {
+		job := models.Job{TemplateVars: true, Command: []string{"{{.Nope}}"}}
+		if err := resolveRunVars(&job, vars, nil); err == nil {
+			t.Error("resolveRunVars accepted an unknown variable")
+		}
+	})
+}
@@ -2,22 +2,22 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/orbex-dev/orbex/internal/database"
+	"github.com/orbex-dev/orbex/internal/docker"
 )
 
-const (
-	heartbeatInterval = 10 * time.Second
-	reaperInterval    = 30 * time.Second
-	staleThreshold    = 60 * time.Second
-	maxPauseDuration  = 24 * time.Hour // Auto-kill paused containers after this duration
-)
+// maxStaleGrace bounds how long a run with a live container but no
+// heartbeat is spared when no worker in this process owns it.
+const maxStaleGrace = 5 * time.Minute
 
 // emitHeartbeat updates heartbeat_at for a running job until ctx is cancelled.
 func (w *Worker) emitHeartbeat(ctx context.Context, runID uuid.UUID) {
-	ticker := time.NewTicker(heartbeatInterval)
+	ticker := time.NewTicker(w.cfg.HeartbeatInterval)
 	defer ticker.Stop()
 
 	for {
@@ -38,9 +38,9 @@ func (w *Worker) emitHeartbeat(ctx context.Context, runID uuid.UUID) {
 
 // RunReaper starts the stale run reaper loop. Blocks until ctx is cancelled.
 func (w *Worker) RunReaper(ctx context.Context) {
-	log.Printf("[reaper] Started (interval=%s, staleThreshold=%s, maxPauseDuration=%s)", reaperInterval, staleThreshold, maxPauseDuration)
+	log.Printf("[reaper] Started (interval=%s, staleThreshold=%s, maxPauseDuration=%s, pauseExpiry=%s)", w.cfg.ReaperInterval, w.cfg.StaleThreshold, w.cfg.MaxPauseDuration, w.cfg.PauseExpiryAction)
 
-	ticker := time.NewTicker(reaperInterval)
+	ticker := time.NewTicker(w.cfg.ReaperInterval)
 	defer ticker.Stop()
 
 	for {
@@ -51,6 +51,8 @@ func (w *Worker) RunReaper(ctx context.Context) {
 		case <-ticker.C:
 			w.reapStaleRuns(ctx)
 			w.reapPausedContainers(ctx)
+			w.releaseStuckQueueItems(ctx)
+			w.expireUnstartedRuns(ctx)
 		}
 	}
 }
@@ -59,52 +61,92 @@ func (w *Worker) RunReaper(ctx context.Context) {
 type staleRun struct {
 	ID          uuid.UUID
 	ContainerID *string
+	DockerHost  *string
+	Version     int
+}
+
+// container returns the run's container ID and a client for the daemon it
+// was placed on. ok is false if the run has no container or its host is no
+// longer configured.
+func (w *Worker) container(sr staleRun) (dc *docker.Client, containerID string, ok bool) {
+	if sr.ContainerID == nil || *sr.ContainerID == "" {
+		return nil, "", false
+	}
+	dc, ok = w.hosts.Get(deref(sr.DockerHost))
+	return dc, *sr.ContainerID, ok
 }
 
 // reapStaleRuns finds runs with expired heartbeats and marks them as failed.
 func (w *Worker) reapStaleRuns(ctx context.Context) {
 	rows, err := w.db.Pool.Query(ctx, `
-		SELECT id, container_id FROM job_runs
+		SELECT id, container_id, docker_host, version, heartbeat_at FROM job_runs
 		WHERE status IN ('running'::run_status, 'paused'::run_status)
 		  AND heartbeat_at IS NOT NULL
 		  AND heartbeat_at < now() - $1::interval
-	`, staleThreshold.String())
+	`, w.cfg.StaleThreshold.String())
 	if err != nil {
 		return
 	}
 	defer rows.Close()
 
 	var stale []staleRun
+	var heartbeats []time.Time
 	for rows.Next() {
 		var sr staleRun
-		if err := rows.Scan(&sr.ID, &sr.ContainerID); err != nil {
+		var heartbeatAt time.Time
+		if err := rows.Scan(&sr.ID, &sr.ContainerID, &sr.DockerHost, &sr.Version, &heartbeatAt); err != nil {
 			continue
 		}
 		stale = append(stale, sr)
+		heartbeats = append(heartbeats, heartbeatAt)
 	}
 
-	for _, sr := range stale {
-		log.Printf("[reaper] Reaping stale run %s (heartbeat expired)", sr.ID)
-
-		// Force kill the container if it still exists
-		if sr.ContainerID != nil && *sr.ContainerID != "" {
-			if err := w.docker.StopContainer(ctx, *sr.ContainerID, 10); err != nil {
-				log.Printf("[reaper] Warning: failed to stop container for %s: %v", sr.ID, err)
-			}
-			_ = w.docker.RemoveContainer(ctx, *sr.ContainerID)
+	for i, sr := range stale {
+		_, owned := w.inFlight.Load(sr.ID)
+		running, err := w.containerRunning(ctx, sr)
+		if err != nil {
+			// Unknown isn't dead: treat it as running, so a Docker blip
+			// doesn't reap a live run but a daemon that stays away does
+			// once the grace runs out
+			log.Printf("[reaper] Warning: couldn't inspect the container of stale run %s: %v", sr.ID, err)
+			running = true
 		}
+		// heartbeat_at is left as it was, so the grace is measured from the
+		// last real heartbeat and runs out
+		if !shouldReap(running, owned, time.Since(heartbeats[i])) {
+			log.Printf("[reaper] Run %s missed its heartbeat but its container may still be running — sparing it for now", sr.ID)
+			continue
+		}
+
+		log.Printf("[reaper] Reaping stale run %s (heartbeat expired)", sr.ID)
 
-		// Mark as failed
-		_, err := w.db.Pool.Exec(ctx, `
+		// Mark as failed first: if the worker finished the run since it was
+		// read, the version check fails and its result is left alone.
+		err = database.CheckVersion(w.db.Pool.Exec(ctx, `
 			UPDATE job_runs SET 
 				status = 'failed'::run_status, 
 				error_message = 'heartbeat timeout: worker may have crashed',
+				failure_reason = 'worker_crash',
 				finished_at = now(),
-				heartbeat_at = NULL
-			WHERE id = $1
-		`, sr.ID)
+				heartbeat_at = NULL,
+				version = version + 1
+			WHERE id = $1 AND version = $2
+		`, sr.ID, sr.Version))
+		if errors.Is(err, database.ErrVersionConflict) {
+			log.Printf("[reaper] Stale run %s was finished elsewhere (conflict) — skipping", sr.ID)
+			continue
+		}
 		if err != nil {
 			log.Printf("[reaper] ERROR marking stale run %s as failed: %v", sr.ID, err)
+			continue
+		}
+
+		// Force kill the container if it still exists
+		if dc, containerID, ok := w.container(sr); ok {
+			if err := dc.StopContainer(ctx, containerID, 10); err != nil {
+				log.Printf("[reaper] Warning: failed to stop container for %s: %v", sr.ID, err)
+			}
+			_ = dc.RemoveContainer(ctx, containerID)
 		}
 
 		// Cleanup queue
@@ -114,50 +156,98 @@ func (w *Worker) reapStaleRuns(ctx context.Context) {
 	}
 }
 
-// reapPausedContainers kills paused containers that have exceeded the max pause duration.
+// shouldReap decides whether a run whose heartbeat expired heartbeatAge ago
+// is really dead. A missed heartbeat alone can be a slow worker (a long GC
+// pause, an overloaded database), so a run whose container is still running
+// is spared while this process is executing it, or — for runs owned by
+// another worker — until maxStaleGrace has passed.
+func shouldReap(containerRunning, ownedHere bool, heartbeatAge time.Duration) bool {
+	if !containerRunning {
+		return true
+	}
+	if ownedHere {
+		return false
+	}
+	return heartbeatAge >= maxStaleGrace
+}
+
+// containerRunning reports whether a run's container exists and is running
+// (a paused container counts). A container that is gone is not running; any
+// other inspect failure is returned, as the state is then unknown.
+func (w *Worker) containerRunning(ctx context.Context, sr staleRun) (bool, error) {
+	dc, containerID, ok := w.container(sr)
+	if !ok {
+		return false, nil
+	}
+	details, err := dc.InspectDetails(ctx, containerID)
+	if errors.Is(err, docker.ErrContainerNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return details.Running, nil
+}
+
+// reapPausedContainers ends pauses that have run out, resuming or killing
+// each run as recorded when it was paused. Runs paused before expiries were
+// recorded get MaxPauseDuration and PauseExpiryAction.
 func (w *Worker) reapPausedContainers(ctx context.Context) {
 	rows, err := w.db.Pool.Query(ctx, `
-		SELECT id, container_id FROM job_runs
+		SELECT id, container_id, docker_host, version, COALESCE(pause_expiry_action, $2) FROM job_runs
 		WHERE status = 'paused'::run_status
-		  AND paused_at IS NOT NULL
-		  AND paused_at < now() - $1::interval
-	`, maxPauseDuration.String())
+		  AND (pause_expires_at < now()
+		       OR (pause_expires_at IS NULL AND paused_at < now() - $1::interval))
+	`, w.cfg.MaxPauseDuration.String(), w.cfg.PauseExpiryAction)
 	if err != nil {
 		return
 	}
 	defer rows.Close()
 
 	var paused []staleRun
+	var actions []string
 	for rows.Next() {
 		var sr staleRun
-		if err := rows.Scan(&sr.ID, &sr.ContainerID); err != nil {
+		var action string
+		if err := rows.Scan(&sr.ID, &sr.ContainerID, &sr.DockerHost, &sr.Version, &action); err != nil {
 			continue
 		}
 		paused = append(paused, sr)
+		actions = append(actions, action)
 	}
 
-	for _, sr := range paused {
-		log.Printf("[reaper] Auto-killing paused run %s (exceeded %s pause limit)", sr.ID, maxPauseDuration)
-
-		// Kill the container
-		if sr.ContainerID != nil && *sr.ContainerID != "" {
-			if err := w.docker.StopContainer(ctx, *sr.ContainerID, 10); err != nil {
-				log.Printf("[reaper] Warning: failed to stop paused container for %s: %v", sr.ID, err)
-			}
-			_ = w.docker.RemoveContainer(ctx, *sr.ContainerID)
+	for i, sr := range paused {
+		if actions[i] == "resume" && w.resumeExpiredPause(ctx, sr) {
+			continue
 		}
+		log.Printf("[reaper] Auto-killing paused run %s (pause expired)", sr.ID)
 
-		// Mark as cancelled
-		_, err := w.db.Pool.Exec(ctx, `
+		// Mark as cancelled before killing, as for stale runs
+		err := database.CheckVersion(w.db.Pool.Exec(ctx, `
 			UPDATE job_runs SET
 				status = 'cancelled'::run_status,
-				error_message = 'auto-killed: exceeded maximum pause duration (24h)',
+				error_message = 'auto-killed: paused past its pause timeout',
+				failure_reason = 'pause_limit',
 				finished_at = now(),
-				heartbeat_at = NULL
-			WHERE id = $1
-		`, sr.ID)
+				heartbeat_at = NULL,
+				version = version + 1
+			WHERE id = $1 AND version = $2
+		`, sr.ID, sr.Version))
+		if errors.Is(err, database.ErrVersionConflict) {
+			log.Printf("[reaper] Paused run %s was finished elsewhere (conflict) — skipping", sr.ID)
+			continue
+		}
 		if err != nil {
 			log.Printf("[reaper] ERROR marking paused run %s as cancelled: %v", sr.ID, err)
+			continue
+		}
+
+		// Kill the container
+		if dc, containerID, ok := w.container(sr); ok {
+			if err := dc.StopContainer(ctx, containerID, 10); err != nil {
+				log.Printf("[reaper] Warning: failed to stop paused container for %s: %v", sr.ID, err)
+			}
+			_ = dc.RemoveContainer(ctx, containerID)
 		}
 
 		// Cleanup queue
@@ -166,3 +256,41 @@ func (w *Worker) reapPausedContainers(ctx context.Context) {
 		log.Printf("[reaper] Reaped paused run %s", sr.ID)
 	}
 }
+
+// resumeExpiredPause unpauses a run whose pause ran out. It reports false if
+// the container couldn't be unpaused, in which case the run is killed instead.
+func (w *Worker) resumeExpiredPause(ctx context.Context, sr staleRun) bool {
+	dc, containerID, ok := w.container(sr)
+	if !ok {
+		return false
+	}
+	if err := dc.UnpauseContainer(ctx, containerID); err != nil {
+		log.Printf("[reaper] Failed to auto-resume paused run %s, killing it instead: %v", sr.ID, err)
+		return false
+	}
+	resumed, err := w.markAutoResumed(ctx, sr.ID)
+	switch {
+	case err != nil:
+		log.Printf("[reaper] ERROR marking auto-resumed run %s as running: %v", sr.ID, err)
+	case !resumed:
+		log.Printf("[reaper] Paused run %s was resumed or finished elsewhere — skipping", sr.ID)
+	default:
+		log.Printf("[reaper] Auto-resumed paused run %s (pause expired)", sr.ID)
+	}
+	return true
+}
+
+// markAutoResumed flips a paused run back to running, reporting false if it
+// is no longer paused. Like a manual resume it leaves the version alone: the
+// worker executing the run still holds that version for its final update.
+func (w *Worker) markAutoResumed(ctx context.Context, runID uuid.UUID) (bool, error) {
+	tag, err := w.db.Pool.Exec(ctx, `
+		UPDATE job_runs SET status = 'running'::run_status, paused_at = NULL,
+			pause_expires_at = NULL, pause_expiry_action = NULL
+		WHERE id = $1 AND status = 'paused'::run_status
+	`, runID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
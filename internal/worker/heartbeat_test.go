@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/orbex-dev/orbex/internal/database"
+)
+
+// testDB connects to ORBEX_TEST_DATABASE_URL and applies the migrations,
+// skipping the test when no database is configured.
+func testDB(t *testing.T) *database.DB {
+	t.Helper()
+	url := os.Getenv("ORBEX_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("ORBEX_TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	db, err := database.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	if err := db.Migrate(ctx, filepath.Join("..", "database", "migrations")); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	return db
+}
+
+// createRun inserts a throwaway user, job and run, removed again (by cascade)
+// when the test ends.
+func createRun(t *testing.T, db *database.DB) (jobID, runID uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	var userID uuid.UUID
+	if err := db.Pool.QueryRow(ctx, `
+		INSERT INTO users (email, password) VALUES ($1, 'x') RETURNING id
+	`, uuid.NewString()+"@example.com").Scan(&userID); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Pool.Exec(context.Background(), "DELETE FROM users WHERE id = $1", userID)
+	})
+	if err := db.Pool.QueryRow(ctx, `
+		INSERT INTO jobs (user_id, name, image) VALUES ($1, 'test', 'alpine') RETURNING id
+	`, userID).Scan(&jobID); err != nil {
+		t.Fatalf("creating job: %v", err)
+	}
+	if err := db.Pool.QueryRow(ctx, `
+		INSERT INTO job_runs (job_id, user_id) VALUES ($1, $2) RETURNING id
+	`, jobID, userID).Scan(&runID); err != nil {
+		t.Fatalf("creating run: %v", err)
+	}
+	return jobID, runID
+}
+
+// TestAutoResumedRunCompletes pauses a running run, lets the pause expire and
+// auto-resumes it, then checks the executing worker's final, version-guarded
+// update still lands.
+func TestAutoResumedRunCompletes(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	_, runID := createRun(t, db)
+	w := &Worker{db: db}
+
+	// Mark running as executeRun does, keeping the version it returns
+	var version int
+	if err := db.Pool.QueryRow(ctx, `
+		UPDATE job_runs SET status = 'running'::run_status, started_at = now(), heartbeat_at = now(),
+			attempt = attempt + 1, version = version + 1
+		WHERE id = $1
+		RETURNING version
+	`, runID).Scan(&version); err != nil {
+		t.Fatalf("marking run running: %v", err)
+	}
+
+	// Pause it with a pause that has already run out
+	past := time.Now().Add(-time.Minute)
+	if _, err := db.Pool.Exec(ctx, `
+		UPDATE job_runs SET status = 'paused'::run_status, paused_at = $1,
+			pause_expires_at = $1, pause_expiry_action = 'resume'
+		WHERE id = $2 AND status = 'running'::run_status
+	`, past, runID); err != nil {
+		t.Fatalf("pausing run: %v", err)
+	}
+
+	resumed, err := w.markAutoResumed(ctx, runID)
+	if err != nil || !resumed {
+		t.Fatalf("markAutoResumed = %v, %v; want true, nil", resumed, err)
+	}
+	if resumed, err := w.markAutoResumed(ctx, runID); err != nil || resumed {
+		t.Fatalf("second markAutoResumed = %v, %v; want false, nil", resumed, err)
+	}
+
+	// The worker finishes the run with the version it got when starting it
+	err = database.CheckVersion(db.Pool.Exec(ctx, `
+		UPDATE job_runs SET status = 'succeeded'::run_status, exit_code = 0,
+			finished_at = now(), heartbeat_at = NULL, version = version + 1
+		WHERE id = $1 AND version = $2
+	`, runID, version))
+	if err != nil {
+		t.Fatalf("finishing auto-resumed run: %v", err)
+	}
+
+	var status string
+	if err := db.Pool.QueryRow(ctx, `SELECT status FROM job_runs WHERE id = $1`, runID).Scan(&status); err != nil {
+		t.Fatalf("reading run: %v", err)
+	}
+	if status != "succeeded" {
+		t.Fatalf("status = %q, want succeeded", status)
+	}
+}
+
+func TestShouldReap(t *testing.T) {
+	tests := []struct {
+		name             string
+		containerRunning bool
+		ownedHere        bool
+		heartbeatAge     time.Duration
+		want             bool
+	}{
+		{"container gone", false, false, time.Minute, true},
+		{"container gone, owned here", false, true, time.Minute, true},
+		{"running, owned here", true, true, time.Hour, false},
+		{"running elsewhere, within grace", true, false, maxStaleGrace - time.Second, false},
+		{"running elsewhere, grace over", true, false, maxStaleGrace, true},
+		{"running elsewhere, long past grace", true, false, time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldReap(tt.containerRunning, tt.ownedHere, tt.heartbeatAge); got != tt.want {
+				t.Errorf("shouldReap(%v, %v, %v) = %v, want %v",
+					tt.containerRunning, tt.ownedHere, tt.heartbeatAge, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/orbex-dev/orbex/internal/database"
+)
+
+// listenRetryDelay is how long to wait before re-establishing a dropped LISTEN.
+const listenRetryDelay = 5 * time.Second
+
+// listenQueue holds a dedicated connection LISTENing on the queue channel and
+// wakes the poll loop on every notification. If the connection drops, polling
+// carries on at its normal interval until the listener reconnects.
+func (w *Worker) listenQueue(ctx context.Context) {
+	for {
+		if err := w.waitForQueueNotifications(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[worker] Queue listener error: %v (retrying in %s)", err, listenRetryDelay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stopCh:
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+// waitForQueueNotifications LISTENs on one pooled connection until it fails.
+func (w *Worker) waitForQueueNotifications(ctx context.Context) error {
+	conn, err := w.db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+database.QueueChannel); err != nil {
+		return err
+	}
+	// The connection returns to the pool afterwards; don't leave it subscribed
+	defer conn.Exec(context.WithoutCancel(ctx), "UNLISTEN "+database.QueueChannel)
+
+	for {
+		if _, err := conn.Conn().WaitForNotification(ctx); err != nil {
+			return err
+		}
+		w.wake()
+	}
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// truncatedMarker prefixes a stored log tail that had its beginning cut off.
+const truncatedMarker = "[truncated]\n"
+
+// storedLogs returns the part of a run's logs to keep in logs_tail: the last
+// MaxStoredLogBytes bytes, since that's usually where the errors are. When
+// logs are cut, the full output is put in the log store and the run records
+// the backend holding it so it can still be fetched.
+func (w *Worker) storedLogs(ctx context.Context, runID uuid.UUID, logs string) string {
+	limit := w.cfg.MaxStoredLogBytes
+	if limit <= 0 || len(logs) <= limit {
+		return logs
+	}
+
+	if w.logs != nil {
+		if err := w.logs.Put(ctx, runID, strings.NewReader(logs)); err != nil {
+			log.Printf("[worker] Warning: failed to store full logs for %s: %v", runID, err)
+		} else if _, err := w.db.Pool.Exec(ctx, `UPDATE job_runs SET full_logs_backend = $1 WHERE id = $2`, w.logs.Backend(), runID); err != nil {
+			log.Printf("[worker] ERROR recording full logs for %s: %v", runID, err)
+		}
+	}
+
+	return truncatedMarker + logTail(logs, limit)
+}
+
+// logTail returns at most the last limit bytes of logs, moving the cut
+// forward to the start of a rune so a multi-byte character is never split
+// (logs_tail is TEXT, which must be valid UTF-8).
+func logTail(logs string, limit int) string {
+	if len(logs) <= limit {
+		return logs
+	}
+	cut := len(logs) - limit
+	for cut < len(logs) && !utf8.RuneStart(logs[cut]) {
+		cut++
+	}
+	return logs[cut:]
+}
@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLogTail(t *testing.T) {
+	tests := []struct {
+		name  string
+		logs  string
+		limit int
+		want  string
+	}{
+		{"under limit", "hello", 10, "hello"},
+		{"at limit", "hello", 5, "hello"},
+		{"ascii cut", "hello world", 5, "world"},
+		{"cut inside a rune", "aé", 1, ""},
+		{"cut after a rune", "aéb", 2, "b"},
+		{"cut before a rune", "aéb", 3, "éb"},
+		{"cut inside a 4-byte rune", "x🙂y", 4, "y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := logTail(tt.logs, tt.limit)
+			if got != tt.want {
+				t.Errorf("logTail(%q, %d) = %q, want %q", tt.logs, tt.limit, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("logTail(%q, %d) = %q is not valid UTF-8", tt.logs, tt.limit, got)
+			}
+		})
+	}
+}
+
+func TestNotificationLogsValidUTF8(t *testing.T) {
+	logs := strings.Repeat("é", maxNotificationLogBytes)
+	got := notificationLogs(logs)
+	if !utf8.ValidString(got) {
+		t.Fatal("notificationLogs split a multi-byte character")
+	}
+	if !strings.HasPrefix(got, truncatedMarker) {
+		t.Fatalf("notificationLogs(...) = %.20q..., want the truncated marker", got)
+	}
+}
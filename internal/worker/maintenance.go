@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maintenanceKey is the settings row holding maintenance mode. It lives in
+// the database so every server process sharing it agrees.
+const maintenanceKey = "maintenance"
+
+// SetMaintenance turns maintenance mode on or off for every server sharing
+// the database. In maintenance mode the scheduler and job dependencies
+// enqueue no runs and the API refuses triggers, while queued and in-flight
+// runs carry on; combine with Drain to stop those too.
+func (w *Worker) SetMaintenance(ctx context.Context, on bool) error {
+	value := "false"
+	if on {
+		value = "true"
+	}
+	var was *string
+	err := w.db.Pool.QueryRow(ctx, `
+		WITH old AS (SELECT value FROM settings WHERE key = $1)
+		INSERT INTO settings (key, value) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = now()
+		RETURNING (SELECT value FROM old)
+	`, maintenanceKey, value).Scan(&was)
+	if err != nil {
+		return err
+	}
+	if was == nil || *was != value {
+		if on {
+			log.Println("[worker] Maintenance mode on: no new runs will be created")
+		} else {
+			log.Println("[worker] Maintenance mode off")
+		}
+	}
+	return nil
+}
+
+// Maintenance reports whether maintenance mode is on. It's off until first
+// set.
+func (w *Worker) Maintenance(ctx context.Context) (bool, error) {
+	var value string
+	err := w.db.Pool.QueryRow(ctx, `SELECT value FROM settings WHERE key = $1`, maintenanceKey).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return value == "true", nil
+}
+
+// inMaintenance reports whether maintenance mode is on for background work
+// deciding whether to create runs. If it can't be read, runs aren't created:
+// holding them back until the next check is the safe side.
+func (w *Worker) inMaintenance(ctx context.Context, who string) bool {
+	on, err := w.Maintenance(ctx)
+	if err != nil {
+		log.Printf("[%s] ERROR reading maintenance mode: %v", who, err)
+		return true
+	}
+	return on
+}
@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"context"
+	"testing"
+)
+
+// TestMaintenanceIsShared checks that maintenance mode set through one worker
+// is seen by another on the same database, as a second server would.
+func TestMaintenanceIsShared(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	a, b := &Worker{db: db}, &Worker{db: db}
+	t.Cleanup(func() { _ = a.SetMaintenance(context.Background(), false) })
+
+	if err := a.SetMaintenance(ctx, true); err != nil {
+		t.Fatalf("SetMaintenance(true): %v", err)
+	}
+	if on, err := b.Maintenance(ctx); err != nil || !on {
+		t.Fatalf("Maintenance() on other worker = %v, %v; want true, nil", on, err)
+	}
+	if err := b.SetMaintenance(ctx, false); err != nil {
+		t.Fatalf("SetMaintenance(false): %v", err)
+	}
+	if on, err := a.Maintenance(ctx); err != nil || on {
+		t.Fatalf("Maintenance() after turning it off = %v, %v; want false, nil", on, err)
+	}
+}
@@ -0,0 +1,14 @@
+package worker
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/orbex-dev/orbex/internal/metrics"
+)
+
+// runQueueWait separates "the job is slow" from "the queue is backed up".
+var runQueueWait = metrics.Factory.NewHistogram(prometheus.HistogramOpts{
+	Name:    "orbex_run_queue_wait_seconds",
+	Help:    "Time runs spent queued before a worker started them.",
+	Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800, 3600},
+})
@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 // notificationPayload is the JSON sent to webhook URLs on run completion.
@@ -21,23 +27,89 @@ type notificationPayload struct {
 	ExitCode  int64     `json:"exit_code"`
 	Duration  int64     `json:"duration_ms"`
 	Error     string    `json:"error,omitempty"`
+	Recovered bool      `json:"recovered,omitempty"`
+	Logs      string    `json:"logs,omitempty"` // Log tail, when the job has notify_include_logs
 	Timestamp time.Time `json:"timestamp"`
 }
 
-// sendNotification checks if the job has a notify_webhook URL and POSTs the result.
+// maxNotificationLogBytes bounds the log tail attached to a notification so
+// webhook bodies and Slack messages stay small.
+const maxNotificationLogBytes = 4 << 10
+
+// SMTPConfig holds the mail server used for email notification channels.
+type SMTPConfig struct {
+	Host     string // Empty disables email delivery
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// notifyChannel is a notification destination loaded for a finished run.
+type notifyChannel struct {
+	Type   string
+	Target string
+}
+
+// sendNotification delivers a run's result to every notification channel on
+// the job whose event filter matches the outcome, subject to the job's
+// notify_on policy. Delivery is asynchronous.
 func (w *Worker) sendNotification(ctx context.Context, jobID, runID uuid.UUID, status string, exitCode int64, durationMs int64, errorMsg string) {
-	var notifyWebhook *string
-	var jobName string
+	var jobName, notifyOn string
+	var includeLogs bool
+	if err := w.db.Pool.QueryRow(ctx, `
+		SELECT name, notify_on, notify_include_logs FROM jobs WHERE id = $1
+	`, jobID).Scan(&jobName, &notifyOn, &includeLogs); err != nil {
+		return
+	}
 
+	// The previous finished run decides whether a success is a recovery.
+	// Cancelled runs are skipped: a manual kill says nothing about job health.
+	var prevStatus string
 	err := w.db.Pool.QueryRow(ctx, `
-		SELECT name, notify_webhook FROM jobs WHERE id = $1
-	`, jobID).Scan(&jobName, &notifyWebhook)
-	if err != nil || notifyWebhook == nil || *notifyWebhook == "" {
+		SELECT status::text FROM job_runs
+		WHERE job_id = $1 AND id != $2 AND status IN ('succeeded', 'failed')
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, jobID, runID).Scan(&prevStatus)
+	if err != nil && err != pgx.ErrNoRows {
+		log.Printf("[notify] ERROR loading previous run for job %s: %v", jobID, err)
+	}
+	recovered := status == "succeeded" && prevStatus == "failed"
+
+	if !shouldNotify(notifyOn, status, recovered) {
+		return
+	}
+
+	event := "failure"
+	if status == "succeeded" {
+		event = "success"
+	}
+
+	rows, err := w.db.Pool.Query(ctx, `
+		SELECT type, target FROM notification_channels
+		WHERE job_id = $1 AND events IN ('all', $2)
+	`, jobID, event)
+	if err != nil {
+		log.Printf("[notify] ERROR loading channels for job %s: %v", jobID, err)
+		return
+	}
+	var channels []notifyChannel
+	for rows.Next() {
+		var c notifyChannel
+		if err := rows.Scan(&c.Type, &c.Target); err != nil {
+			continue
+		}
+		channels = append(channels, c)
+	}
+	rows.Close()
+	if len(channels) == 0 {
 		return // No notification configured
 	}
 
 	payload := notificationPayload{
 		Event:     "run.completed",
+		Recovered: recovered,
 		RunID:     runID.String(),
 		JobID:     jobID.String(),
 		JobName:   jobName,
@@ -47,16 +119,135 @@ func (w *Worker) sendNotification(ctx context.Context, jobID, runID uuid.UUID, s
 		Error:     errorMsg,
 		Timestamp: time.Now(),
 	}
+	if includeLogs {
+		var logsTail *string
+		if err := w.db.Pool.QueryRow(ctx, `SELECT logs_tail FROM job_runs WHERE id = $1`, runID).Scan(&logsTail); err != nil {
+			log.Printf("[notify] ERROR loading logs for run %s: %v", runID, err)
+		} else if logsTail != nil {
+			payload.Logs = notificationLogs(*logsTail)
+		}
+	}
+
+	for _, c := range channels {
+		go func(c notifyChannel) {
+			var err error
+			switch c.Type {
+			case "webhook":
+				err = deliverWebhook(c.Target, payload)
+			case "slack":
+				err = deliverSlack(c.Target, payload)
+			case "email":
+				err = w.deliverEmail(c.Target, payload)
+			default:
+				err = fmt.Errorf("unknown channel type %q", c.Type)
+			}
+			if err != nil {
+				log.Printf("[notify] %s delivery failed for run %s: %v", c.Type, runID, err)
+				return
+			}
+			log.Printf("[notify] %s delivered for run %s → %s", c.Type, runID, c.Target)
+		}(c)
+	}
+}
+
+// shouldNotify applies a job's notify_on policy to a finished run.
+func shouldNotify(notifyOn, status string, recovered bool) bool {
+	switch notifyOn {
+	case "failure":
+		return status != "succeeded"
+	case "success":
+		return status == "succeeded"
+	case "failure_and_recovery":
+		return status != "succeeded" || recovered
+	default:
+		return true
+	}
+}
 
+// deliverWebhook POSTs the raw JSON payload to a URL.
+func deliverWebhook(target string, payload notificationPayload) error {
 	data, _ := json.Marshal(payload)
-	go func() {
-		client := &http.Client{Timeout: 10 * time.Second}
-		resp, err := client.Post(*notifyWebhook, "application/json", bytes.NewReader(data))
-		if err != nil {
-			log.Printf("[notify] Webhook delivery failed for run %s: %v", runID, err)
-			return
-		}
-		defer resp.Body.Close()
-		log.Printf("[notify] Webhook delivered for run %s → %s (status %d)", runID, *notifyWebhook, resp.StatusCode)
-	}()
+	return postJSON(target, data)
+}
+
+// deliverSlack posts a formatted message to a Slack incoming webhook.
+func deliverSlack(target string, payload notificationPayload) error {
+	text := notificationText(payload)
+	if payload.Logs != "" {
+		text += "\n```\n" + strings.TrimRight(payload.Logs, "\n") + "\n```"
+	}
+	data, _ := json.Marshal(map[string]string{"text": text})
+	return postJSON(target, data)
+}
+
+// deliverEmail sends the run result to an address via the configured SMTP server.
+func (w *Worker) deliverEmail(to string, payload notificationPayload) error {
+	smtpCfg := w.cfg.SMTP
+	if smtpCfg.Host == "" {
+		return fmt.Errorf("SMTP is not configured")
+	}
+
+	subject := fmt.Sprintf("[Orbex] %s %s", payload.JobName, payload.Status)
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", smtpCfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(notificationText(payload))
+	fmt.Fprintf(&msg, "\r\n\r\nRun: %s\r\nJob: %s\r\n", payload.RunID, payload.JobID)
+	if payload.Logs != "" {
+		msg.WriteString("\r\nLogs:\r\n")
+		msg.WriteString(strings.ReplaceAll(payload.Logs, "\n", "\r\n"))
+	}
+
+	var auth smtp.Auth
+	if smtpCfg.Username != "" {
+		auth = smtp.PlainAuth("", smtpCfg.Username, smtpCfg.Password, smtpCfg.Host)
+	}
+	addr := net.JoinHostPort(smtpCfg.Host, strconv.Itoa(smtpCfg.Port))
+	return smtp.SendMail(addr, auth, smtpCfg.From, []string{to}, []byte(msg.String()))
+}
+
+// notificationLogs trims a run's log tail to the last
+// maxNotificationLogBytes bytes, starting at a line boundary where possible.
+func notificationLogs(logs string) string {
+	logs = strings.TrimPrefix(logs, truncatedMarker)
+	if len(logs) <= maxNotificationLogBytes {
+		return logs
+	}
+	logs = logTail(logs, maxNotificationLogBytes)
+	if i := strings.IndexByte(logs, '\n'); i >= 0 && i < len(logs)-1 {
+		logs = logs[i+1:]
+	}
+	return truncatedMarker + logs
+}
+
+// notificationText renders a one-line human summary of a run result.
+func notificationText(p notificationPayload) string {
+	duration := time.Duration(p.Duration) * time.Millisecond
+	if p.Recovered {
+		return fmt.Sprintf("✅ %s recovered: succeeded in %s (run %s)", p.JobName, duration, p.RunID[:8])
+	}
+	if p.Status == "succeeded" {
+		return fmt.Sprintf("✅ %s succeeded in %s (run %s)", p.JobName, duration, p.RunID[:8])
+	}
+	text := fmt.Sprintf("❌ %s %s after %s (run %s, exit %d)", p.JobName, p.Status, duration, p.RunID[:8], p.ExitCode)
+	if p.Error != "" {
+		text += ": " + p.Error
+	}
+	return text
+}
+
+// postJSON POSTs a JSON body and treats any non-2xx response as an error.
+func postJSON(target string, data []byte) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(target, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
 }
@@ -0,0 +1,165 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/orbex-dev/orbex/internal/models"
+)
+
+// stuckPickThreshold is how long a queue item may stay picked while its run
+// is still pending. A live worker marks the run running right after picking
+// it, so an item older than this was picked by a worker that died.
+const stuckPickThreshold = 2 * time.Minute
+
+var (
+	// ErrQueueItemNotFound is returned by UnlockQueueItem for an unknown item.
+	ErrQueueItemNotFound = errors.New("queue item not found")
+	// ErrQueueItemStarted is returned by UnlockQueueItem when the item's run
+	// has already started, so re-queueing it would run it twice.
+	ErrQueueItemStarted = errors.New("queue item's run has already started")
+)
+
+// releaseStuckQueueItems un-picks queue items whose worker died before
+// starting the run, so another worker claims them, and drops items whose run
+// already finished (which workers no longer claim). Items this process is
+// still executing are left alone.
+func (w *Worker) releaseStuckQueueItems(ctx context.Context) {
+	rows, err := w.db.Pool.Query(ctx, `
+		SELECT q.id, q.run_id, r.status
+		FROM job_queue q
+		JOIN job_runs r ON r.id = q.run_id
+		WHERE (q.picked_at IS NULL OR q.picked_at < now() - $1::interval)
+		  AND r.status IN ('pending'::run_status, 'succeeded'::run_status, 'failed'::run_status, 'cancelled'::run_status)
+		  AND NOT (q.picked_at IS NULL AND r.status = 'pending'::run_status)
+	`, stuckPickThreshold.String())
+	if err != nil {
+		return
+	}
+	type stuckItem struct {
+		queueID, runID uuid.UUID
+		status         models.RunStatus
+	}
+	items, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (stuckItem, error) {
+		var it stuckItem
+		err := row.Scan(&it.queueID, &it.runID, &it.status)
+		return it, err
+	})
+	if err != nil {
+		return
+	}
+
+	released := 0
+	for _, it := range items {
+		if _, owned := w.inFlight.Load(it.runID); owned {
+			continue
+		}
+		if it.status.IsTerminal() {
+			w.cleanupQueue(ctx, it.queueID)
+			continue
+		}
+		// The run may have started since it was listed; only a run still
+		// pending is released, in the same statement that checks it
+		tag, err := w.db.Pool.Exec(ctx, `
+			UPDATE job_queue q SET picked_at = NULL
+			FROM job_runs r
+			WHERE q.id = $1 AND r.id = q.run_id
+			  AND r.status = 'pending'::run_status
+			  AND q.picked_at < now() - $2::interval
+		`, it.queueID, stuckPickThreshold.String())
+		if err != nil {
+			log.Printf("[reaper] ERROR releasing queue item %s: %v", it.queueID, err)
+			continue
+		}
+		if tag.RowsAffected() > 0 {
+			log.Printf("[reaper] Released run %s: picked by a worker that never started it", it.runID)
+			released++
+		}
+	}
+	if released > 0 {
+		w.wake()
+	}
+}
+
+// expireUnstartedRuns fails pending runs whose job's start deadline passed
+// while they waited in the queue, and drops their queue items. Bumping the
+// version makes a worker that claims one of them in the meantime back off.
+func (w *Worker) expireUnstartedRuns(ctx context.Context) {
+	rows, err := w.db.Pool.Query(ctx, `
+		UPDATE job_runs r SET
+			status = 'failed'::run_status,
+			error_message = format('container not running within %ss of being queued (stuck in the queue)', j.start_timeout_seconds),
+			failure_reason = 'start_timeout',
+			finished_at = now(),
+			version = r.version + 1
+		FROM jobs j
+		WHERE j.id = r.job_id
+		  AND r.status = 'pending'::run_status
+		  AND j.start_timeout_seconds > 0
+		  AND r.created_at < now() - make_interval(secs => j.start_timeout_seconds)
+		RETURNING r.id
+	`)
+	if err != nil {
+		log.Printf("[reaper] ERROR expiring unstarted runs: %v", err)
+		return
+	}
+	runIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil || len(runIDs) == 0 {
+		return
+	}
+	_, _ = w.db.Pool.Exec(ctx, `DELETE FROM job_queue WHERE run_id = ANY($1)`, runIDs)
+	for _, id := range runIDs {
+		log.Printf("[reaper] Run %s missed its start deadline — failed", id)
+	}
+}
+
+// UnlockQueueItem clears picked_at on a queue item so a worker claims its run
+// again, whatever its age. It refuses once the run has started.
+func (w *Worker) UnlockQueueItem(ctx context.Context, queueID uuid.UUID) (models.QueueItem, error) {
+	var item models.QueueItem
+	var status models.RunStatus
+	err := w.db.Pool.QueryRow(ctx, `
+		SELECT q.run_id, r.status FROM job_queue q JOIN job_runs r ON r.id = q.run_id WHERE q.id = $1
+	`, queueID).Scan(&item.RunID, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return item, ErrQueueItemNotFound
+	}
+	if err != nil {
+		return item, err
+	}
+	if _, owned := w.inFlight.Load(item.RunID); owned {
+		return item, fmt.Errorf("%w (executing on this worker)", ErrQueueItemStarted)
+	}
+
+	// The status check is part of the update, so a run that starts between
+	// the read above and here isn't released to run a second time
+	err = w.db.Pool.QueryRow(ctx, `
+		UPDATE job_queue q SET picked_at = NULL
+		FROM job_runs r
+		WHERE q.id = $1 AND r.id = q.run_id AND r.status = 'pending'::run_status
+		RETURNING q.id, q.job_id, q.run_id, q.priority, q.scheduled_at, q.picked_at, q.created_at
+	`, queueID).Scan(&item.ID, &item.JobID, &item.RunID, &item.Priority, &item.ScheduledAt, &item.PickedAt, &item.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = w.db.Pool.QueryRow(ctx, `
+			SELECT r.status FROM job_queue q JOIN job_runs r ON r.id = q.run_id WHERE q.id = $1
+		`, queueID).Scan(&status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return item, ErrQueueItemNotFound
+		}
+		if err != nil {
+			return item, err
+		}
+		return item, fmt.Errorf("%w (status %s)", ErrQueueItemStarted, status)
+	}
+	if err != nil {
+		return item, err
+	}
+	log.Printf("[worker] Queue item %s (run %s) force-unlocked", queueID, item.RunID)
+	w.wake()
+	return item, nil
+}
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// resultPrefix marks a line of run output that carries the run's result:
+// a job prints "::orbex-result::" followed by a JSON value, e.g.
+//
+//	::orbex-result::{"rows": 1234}
+//
+// If several such lines are printed, the last one wins.
+const resultPrefix = "::orbex-result::"
+
+// maxResultBytes caps a run's result; larger values are ignored.
+const maxResultBytes = 64 << 10
+
+// parseRunResult returns the run result in logs, or nil if there is none.
+// A result line that isn't valid JSON or is too large is ignored, with the
+// problem returned as warning.
+func parseRunResult(logs string) (result json.RawMessage, warning string) {
+	for line := range strings.Lines(logs) {
+		value, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), resultPrefix)
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) > maxResultBytes:
+			warning = "result exceeds 64KB"
+		case !json.Valid([]byte(value)):
+			warning = "result is not valid JSON"
+		default:
+			result, warning = json.RawMessage(value), ""
+		}
+	}
+	return result, warning
+}
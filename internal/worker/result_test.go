@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRunResult(t *testing.T) {
+	oversize := resultPrefix + `"` + strings.Repeat("x", maxResultBytes) + `"` + "\n"
+	tests := []struct {
+		name        string
+		logs        string
+		wantResult  string
+		wantWarning string
+	}{
+		{"none", "hello\nworld\n", "", ""},
+		{"single", "start\n::orbex-result::{\"rows\": 1234}\ndone\n", `{"rows": 1234}`, ""},
+		{"crlf and padding", "::orbex-result::  [1, 2]  \r\n", `[1, 2]`, ""},
+		{"last wins", "::orbex-result::1\n::orbex-result::2\n", "2", ""},
+		{"no trailing newline", "::orbex-result::true", "true", ""},
+		{"invalid JSON", "::orbex-result::{rows\n", "", "result is not valid JSON"},
+		{"invalid after valid keeps valid", "::orbex-result::1\n::orbex-result::nope\n", "1", "result is not valid JSON"},
+		{"valid after invalid clears warning", "::orbex-result::nope\n::orbex-result::1\n", "1", ""},
+		{"oversize", oversize, "", "result exceeds 64KB"},
+		{"prefix mid-line ignored", "echo ::orbex-result::1\n", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, warning := parseRunResult(tt.logs)
+			if string(result) != tt.wantResult || warning != tt.wantWarning {
+				t.Errorf("parseRunResult() = %q, %q; want %q, %q", result, warning, tt.wantResult, tt.wantWarning)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/orbex-dev/orbex/internal/docker"
+)
+
+const retentionInterval = time.Hour
+
+// RunRetention periodically deletes finished runs that fall outside the
+// retention policy. Blocks until ctx is cancelled.
+func (w *Worker) RunRetention(ctx context.Context) {
+	log.Printf("[retention] Started (interval=%s, days=%d, maxRuns=%d, logDays=%d, keptContainerTTL=%s)",
+		retentionInterval, w.cfg.RetentionDays, w.cfg.RetentionMaxRuns, w.cfg.LogRetentionDays, w.cfg.KeptContainerTTL)
+
+	// Sweep immediately on startup, then every interval
+	w.removeKeptContainers(ctx)
+	w.pruneRuns(ctx)
+	w.pruneLogs(ctx)
+	w.pruneRefreshTokens(ctx)
+
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[retention] Stopped")
+			return
+		case <-ticker.C:
+			w.removeKeptContainers(ctx)
+			w.pruneRuns(ctx)
+			w.pruneLogs(ctx)
+			w.pruneRefreshTokens(ctx)
+		}
+	}
+}
+
+// pruneRuns deletes finished runs that are older than the retention window or
+// beyond the per-job run limit. A job's own retention_days/retention_max_runs
+// take precedence over the global config. In-flight runs are never touched,
+// nor are runs whose container is still kept (they go once it is removed).
+// Full logs the pruned runs left in the log store are deleted with them.
+func (w *Worker) pruneRuns(ctx context.Context) {
+	rows, err := w.db.Pool.Query(ctx, `
+		DELETE FROM job_runs r
+		USING (
+			SELECT jr.id, jr.created_at,
+			       ROW_NUMBER() OVER (PARTITION BY jr.job_id ORDER BY jr.created_at DESC) AS rn,
+			       COALESCE(j.retention_days, $1) AS keep_days,
+			       COALESCE(j.retention_max_runs, $2) AS keep_runs
+			FROM job_runs jr
+			JOIN jobs j ON j.id = jr.job_id
+			WHERE jr.status IN ('succeeded'::run_status, 'failed'::run_status, 'cancelled'::run_status)
+			  AND jr.container_kept_until IS NULL
+		) old
+		WHERE r.id = old.id
+		  AND ((old.keep_days > 0 AND old.created_at < now() - make_interval(days => old.keep_days))
+		    OR (old.keep_runs > 0 AND old.rn > old.keep_runs))
+		RETURNING r.id, r.full_logs_backend, r.logs_object_key
+	`, w.cfg.RetentionDays, w.cfg.RetentionMaxRuns)
+	if err != nil {
+		log.Printf("[retention] ERROR pruning runs: %v", err)
+		return
+	}
+
+	pruned, err := w.deleteFullLogs(ctx, rows)
+	if err != nil {
+		log.Printf("[retention] ERROR pruning runs: %v", err)
+		return
+	}
+	if pruned > 0 {
+		log.Printf("[retention] Pruned %d old runs", pruned)
+	}
+}
+
+// pruneLogs clears the stored logs of finished runs older than their job's
+// log_retention_days (or the global LogRetentionDays), deleting any full logs
+// kept in the log store. The runs themselves are kept.
+func (w *Worker) pruneLogs(ctx context.Context) {
+	rows, err := w.db.Pool.Query(ctx, `
+		WITH expired AS (
+			SELECT r.id, r.full_logs_backend, r.logs_object_key
+			FROM job_runs r
+			JOIN jobs j ON j.id = r.job_id
+			WHERE r.status IN ('succeeded'::run_status, 'failed'::run_status, 'cancelled'::run_status)
+			  AND (r.logs_tail IS NOT NULL OR r.full_logs_backend IS NOT NULL OR r.logs_object_key IS NOT NULL)
+			  AND COALESCE(j.log_retention_days, $1) > 0
+			  AND COALESCE(r.finished_at, r.created_at) < now() - make_interval(days => COALESCE(j.log_retention_days, $1))
+			FOR UPDATE OF r
+		)
+		UPDATE job_runs r SET logs_tail = NULL, full_logs_backend = NULL, logs_object_key = NULL
+		FROM expired
+		WHERE r.id = expired.id
+		RETURNING expired.id, expired.full_logs_backend, expired.logs_object_key
+	`, w.cfg.LogRetentionDays)
+	if err != nil {
+		log.Printf("[retention] ERROR pruning logs: %v", err)
+		return
+	}
+
+	cleared, err := w.deleteFullLogs(ctx, rows)
+	if err != nil {
+		log.Printf("[retention] ERROR pruning logs: %v", err)
+		return
+	}
+	if cleared > 0 {
+		log.Printf("[retention] Cleared logs of %d runs", cleared)
+	}
+}
+
+// pruneRefreshTokens deletes expired refresh tokens. They can no longer be
+// exchanged, but nothing else removes them.
+func (w *Worker) pruneRefreshTokens(ctx context.Context) {
+	tag, err := w.db.Pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE expires_at < now()`)
+	if err != nil {
+		log.Printf("[retention] ERROR pruning refresh tokens: %v", err)
+		return
+	}
+	if n := tag.RowsAffected(); n > 0 {
+		log.Printf("[retention] Pruned %d expired refresh tokens", n)
+	}
+}
+
+// deleteFullLogs reads (run id, full_logs_backend, logs_object_key) rows and
+// deletes the full logs each run kept: from its log store backend, or for
+// runs from before the log store, the object at logs_object_key. It returns
+// the number of rows.
+func (w *Worker) deleteFullLogs(ctx context.Context, rows pgx.Rows) (int, error) {
+	type storedLogs struct {
+		runID   uuid.UUID
+		backend string
+	}
+	var n int
+	var stored []storedLogs
+	var legacyKeys []string
+	for rows.Next() {
+		var runID uuid.UUID
+		var backend, key *string
+		if err := rows.Scan(&runID, &backend, &key); err != nil {
+			continue
+		}
+		n++
+		if backend != nil {
+			stored = append(stored, storedLogs{runID, *backend})
+		}
+		if key != nil {
+			legacyKeys = append(legacyKeys, *key)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	if w.logs != nil {
+		for _, s := range stored {
+			if err := w.logs.Delete(ctx, s.backend, s.runID); err != nil {
+				log.Printf("[retention] Warning: failed to delete logs of %s: %v", s.runID, err)
+			}
+		}
+	}
+	if w.storage != nil {
+		for _, key := range legacyKeys {
+			if err := w.storage.Delete(ctx, key); err != nil {
+				log.Printf("[retention] Warning: failed to delete logs %s: %v", key, err)
+			}
+		}
+	}
+	return n, nil
+}
+
+// removeKeptContainers removes failed containers that were kept for debugging
+// once their keep window has passed. A run stops being tracked only once its
+// container is gone; failed removals are retried on the next sweep.
+func (w *Worker) removeKeptContainers(ctx context.Context) {
+	rows, err := w.db.Pool.Query(ctx, `
+		SELECT id, container_id, docker_host FROM job_runs
+		WHERE container_kept_until IS NOT NULL AND container_kept_until < now()
+	`)
+	if err != nil {
+		log.Printf("[retention] ERROR listing kept containers: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	var expired []staleRun
+	for rows.Next() {
+		var sr staleRun
+		if err := rows.Scan(&sr.ID, &sr.ContainerID, &sr.DockerHost); err != nil {
+			continue
+		}
+		expired = append(expired, sr)
+	}
+
+	removed := 0
+	for _, sr := range expired {
+		if sr.ContainerID != nil && *sr.ContainerID != "" {
+			dc, containerID, ok := w.container(sr)
+			if !ok {
+				log.Printf("[retention] Warning: can't remove kept container for %s: docker host %q is not configured", sr.ID, deref(sr.DockerHost))
+				continue
+			}
+			err := dc.RemoveContainer(ctx, containerID)
+			if err != nil && !errors.Is(err, docker.ErrContainerNotFound) {
+				log.Printf("[retention] Warning: failed to remove kept container for %s: %v", sr.ID, err)
+				continue
+			}
+		}
+		if _, err := w.db.Pool.Exec(ctx, `UPDATE job_runs SET container_kept_until = NULL WHERE id = $1`, sr.ID); err != nil {
+			log.Printf("[retention] ERROR clearing kept container of %s: %v", sr.ID, err)
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		log.Printf("[retention] Removed %d kept containers", removed)
+	}
+}
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// TestPruneRefreshTokens checks that expired refresh tokens are deleted and
+// live ones kept.
+func TestPruneRefreshTokens(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	w := &Worker{db: db}
+
+	var userID uuid.UUID
+	if err := db.Pool.QueryRow(ctx, `
+		INSERT INTO users (email, password) VALUES ($1, 'x') RETURNING id
+	`, uuid.NewString()+"@example.com").Scan(&userID); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Pool.Exec(context.Background(), "DELETE FROM users WHERE id = $1", userID)
+	})
+	expired, live := uuid.NewString(), uuid.NewString()
+	if _, err := db.Pool.Exec(ctx, `
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, now() - interval '1 minute'), ($1, $3, now() + interval '1 hour')
+	`, userID, expired, live); err != nil {
+		t.Fatalf("creating refresh tokens: %v", err)
+	}
+
+	w.pruneRefreshTokens(ctx)
+
+	rows, err := db.Pool.Query(ctx, `SELECT token_hash FROM refresh_tokens WHERE user_id = $1`, userID)
+	if err != nil {
+		t.Fatalf("listing refresh tokens: %v", err)
+	}
+	var left []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			t.Fatalf("scanning refresh token: %v", err)
+		}
+		left = append(left, hash)
+	}
+	if len(left) != 1 || left[0] != live {
+		t.Errorf("tokens left = %v, want only the live one %s", left, live)
+	}
+}
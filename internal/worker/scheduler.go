@@ -5,7 +5,8 @@ import (
 	"log"
 	"time"
 
-	"github.com/robfig/cron/v3"
+	"github.com/orbex-dev/orbex/internal/database"
+	"github.com/orbex-dev/orbex/internal/schedule"
 )
 
 const schedulerInterval = 60 * time.Second
@@ -39,8 +40,14 @@ type scheduledJob struct {
 	Schedule string
 }
 
-// checkScheduledJobs finds all active jobs with schedules and enqueues runs if they're due.
+// checkScheduledJobs finds all active jobs with schedules and enqueues runs if
+// they're due. Nothing is enqueued in maintenance mode; runs due meanwhile are
+// enqueued once, on the first check after it ends.
 func (w *Worker) checkScheduledJobs(ctx context.Context) {
+	if w.inMaintenance(ctx, "scheduler") {
+		return
+	}
+
 	rows, err := w.db.Pool.Query(ctx, `
 		SELECT j.id, j.user_id, j.schedule
 		FROM jobs j
@@ -55,23 +62,22 @@ func (w *Worker) checkScheduledJobs(ctx context.Context) {
 
 	for rows.Next() {
 		var jobID, userID [16]byte
-		var schedule string
-		if err := rows.Scan(&jobID, &userID, &schedule); err != nil {
+		var expr string
+		if err := rows.Scan(&jobID, &userID, &expr); err != nil {
 			log.Printf("[scheduler] ERROR scanning job: %v", err)
 			continue
 		}
 
-		if w.shouldEnqueue(ctx, jobID, schedule) {
+		if w.shouldEnqueue(ctx, jobID, expr) {
 			w.enqueueScheduledRun(ctx, jobID, userID)
 		}
 	}
 }
 
 // shouldEnqueue checks if a scheduled job is due for a new run.
-func (w *Worker) shouldEnqueue(ctx context.Context, jobID [16]byte, schedule string) bool {
+func (w *Worker) shouldEnqueue(ctx context.Context, jobID [16]byte, expr string) bool {
 	// Parse cron expression
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	sched, err := parser.Parse(schedule)
+	sched, err := schedule.Parse(expr)
 	if err != nil {
 		log.Printf("[scheduler] Invalid cron expression for job %x: %v", jobID[:4], err)
 		return false
@@ -116,8 +122,8 @@ func (w *Worker) enqueueScheduledRun(ctx context.Context, jobID, userID [16]byte
 	// Create run record
 	var runID [16]byte
 	err := w.db.Pool.QueryRow(ctx, `
-		INSERT INTO job_runs (job_id, user_id, status)
-		VALUES ($1, $2, 'pending'::run_status)
+		INSERT INTO job_runs (job_id, user_id, status, trigger_source)
+		VALUES ($1, $2, 'pending'::run_status, 'schedule'::trigger_source)
 		RETURNING id
 	`, jobID, userID).Scan(&runID)
 	if err != nil {
@@ -134,6 +140,9 @@ func (w *Worker) enqueueScheduledRun(ctx context.Context, jobID, userID [16]byte
 		log.Printf("[scheduler] ERROR enqueuing run for job %x: %v", jobID[:4], err)
 		return
 	}
+	if err := database.NotifyQueue(ctx, w.db.Pool); err != nil {
+		log.Printf("[scheduler] Warning: failed to notify workers: %v", err)
+	}
 
 	log.Printf("[scheduler] Enqueued run %x for scheduled job %x", runID[:4], jobID[:4])
 }
@@ -2,75 +2,162 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/orbex-dev/orbex/internal/compose"
 	"github.com/orbex-dev/orbex/internal/database"
 	"github.com/orbex-dev/orbex/internal/docker"
+	"github.com/orbex-dev/orbex/internal/envcrypt"
+	"github.com/orbex-dev/orbex/internal/logstore"
 	"github.com/orbex-dev/orbex/internal/models"
+	"github.com/orbex-dev/orbex/internal/runvars"
 	"github.com/orbex-dev/orbex/internal/storage"
 )
 
 // Config holds worker configuration.
 type Config struct {
-	MaxConcurrent int           // Max parallel container runs
-	PollInterval  time.Duration // How often to check for work
+	MaxConcurrent   int           // Max parallel container runs
+	PollInterval    time.Duration // How often to check for work
+	MaxPollInterval time.Duration // Ceiling for the idle backoff of PollInterval
+	ClaimBatchSize  int           // Max runs claimed per poll (0 = as many as there are free slots)
+
+	HeartbeatInterval time.Duration // How often a running run's heartbeat is refreshed
+	ReaperInterval    time.Duration // How often the reaper looks for stale and expired runs
+	StaleThreshold    time.Duration // Heartbeat age after which a run is considered dead
+
+	RetentionDays    int // Delete finished runs older than this (0 = keep forever)
+	RetentionMaxRuns int // Keep at most this many finished runs per job (0 = unlimited)
+	LogRetentionDays int // Clear logs of runs finished longer ago than this (0 = keep)
+
+	MaxStoredLogBytes int // Keep only the last this-many bytes of a run's logs in the database (0 = all)
+
+	BlockNetworkByDefault bool // Run containers without networking unless the job opts in
+
+	KeepFailedContainers bool          // Leave failed containers for debugging unless the job overrides it
+	KeptContainerTTL     time.Duration // How long a kept container survives before the sweeper removes it
+
+	MaxPauseDuration  time.Duration // Pause length for runs paused without an expiry recorded
+	PauseExpiryAction string        // "kill" or "resume", for runs paused without one recorded
+
+	SMTP SMTPConfig // Mail server for email notification channels
+
+	EnvKeys *envcrypt.Keyring // Decrypts job env sealed at rest; nil = plaintext only
+
+	DefaultEnv map[string]string // Operator env for every run, under the job's own; see withDefaultEnv
+
+	AllowedCapabilities []string // Capabilities a job may add; others fail the run (ALLOWED_CAPABILITIES)
 }
 
 // DefaultConfig returns sensible defaults.
 func DefaultConfig() Config {
 	return Config{
-		MaxConcurrent: 5,
-		PollInterval:  time.Second,
+		MaxConcurrent:   5,
+		PollInterval:    time.Second,
+		MaxPollInterval: 10 * time.Second,
+
+		HeartbeatInterval: 10 * time.Second,
+		ReaperInterval:    30 * time.Second,
+		StaleThreshold:    60 * time.Second,
+
+		KeptContainerTTL: 24 * time.Hour,
+
+		MaxPauseDuration:  24 * time.Hour,
+		PauseExpiryAction: "kill",
 	}
 }
 
 // Worker polls the job_queue and executes runs.
 type Worker struct {
 	db      *database.DB
-	docker  *docker.Client
+	docker  *docker.Client // The default daemon (compose runs, builds)
+	hosts   *docker.Hosts
 	storage *storage.Client
+	logs    *logstore.Stores // Full logs of runs cut to MaxStoredLogBytes; nil = not kept
 	cfg     Config
 
 	activeRuns atomic.Int32
+	inFlight   sync.Map    // Run IDs (uuid.UUID) this process is executing
+	draining   atomic.Bool // Set by Drain: finish in-flight runs but claim no new ones
 	wg         sync.WaitGroup
 	stopCh     chan struct{}
+	runCtx     context.Context    // Parent of in-flight runs; outlives the poll loop's ctx
+	abortRuns  context.CancelFunc // Cancels runCtx once Shutdown's grace period is up
+	wakeCh     chan struct{}      // Signalled by listenQueue when a run is enqueued
 }
 
 // New creates a new Worker.
-func New(db *database.DB, dockerClient *docker.Client, storageClient *storage.Client, cfg Config) *Worker {
+func New(db *database.DB, dockerHosts *docker.Hosts, storageClient *storage.Client, logStore *logstore.Stores, cfg Config) *Worker {
 	if cfg.MaxConcurrent <= 0 {
 		cfg.MaxConcurrent = 5
 	}
 	if cfg.PollInterval <= 0 {
 		cfg.PollInterval = time.Second
 	}
+	if cfg.MaxPollInterval < cfg.PollInterval {
+		cfg.MaxPollInterval = cfg.PollInterval
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = 10 * time.Second
+	}
+	if cfg.ReaperInterval <= 0 {
+		cfg.ReaperInterval = 30 * time.Second
+	}
+	if cfg.StaleThreshold <= 0 {
+		cfg.StaleThreshold = 60 * time.Second
+	}
+	if cfg.KeptContainerTTL <= 0 {
+		cfg.KeptContainerTTL = 24 * time.Hour
+	}
+	if cfg.MaxPauseDuration <= 0 {
+		cfg.MaxPauseDuration = 24 * time.Hour
+	}
+	if cfg.PauseExpiryAction == "" {
+		cfg.PauseExpiryAction = "kill"
+	}
 
-	return &Worker{
-		db:      db,
-		docker:  dockerClient,
-		storage: storageClient,
-		cfg:     cfg,
-		stopCh:  make(chan struct{}),
+	runCtx, abortRuns := context.WithCancel(context.Background())
+	w := &Worker{
+		db:        db,
+		docker:    dockerHosts.Default,
+		hosts:     dockerHosts,
+		storage:   storageClient,
+		logs:      logStore,
+		cfg:       cfg,
+		stopCh:    make(chan struct{}),
+		wakeCh:    make(chan struct{}, 1),
+		runCtx:    runCtx,
+		abortRuns: abortRuns,
 	}
+	return w
 }
 
 // Run starts the worker poll loop. Blocks until ctx is cancelled.
+// Each poll claims runs until the free slots (or ClaimBatchSize) are used up.
+// A poll that finds the queue empty doubles the wait before the next one,
+// up to MaxPollInterval; claiming a run resets it to PollInterval. A queue
+// notification triggers an immediate poll, so the ticker is only a safety net.
 func (w *Worker) Run(ctx context.Context) {
-	log.Printf("[worker] Started (maxConcurrent=%d, pollInterval=%s)", w.cfg.MaxConcurrent, w.cfg.PollInterval)
+	log.Printf("[worker] Started (maxConcurrent=%d, pollInterval=%s, maxPollInterval=%s, claimBatchSize=%d)",
+		w.cfg.MaxConcurrent, w.cfg.PollInterval, w.cfg.MaxPollInterval, w.cfg.ClaimBatchSize)
+
+	go w.listenQueue(ctx)
 
-	ticker := time.NewTicker(w.cfg.PollInterval)
-	defer ticker.Stop()
+	interval := w.cfg.PollInterval
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
 
 	for {
 		select {
@@ -80,15 +167,84 @@ func (w *Worker) Run(ctx context.Context) {
 		case <-w.stopCh:
 			log.Println("[worker] Stop signal received")
 			return
-		case <-ticker.C:
-			if int(w.activeRuns.Load()) < w.cfg.MaxConcurrent {
-				w.pollAndExecute(ctx)
+		case <-timer.C:
+			if w.draining.Load() {
+				interval = w.cfg.MaxPollInterval
+			} else if int(w.activeRuns.Load()) < w.cfg.MaxConcurrent {
+				interval = w.nextPollInterval(interval, w.claimRuns(ctx) > 0)
+			} else {
+				// At capacity: the queue may well have work, so don't back off
+				interval = w.cfg.PollInterval
+			}
+			timer.Reset(interval)
+		case <-w.wakeCh:
+			// One notification may stand in for several enqueues
+			if !w.draining.Load() {
+				w.claimRuns(ctx)
 			}
+			interval = w.cfg.PollInterval
+			timer.Reset(interval)
 		}
 	}
 }
 
-// Shutdown gracefully waits for all in-flight runs to complete.
+// claimRuns claims and starts queued runs while there are free slots, up to
+// ClaimBatchSize, and returns how many it claimed. Each claim is its own
+// SKIP LOCKED transaction, so other workers can claim alongside.
+func (w *Worker) claimRuns(ctx context.Context) int {
+	claimed := 0
+	for int(w.activeRuns.Load()) < w.cfg.MaxConcurrent {
+		if w.cfg.ClaimBatchSize > 0 && claimed >= w.cfg.ClaimBatchSize {
+			break
+		}
+		if !w.pollAndExecute(ctx) {
+			break
+		}
+		claimed++
+	}
+	return claimed
+}
+
+// nextPollInterval returns the wait before the next poll.
+func (w *Worker) nextPollInterval(current time.Duration, foundWork bool) time.Duration {
+	if foundWork {
+		return w.cfg.PollInterval
+	}
+	return min(current*2, w.cfg.MaxPollInterval)
+}
+
+// Drain stops the worker from claiming new runs. In-flight runs carry on to
+// completion and queued runs stay queued for another worker (or for Resume).
+func (w *Worker) Drain() {
+	if !w.draining.Swap(true) {
+		log.Printf("[worker] Draining: no new runs will be claimed (%d in flight)", w.activeRuns.Load())
+	}
+}
+
+// Resume undoes Drain so the worker claims runs again.
+func (w *Worker) Resume() {
+	if w.draining.Swap(false) {
+		log.Println("[worker] Resumed claiming runs")
+		w.wake()
+	}
+}
+
+// wake prompts the poll loop to check the queue now rather than at its next
+// tick.
+func (w *Worker) wake() {
+	select {
+	case w.wakeCh <- struct{}{}:
+	default: // A wake-up is already pending
+	}
+}
+
+// Draining reports whether Drain is in effect.
+func (w *Worker) Draining() bool {
+	return w.draining.Load()
+}
+
+// Shutdown stops the poll loop and waits up to timeout for in-flight runs to
+// complete, then interrupts any still running.
 func (w *Worker) Shutdown(timeout time.Duration) {
 	close(w.stopCh)
 
@@ -101,16 +257,51 @@ func (w *Worker) Shutdown(timeout time.Duration) {
 	select {
 	case <-done:
 		log.Println("[worker] All runs completed")
+		return
 	case <-time.After(timeout):
+	}
+
+	// Out of patience: interrupt the remaining runs so they stop their
+	// containers and record the failure rather than being left running.
+	log.Printf("[worker] Shutdown grace period elapsed, interrupting %d runs", w.activeRuns.Load())
+	w.abortRuns()
+	select {
+	case <-done:
+		log.Println("[worker] Interrupted runs stopped")
+	case <-time.After(shutdownAbortWait):
 		log.Println("[worker] Shutdown timed out, some runs may be orphaned")
 	}
 }
 
+// shutdownAbortWait is how long Shutdown waits for interrupted runs to stop
+// their containers and record the outcome.
+const shutdownAbortWait = 15 * time.Second
+
 // ActiveRuns returns the number of currently executing runs.
 func (w *Worker) ActiveRuns() int {
 	return int(w.activeRuns.Load())
 }
 
+// underJobLimit takes a transaction-scoped lock on the job and reports
+// whether it has fewer than limit runs in flight, i.e. picked queue items.
+func (w *Worker) underJobLimit(ctx context.Context, tx pgx.Tx, jobID uuid.UUID, limit int) bool {
+	// Separate statements, so the count's snapshot is taken once the lock
+	// is held and sees claims committed by whoever held it before.
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, jobID.String()); err != nil {
+		log.Printf("[worker] ERROR locking job %s: %v", jobID, err)
+		return false
+	}
+	var inFlight int
+	err := tx.QueryRow(ctx, `
+		SELECT count(*) FROM job_queue WHERE job_id = $1 AND picked_at IS NOT NULL
+	`, jobID).Scan(&inFlight)
+	if err != nil {
+		log.Printf("[worker] ERROR checking run limit for job %s: %v", jobID, err)
+		return false
+	}
+	return inFlight < limit
+}
+
 // queuedJob holds the joined data from job_queue + jobs.
 type queuedJob struct {
 	QueueID        uuid.UUID
@@ -121,59 +312,115 @@ type queuedJob struct {
 	Image          string
 	Command        []string
 	EnvJSON        []byte
+	EnvSealed      []byte
+	EnvKeyID       *string
 	MemoryMB       int
 	CPUMillicores  int
 	TimeoutSeconds int
+	StartTimeout   *int
 	Script         *string
 	ScriptLang     *string
 	SourceType     string
+	RuntimeBudget  *int
+	ImageDigest    *string
+	KeepFailed     *bool
+	Stdin          *string
+	NetworkAccess  *bool
+	RestartPolicy  *string
+	LogDriver      *string
+	GPUs           *string
+	RequestID      *string
+	Version        int
+	DockerHost     *string
+	DNS            []string
+	ExtraHosts     []string
+	CapAdd         []string
+	CapDrop        []string
+	Labels         map[string]string
+	MaxConcurrent  *int
+	TemplateVars   bool
+	RunEnvJSON     []byte
+	RunEnvSealed   []byte
+	RunEnvKeyID    *string
 }
 
 // pollAndExecute claims one job from the queue using SKIP LOCKED and executes it.
-func (w *Worker) pollAndExecute(ctx context.Context) {
+// It reports whether a run was claimed.
+func (w *Worker) pollAndExecute(ctx context.Context) bool {
 	tx, err := w.db.Pool.Begin(ctx)
 	if err != nil {
-		return
+		return false
 	}
 
 	var qj queuedJob
 	err = tx.QueryRow(ctx, `
 		SELECT q.id, q.run_id, q.job_id,
-		       j.user_id, j.name, j.image, j.command, j.env,
-		       j.memory_mb, j.cpu_millicores, j.timeout_seconds,
-		       j.script, j.script_lang, j.source_type
+		       j.user_id, j.name, j.image, j.command, j.env, j.env_sealed, j.env_key_id,
+		       COALESCE(r.memory_mb, j.memory_mb), COALESCE(r.cpu_millicores, j.cpu_millicores), j.timeout_seconds, j.start_timeout_seconds,
+		       j.script, j.script_lang, j.source_type, j.daily_runtime_budget_seconds,
+		       j.image_digest, j.keep_failed_containers, COALESCE(r.stdin, j.stdin),
+		       j.network_access, j.restart_policy, j.log_driver, j.gpus, r.request_id, r.version, j.docker_host, j.dns, j.extra_hosts, j.cap_add, j.cap_drop, r.labels,
+		       j.max_concurrent_runs, j.template_vars, r.env, r.env_sealed, r.env_key_id
 		FROM job_queue q
 		JOIN jobs j ON j.id = q.job_id
+		JOIN job_runs r ON r.id = q.run_id
 		WHERE q.picked_at IS NULL
 		  AND q.scheduled_at <= now()
+		  AND r.status = 'pending'::run_status
+		  AND (j.max_concurrent_runs IS NULL OR (
+		        SELECT count(*) FROM job_queue p WHERE p.job_id = q.job_id AND p.picked_at IS NOT NULL
+		      ) < j.max_concurrent_runs)
 		ORDER BY q.priority DESC, q.scheduled_at ASC
 		LIMIT 1
 		FOR UPDATE OF q SKIP LOCKED
 	`).Scan(
 		&qj.QueueID, &qj.RunID, &qj.JobID,
-		&qj.UserID, &qj.JobName, &qj.Image, &qj.Command, &qj.EnvJSON,
-		&qj.MemoryMB, &qj.CPUMillicores, &qj.TimeoutSeconds,
-		&qj.Script, &qj.ScriptLang, &qj.SourceType,
+		&qj.UserID, &qj.JobName, &qj.Image, &qj.Command, &qj.EnvJSON, &qj.EnvSealed, &qj.EnvKeyID,
+		&qj.MemoryMB, &qj.CPUMillicores, &qj.TimeoutSeconds, &qj.StartTimeout,
+		&qj.Script, &qj.ScriptLang, &qj.SourceType, &qj.RuntimeBudget,
+		&qj.ImageDigest, &qj.KeepFailed, &qj.Stdin,
+		&qj.NetworkAccess, &qj.RestartPolicy, &qj.LogDriver, &qj.GPUs, &qj.RequestID, &qj.Version, &qj.DockerHost, &qj.DNS, &qj.ExtraHosts, &qj.CapAdd, &qj.CapDrop, &qj.Labels,
+		&qj.MaxConcurrent, &qj.TemplateVars, &qj.RunEnvJSON, &qj.RunEnvSealed, &qj.RunEnvKeyID,
 	)
 	if err != nil {
 		tx.Rollback(ctx)
-		return // No work available (pgx.ErrNoRows) or error
+		return false // No work available (pgx.ErrNoRows) or error
+	}
+
+	// The filter above can race with another worker claiming a run of the
+	// same job; recount under a per-job lock before taking the last slot.
+	if qj.MaxConcurrent != nil && !w.underJobLimit(ctx, tx, qj.JobID, *qj.MaxConcurrent) {
+		tx.Rollback(ctx)
+		return false
 	}
 
 	// Mark as picked
 	_, err = tx.Exec(ctx, `UPDATE job_queue SET picked_at = now() WHERE id = $1`, qj.QueueID)
 	if err != nil {
 		tx.Rollback(ctx)
-		return
+		return false
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return
+		return false
 	}
 
-	// Parse env
-	var env map[string]string
-	_ = json.Unmarshal(qj.EnvJSON, &env)
+	// Decrypt env. A run whose env can't be read fails rather than starting
+	// without its secrets.
+	env, err := w.cfg.EnvKeys.DecodeEnv(qj.EnvJSON, qj.EnvSealed, qj.EnvKeyID, qj.JobID)
+	if err != nil {
+		w.failRun(ctx, qj.RunID, qj.Version, time.Now(), models.FailureCreateError, fmt.Sprintf("reading job env: %v", err))
+		w.cleanupQueue(ctx, qj.QueueID)
+		return true
+	}
+	// Env given at trigger time wins over the job's for this run
+	runEnv, err := w.cfg.EnvKeys.DecodeEnv(qj.RunEnvJSON, qj.RunEnvSealed, qj.RunEnvKeyID, qj.RunID)
+	if err != nil {
+		w.failRun(ctx, qj.RunID, qj.Version, time.Now(), models.FailureCreateError, fmt.Sprintf("reading run env: %v", err))
+		w.cleanupQueue(ctx, qj.QueueID)
+		return true
+	}
+	maps.Copy(env, runEnv)
 
 	job := models.Job{
 		ID:             qj.JobID,
@@ -188,6 +435,22 @@ func (w *Worker) pollAndExecute(ctx context.Context) {
 		Script:         qj.Script,
 		ScriptLang:     qj.ScriptLang,
 		SourceType:     qj.SourceType,
+
+		StartTimeoutSeconds:       qj.StartTimeout,
+		DailyRuntimeBudgetSeconds: qj.RuntimeBudget,
+		ImageDigest:               qj.ImageDigest,
+		KeepFailedContainers:      qj.KeepFailed,
+		Stdin:                     qj.Stdin,
+		NetworkAccess:             qj.NetworkAccess,
+		RestartPolicy:             qj.RestartPolicy,
+		LogDriver:                 qj.LogDriver,
+		GPUs:                      qj.GPUs,
+		DockerHost:                qj.DockerHost,
+		DNS:                       qj.DNS,
+		ExtraHosts:                qj.ExtraHosts,
+		CapAdd:                    qj.CapAdd,
+		CapDrop:                   qj.CapDrop,
+		TemplateVars:              qj.TemplateVars,
 	}
 
 	// Execute in background
@@ -196,49 +459,143 @@ func (w *Worker) pollAndExecute(ctx context.Context) {
 	go func() {
 		defer w.wg.Done()
 		defer w.activeRuns.Add(-1)
-		w.executeRun(job, qj.RunID, qj.QueueID)
+		w.inFlight.Store(qj.RunID, struct{}{})
+		defer w.inFlight.Delete(qj.RunID)
+		// Runs get the worker's run context rather than ctx: stopping the poll
+		// loop must not cut short runs that can finish within Shutdown's grace
+		w.executeRun(w.runCtx, job, qj.RunID, qj.QueueID, qj.Version, deref(qj.RequestID), qj.Labels)
 	}()
+	return true
 }
 
 // executeRun pulls the image, creates a container, runs it, and captures the result.
-func (w *Worker) executeRun(job models.Job, runID, queueID uuid.UUID) {
-	ctx := context.Background()
+// Docker operations are bound to ctx, which Shutdown cancels only once its
+// grace period is up; status bookkeeping uses dbCtx so it still lands afterwards.
+// requestID is the API request that triggered the run ("" for scheduled runs)
+// and is included in log lines so they can be correlated with the API logs.
+// version is the run's version as claimed; status transitions are guarded by
+// it so a run cancelled or reaped in the meantime isn't overwritten.
+// labels are the run's trigger labels, available to command/env templates.
+func (w *Worker) executeRun(ctx context.Context, job models.Job, runID, queueID uuid.UUID, version int, requestID string, labels map[string]string) {
+	dbCtx := context.WithoutCancel(ctx)
 	startedAt := time.Now()
 
 	// Panic recovery
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("[worker] PANIC in run %s: %v", runID, r)
-			w.failRun(ctx, runID, startedAt, fmt.Sprintf("panic: %v", r))
+			w.failRun(dbCtx, runID, version, startedAt, models.FailureWorkerCrash, fmt.Sprintf("panic: %v", r))
 		}
 	}()
 
-	log.Printf("[worker] Executing run %s for job %s (image: %s)", runID, job.Name, job.Image)
+	log.Printf("[worker] Executing run %s for job %s (image: %s, request: %s)", runID, job.Name, job.Image, requestID)
+
+	if msg := w.checkRuntimeBudget(dbCtx, job); msg != "" {
+		w.failRun(dbCtx, runID, version, startedAt, models.FailureBudgetExhausted, msg)
+		w.cleanupQueue(dbCtx, queueID)
+		return
+	}
+
+	if job.SourceType == "compose" {
+		job.DockerHost = nil // Compose runs always use the default daemon
+	}
+	dc, ok := w.hosts.Get(deref(job.DockerHost))
+	if !ok {
+		w.failRun(dbCtx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("docker host %q is not configured", *job.DockerHost))
+		w.cleanupQueue(dbCtx, queueID)
+		return
+	}
+	vars := runvars.New(job.ID, job.Name, runID, startedAt, labels)
+	if err := resolveRunVars(&job, vars, w.cfg.DefaultEnv); err != nil {
+		w.failRun(dbCtx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("invalid template: %v", err))
+		w.cleanupQueue(dbCtx, queueID)
+		return
+	}
+	addRunIdentityEnv(job.Env, vars, requestID)
+	// The API checks cap_add on save, but jobs saved (or cloned from ones
+	// saved) before the operator narrowed ALLOWED_CAPABILITIES keep theirs
+	if denied := w.disallowedCapabilities(job.CapAdd); len(denied) > 0 {
+		w.failRun(dbCtx, runID, version, startedAt, models.FailureCreateError,
+			fmt.Sprintf("capabilities not in ALLOWED_CAPABILITIES: %s", strings.Join(denied, ", ")))
+		w.cleanupQueue(dbCtx, queueID)
+		return
+	}
 
 	// Mark as running
-	if _, err := w.db.Pool.Exec(ctx, `
-		UPDATE job_runs SET status = 'running'::run_status, started_at = $1, heartbeat_at = $1
-		WHERE id = $2
-	`, startedAt, runID); err != nil {
+	var queuedAt time.Time
+	err := w.db.Pool.QueryRow(dbCtx, `
+		UPDATE job_runs SET status = 'running'::run_status, started_at = $1, heartbeat_at = $1,
+			attempt = attempt + 1, version = version + 1, docker_host = $4
+		WHERE id = $2 AND version = $3
+		RETURNING version, created_at
+	`, startedAt, runID, version, job.DockerHost).Scan(&version, &queuedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("[worker] Run %s changed since it was claimed (conflict) — not starting it", runID)
+		w.cleanupQueue(dbCtx, queueID)
+		return
+	}
+	if err != nil {
 		log.Printf("[worker] ERROR marking run %s as running: %v", runID, err)
 		return
 	}
+	runQueueWait.Observe(startedAt.Sub(queuedAt).Seconds())
 
 	// Handle compose source type separately
 	if job.SourceType == "compose" {
-		w.executeComposeRun(ctx, job, runID, queueID, startedAt)
+		w.executeComposeRun(ctx, job, runID, queueID, version, startedAt)
 		return
 	}
 
-	// Pull image
-	if err := w.docker.PullImage(ctx, job.Image); err != nil {
-		w.failRun(ctx, runID, startedAt, fmt.Sprintf("image pull failed: %v", err))
+	// The start deadline bounds everything from enqueue until the container
+	// is running: pulling, creating and starting it use startCtx. A step that
+	// fails because the deadline passed fails the run as start_timeout.
+	startCtx := ctx
+	if job.StartTimeoutSeconds != nil && *job.StartTimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		startCtx, cancel = context.WithDeadline(ctx, queuedAt.Add(time.Duration(*job.StartTimeoutSeconds)*time.Second))
+		defer cancel()
+	}
+	startTimedOut := func() bool {
+		return errors.Is(startCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+	}
+	if startTimedOut() {
+		w.failRun(ctx, runID, version, startedAt, models.FailureStartTimeout, startTimeoutMessage(job, "before it was picked up"))
+		w.cleanupQueue(ctx, queueID)
+		return
+	}
+
+	// Pull image (by digest when the job is pinned to one)
+	image := job.Image
+	if job.ImageDigest != nil {
+		image = docker.PinnedRef(job.Image, *job.ImageDigest)
+	}
+	err = dc.PullImageProgress(startCtx, image, func(p docker.PullProgress) {
+		_, _ = w.db.Pool.Exec(dbCtx, `UPDATE job_runs SET status_detail = $1 WHERE id = $2`, p.String(), runID)
+	})
+	_, _ = w.db.Pool.Exec(dbCtx, `UPDATE job_runs SET status_detail = NULL WHERE id = $1`, runID)
+	if err != nil && startTimedOut() {
+		w.failRun(ctx, runID, version, startedAt, models.FailureStartTimeout, startTimeoutMessage(job, "while pulling "+image))
+		w.cleanupQueue(ctx, queueID)
+		return
+	} else if errors.Is(err, docker.ErrPullTimeout) {
+		w.failRun(ctx, runID, version, startedAt, models.FailureImagePull, fmt.Sprintf("image pull timed out after %s (%s)", dc.EffectivePullTimeout(), image))
+		w.cleanupQueue(ctx, queueID)
+		return
+	} else if err != nil {
+		w.failRun(ctx, runID, version, startedAt, models.FailureImagePull, fmt.Sprintf("image pull failed: %v", err))
 		w.cleanupQueue(ctx, queueID)
 		return
 	}
 
+	// Record exactly which image this run uses
+	if digest, err := dc.ImageDigest(startCtx, image); err != nil {
+		log.Printf("[worker] Warning: failed to resolve digest for %s: %v", image, err)
+	} else if _, err := w.db.Pool.Exec(dbCtx, `UPDATE job_runs SET image_digest = $1 WHERE id = $2`, digest, runID); err != nil {
+		log.Printf("[worker] ERROR recording image digest for %s: %v", runID, err)
+	}
+
 	// Create container
-	containerName := fmt.Sprintf("orbex-%s-%s", job.Name, runID.String()[:8])
+	containerName := runContainerName(job.Name, runID)
 
 	// Handle inline script mounting
 	var binds []string
@@ -250,7 +607,7 @@ func (w *Worker) executeRun(job models.Job, runID, queueID uuid.UUID) {
 		os.MkdirAll(scriptDir, 0755)
 		scriptPath := filepath.Join(scriptDir, runID.String()+ext)
 		if err := os.WriteFile(scriptPath, []byte(*job.Script), 0644); err != nil {
-			w.failRun(ctx, runID, startedAt, fmt.Sprintf("failed to write script: %v", err))
+			w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("failed to write script: %v", err))
 			w.cleanupQueue(ctx, queueID)
 			return
 		}
@@ -270,7 +627,7 @@ func (w *Worker) executeRun(job models.Job, runID, queueID uuid.UUID) {
 		prefix := fmt.Sprintf("uploads/%s/%s/", job.UserID, job.ID)
 		objects, err := w.storage.List(ctx, prefix)
 		if err != nil {
-			w.failRun(ctx, runID, startedAt, fmt.Sprintf("failed to list uploaded files: %v", err))
+			w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("failed to list uploaded files: %v", err))
 			w.cleanupQueue(ctx, queueID)
 			return
 		}
@@ -279,7 +636,7 @@ func (w *Worker) executeRun(job models.Job, runID, queueID uuid.UUID) {
 			filename := filepath.Base(obj.Key)
 			reader, err := w.storage.Download(ctx, obj.Key)
 			if err != nil {
-				w.failRun(ctx, runID, startedAt, fmt.Sprintf("failed to download %s: %v", filename, err))
+				w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("failed to download %s: %v", filename, err))
 				w.cleanupQueue(ctx, queueID)
 				os.RemoveAll(workspaceDir)
 				return
@@ -288,7 +645,7 @@ func (w *Worker) executeRun(job models.Job, runID, queueID uuid.UUID) {
 			file, err := os.Create(localPath)
 			if err != nil {
 				reader.Close()
-				w.failRun(ctx, runID, startedAt, fmt.Sprintf("failed to create %s: %v", filename, err))
+				w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("failed to create %s: %v", filename, err))
 				w.cleanupQueue(ctx, queueID)
 				os.RemoveAll(workspaceDir)
 				return
@@ -303,43 +660,108 @@ func (w *Worker) executeRun(job models.Job, runID, queueID uuid.UUID) {
 		log.Printf("[worker] Mounted %d uploaded files for run %s", len(objects), runID)
 	}
 
-	containerID, err := w.docker.CreateContainer(ctx, docker.ContainerConfig{
+	// Without a command the container runs the image's default; catch images
+	// that have none here instead of letting the create fail cryptically.
+	if len(command) == 0 {
+		if ok, err := dc.HasDefaultCommand(startCtx, image); err != nil {
+			log.Printf("[worker] Warning: failed to inspect %s for a default command: %v", image, err)
+		} else if !ok {
+			w.failRun(ctx, runID, version, startedAt, models.FailureCreateError,
+				fmt.Sprintf("image %s has no default command; please specify one", image))
+			w.cleanupQueue(ctx, queueID)
+			if uploadCleanup != nil {
+				uploadCleanup()
+			}
+			return
+		}
+	}
+
+	containerID, err := w.createContainer(startCtx, dc, runID, docker.ContainerConfig{
 		Name:          containerName,
-		Image:         job.Image,
+		Image:         image,
 		Command:       command,
 		Env:           job.Env,
 		MemoryMB:      job.MemoryMB,
 		CPUMillicores: job.CPUMillicores,
 		Binds:         binds,
+		Stdin:         job.Stdin != nil,
+		NoNetwork:     !w.networkAccess(job),
+		RestartPolicy: deref(job.RestartPolicy),
+		LogDriver:     deref(job.LogDriver),
+		GPUs:          deref(job.GPUs),
+		DNS:           job.DNS,
+		ExtraHosts:    job.ExtraHosts,
+		CapAdd:        job.CapAdd,
+		CapDrop:       job.CapDrop,
 	})
-	if err != nil {
-		w.failRun(ctx, runID, startedAt, fmt.Sprintf("container create failed: %v", err))
+	if err != nil && startTimedOut() {
+		w.failRun(ctx, runID, version, startedAt, models.FailureStartTimeout, startTimeoutMessage(job, "while creating the container"))
+		_ = dc.RemoveContainer(dbCtx, containerName) // The daemon may have created it after all
+		w.cleanupQueue(ctx, queueID)
+		return
+	} else if err != nil {
+		w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("container create failed: %v", err))
 		w.cleanupQueue(ctx, queueID)
 		return
 	}
 
 	// Store container ID
-	_, _ = w.db.Pool.Exec(ctx, `UPDATE job_runs SET container_id = $1 WHERE id = $2`, containerID, runID)
+	_, _ = w.db.Pool.Exec(dbCtx, `UPDATE job_runs SET container_id = $1 WHERE id = $2`, containerID, runID)
 
-	// Set up wait channel BEFORE starting (avoid race with fast-exiting containers)
+	// Set up wait channel BEFORE starting (avoid race with fast-exiting containers).
+	// With a restart policy, Docker restarts a crashed container in place, so
+	// the run only ends at the final exit. The timeout below covers all
+	// attempts together, and stopping the container on timeout also stops
+	// Docker from restarting it again.
 	type waitResult struct {
 		exitCode int64
 		err      error
 	}
 	waitCh := make(chan waitResult, 1)
 	go func() {
-		exitCode, err := w.docker.WaitContainer(ctx, containerID)
+		exitCode, err := dc.WaitContainer(ctx, containerID)
+		if err == nil && job.RestartPolicy != nil {
+			exitCode, err = dc.WaitRestarts(ctx, containerID, exitCode)
+		}
 		waitCh <- waitResult{exitCode, err}
 	}()
 
+	// Attach to stdin before starting, so the process can't start reading
+	// before anything is connected
+	var stdin *docker.Stdin
+	if job.Stdin != nil {
+		stdin, err = dc.AttachStdin(ctx, containerID)
+		if err != nil {
+			w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("attaching stdin failed: %v", err))
+			_ = dc.RemoveContainer(dbCtx, containerID)
+			w.cleanupQueue(ctx, queueID)
+			return
+		}
+		defer stdin.Close()
+	}
+
 	// Start container
-	if err := w.docker.StartContainer(ctx, containerID); err != nil {
-		w.failRun(ctx, runID, startedAt, fmt.Sprintf("container start failed: %v", err))
-		_ = w.docker.RemoveContainer(ctx, containerID)
+	if err := dc.StartContainer(startCtx, containerID); err != nil {
+		if startTimedOut() {
+			w.failRun(ctx, runID, version, startedAt, models.FailureStartTimeout, startTimeoutMessage(job, "while starting the container"))
+		} else {
+			w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("container start failed: %v", err))
+		}
+		_ = dc.RemoveContainer(dbCtx, containerID)
 		w.cleanupQueue(ctx, queueID)
 		return
 	}
 
+	// Feed stdin; the process blocks reading it until the data arrives
+	if stdin != nil {
+		if err := stdin.Write(*job.Stdin); err != nil {
+			w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("writing stdin failed: %v", err))
+			_ = dc.RemoveContainer(dbCtx, containerID)
+			w.cleanupQueue(ctx, queueID)
+			return
+		}
+	}
+
 	// Start heartbeat emitter
 	heartbeatCtx, heartbeatCancel := context.WithCancel(ctx)
 	go w.emitHeartbeat(heartbeatCtx, runID)
@@ -361,7 +783,7 @@ func (w *Worker) executeRun(job models.Job, runID, queueID uuid.UUID) {
 		case <-timer.C:
 			timedOut = true
 			log.Printf("[worker] Run %s timed out after %ds — killing container", runID, job.TimeoutSeconds)
-			_ = w.docker.StopContainer(ctx, containerID, 5)
+			_ = dc.StopContainer(dbCtx, containerID, 5)
 			wr := <-waitCh // Wait for container to actually stop
 			result.exitCode = wr.exitCode
 			result.err = wr.err
@@ -372,74 +794,128 @@ func (w *Worker) executeRun(job models.Job, runID, queueID uuid.UUID) {
 		result.err = wr.err
 	}
 
+	// Worker shutdown cancelled the wait — stop the container rather than leak it
+	interrupted := result.err != nil && ctx.Err() != nil
+	if interrupted {
+		log.Printf("[worker] Run %s interrupted by shutdown — stopping container", runID)
+		_ = dc.StopContainer(dbCtx, containerID, 5)
+	}
+
 	heartbeatCancel()
 	duration := time.Since(startedAt)
 
-	// Capture logs (GetLogs already demuxes via stdcopy)
-	logStr, err := w.docker.GetLogs(ctx, containerID, "all")
-	if err != nil {
-		log.Printf("[worker] Warning: failed to get logs for %s: %v", runID, err)
+	// Capture logs (GetLogs already demuxes via stdcopy). With the "none"
+	// log driver there are none to read.
+	var logStr string
+	if deref(job.LogDriver) != docker.LogDriverNone {
+		logStr, err = dc.GetLogs(dbCtx, containerID, "all", "")
+		if err != nil {
+			log.Printf("[worker] Warning: failed to get logs for %s: %v", runID, err)
+		}
+	}
+	// Record the run's result before its final status, so whoever waits for
+	// the status sees the result with it
+	runResult, warning := parseRunResult(logStr)
+	if warning != "" {
+		log.Printf("[worker] Warning: ignoring result of run %s: %s", runID, warning)
 	}
+	if runResult != nil {
+		if _, err := w.db.Pool.Exec(dbCtx, `UPDATE job_runs SET result = $1 WHERE id = $2`, runResult, runID); err != nil {
+			log.Printf("[worker] ERROR recording result for %s: %v", runID, err)
+		}
+	}
+	logStr = w.storedLogs(dbCtx, runID, logStr)
 
 	// Determine final status
-	var status string
+	var status, errMsg string
 	exitCode := result.exitCode
 
-	if timedOut {
+	if interrupted {
+		status = "cancelled"
+		errMsg = shutdownMessage
+		updateErr := database.CheckVersion(w.db.Pool.Exec(dbCtx, `
+			UPDATE job_runs SET
+				status = 'cancelled'::run_status, error_message = $1, finished_at = $2,
+				duration_ms = $3, logs_tail = $4, heartbeat_at = NULL, failure_reason = 'server_shutdown',
+				version = version + 1
+			WHERE id = $5 AND version = $6
+		`, errMsg, time.Now(), duration.Milliseconds(), logStr, runID, version))
+		logFinishError(runID, "cancelled", updateErr)
+	} else if timedOut {
 		status = "failed"
-		_, updateErr := w.db.Pool.Exec(ctx, `
+		errMsg = fmt.Sprintf("timeout exceeded (%ds limit)", job.TimeoutSeconds)
+		updateErr := database.CheckVersion(w.db.Pool.Exec(dbCtx, `
 			UPDATE job_runs SET 
 				status = 'failed'::run_status, exit_code = $1, 
 				error_message = $2, finished_at = $3, duration_ms = $4, 
-				logs_tail = $5, heartbeat_at = NULL
-			WHERE id = $6
-		`, exitCode, fmt.Sprintf("timeout exceeded (%ds limit)", job.TimeoutSeconds),
-			time.Now(), duration.Milliseconds(), logStr, runID)
-		if updateErr != nil {
-			log.Printf("[worker] ERROR updating timeout status for %s: %v", runID, updateErr)
-		}
+				logs_tail = $5, heartbeat_at = NULL, failure_reason = 'timeout',
+				version = version + 1
+			WHERE id = $6 AND version = $7
+		`, exitCode, errMsg, time.Now(), duration.Milliseconds(), logStr, runID, version))
+		logFinishError(runID, "timeout", updateErr)
 	} else if result.err != nil {
 		status = "failed"
-		errMsg := result.err.Error()
-		_, updateErr := w.db.Pool.Exec(ctx, `
+		errMsg = result.err.Error()
+		// Daemon blips are ridden out inside the wait; getting here means the
+		// daemon stayed away, which says nothing about the container itself.
+		reason := models.FailureWorkerCrash
+		if errors.Is(result.err, docker.ErrDaemonUnavailable) {
+			reason = models.FailureDaemonUnavailable
+		}
+		updateErr := database.CheckVersion(w.db.Pool.Exec(dbCtx, `
 			UPDATE job_runs SET 
 				status = 'failed'::run_status, exit_code = $1, error_message = $2,
-				finished_at = $3, duration_ms = $4, logs_tail = $5, heartbeat_at = NULL
-			WHERE id = $6
-		`, exitCode, errMsg, time.Now(), duration.Milliseconds(), logStr, runID)
-		if updateErr != nil {
-			log.Printf("[worker] ERROR updating failed status for %s: %v", runID, updateErr)
-		}
+				finished_at = $3, duration_ms = $4, logs_tail = $5, heartbeat_at = NULL,
+				failure_reason = $6, version = version + 1
+			WHERE id = $7 AND version = $8
+		`, exitCode, errMsg, time.Now(), duration.Milliseconds(), logStr, reason, runID, version))
+		logFinishError(runID, "failed", updateErr)
 	} else if exitCode == 0 {
 		status = "succeeded"
-		_, updateErr := w.db.Pool.Exec(ctx, `
+		updateErr := database.CheckVersion(w.db.Pool.Exec(dbCtx, `
 			UPDATE job_runs SET 
 				status = 'succeeded'::run_status, exit_code = 0,
-				finished_at = $1, duration_ms = $2, logs_tail = $3, heartbeat_at = NULL
-			WHERE id = $4
-		`, time.Now(), duration.Milliseconds(), logStr, runID)
-		if updateErr != nil {
-			log.Printf("[worker] ERROR updating succeeded status for %s: %v", runID, updateErr)
+				finished_at = $1, duration_ms = $2, logs_tail = $3, heartbeat_at = NULL,
+				version = version + 1
+			WHERE id = $4 AND version = $5
+		`, time.Now(), duration.Milliseconds(), logStr, runID, version))
+		logFinishError(runID, "succeeded", updateErr)
+		if updateErr == nil {
+			// Update job stats for anomaly detection baseline
+			w.updateJobStats(dbCtx, job.ID, duration.Milliseconds())
+			w.enqueueDependents(dbCtx, job.ID, runID)
 		}
-		// Update job stats for anomaly detection baseline
-		w.updateJobStats(ctx, job.ID, duration.Milliseconds())
 	} else {
 		status = "failed"
-		_, updateErr := w.db.Pool.Exec(ctx, `
+
+		// Exit code 137 alone can't distinguish an OOM kill from any other
+		// SIGKILL, so ask Docker whether the memory cgroup did it.
+		oom, err := dc.OOMKilled(dbCtx, containerID)
+		if err != nil {
+			log.Printf("[worker] Warning: failed to inspect %s for OOM: %v", runID, err)
+		} else if oom {
+			log.Printf("[worker] Run %s was OOM-killed (memory_mb=%d)", runID, job.MemoryMB)
+		}
+		var reason models.FailureReason
+		reason, errMsg = exitFailure(exitCode, oom, job.MemoryMB)
+		updateErr := database.CheckVersion(w.db.Pool.Exec(dbCtx, `
 			UPDATE job_runs SET 
 				status = 'failed'::run_status, exit_code = $1,
 				error_message = $2, finished_at = $3, duration_ms = $4, 
-				logs_tail = $5, heartbeat_at = NULL
-			WHERE id = $6
-		`, exitCode, fmt.Sprintf("exit code %d", exitCode), time.Now(), duration.Milliseconds(), logStr, runID)
-		if updateErr != nil {
-			log.Printf("[worker] ERROR updating failed status for %s: %v", runID, updateErr)
-		}
+				logs_tail = $5, heartbeat_at = NULL, failure_reason = $6,
+				version = version + 1
+			WHERE id = $7 AND version = $8
+		`, exitCode, errMsg, time.Now(), duration.Milliseconds(), logStr, reason, runID, version))
+		logFinishError(runID, "failed", updateErr)
 	}
 
-	// Cleanup
-	w.cleanupQueue(ctx, queueID)
-	_ = w.docker.RemoveContainer(ctx, containerID)
+	// Cleanup (a failed container may be kept around for debugging)
+	w.cleanupQueue(dbCtx, queueID)
+	if status == "failed" && w.keepFailedContainer(job) {
+		w.keepContainer(dbCtx, runID)
+	} else {
+		_ = dc.RemoveContainer(dbCtx, containerID)
+	}
 	if scriptCleanup != nil {
 		scriptCleanup()
 	}
@@ -447,39 +923,198 @@ func (w *Worker) executeRun(job models.Job, runID, queueID uuid.UUID) {
 		uploadCleanup()
 	}
 
-	// Send notification if configured
-	var errMsg string
-	if timedOut {
-		errMsg = fmt.Sprintf("timeout exceeded (%ds limit)", job.TimeoutSeconds)
-	} else if result.err != nil {
-		errMsg = result.err.Error()
-	} else if exitCode != 0 {
-		errMsg = fmt.Sprintf("exit code %d", exitCode)
+	// Send notification if configured. A shutdown says nothing about the job.
+	if !interrupted {
+		w.sendNotification(dbCtx, job.ID, runID, status, exitCode, duration.Milliseconds(), errMsg)
 	}
-	w.sendNotification(ctx, job.ID, runID, status, exitCode, duration.Milliseconds(), errMsg)
 
-	log.Printf("[worker] Run %s completed: status=%s exitCode=%d duration=%dms logs=%d bytes",
-		runID, status, exitCode, duration.Milliseconds(), len(logStr))
+	log.Printf("[worker] Run %s completed: status=%s exitCode=%d duration=%dms logs=%d bytes request=%s",
+		runID, status, exitCode, duration.Milliseconds(), len(logStr), requestID)
 }
 
-// failRun marks a run as failed.
-func (w *Worker) failRun(ctx context.Context, runID uuid.UUID, startedAt time.Time, errorMsg string) {
+// createContainer creates a run's container. Container names are derived from
+// the run, so a name conflict means a leftover container from an earlier
+// attempt whose cleanup didn't complete; it is removed and the create retried
+// once.
+func (w *Worker) createContainer(ctx context.Context, dc *docker.Client, runID uuid.UUID, cfg docker.ContainerConfig) (string, error) {
+	containerID, err := dc.CreateContainer(ctx, cfg)
+	if !errors.Is(err, docker.ErrNameConflict) {
+		return containerID, err
+	}
+
+	log.Printf("[worker] Container name %s already in use for run %s — removing the stale container and retrying", cfg.Name, runID)
+	if rmErr := dc.RemoveContainer(ctx, cfg.Name); rmErr != nil {
+		return "", fmt.Errorf("%w (removing stale container: %v)", err, rmErr)
+	}
+	return dc.CreateContainer(ctx, cfg)
+}
+
+// networkAccess reports whether a job's container gets networking. The job's
+// own setting takes precedence over the global default.
+func (w *Worker) networkAccess(job models.Job) bool {
+	if job.NetworkAccess != nil {
+		return *job.NetworkAccess
+	}
+	return !w.cfg.BlockNetworkByDefault
+}
+
+// disallowedCapabilities returns the entries of capAdd that
+// AllowedCapabilities doesn't permit.
+func (w *Worker) disallowedCapabilities(capAdd []string) []string {
+	var denied []string
+	for _, c := range capAdd {
+		if !slices.ContainsFunc(w.cfg.AllowedCapabilities, func(a string) bool { return docker.SameCapability(a, c) }) {
+			denied = append(denied, c)
+		}
+	}
+	return denied
+}
+
+// keepFailedContainer reports whether a failed run's container should be left
+// in place. The job's own setting takes precedence over the global config.
+func (w *Worker) keepFailedContainer(job models.Job) bool {
+	if job.KeepFailedContainers != nil {
+		return *job.KeepFailedContainers
+	}
+	return w.cfg.KeepFailedContainers
+}
+
+// keepContainer records that a run's container was left for debugging; the
+// retention sweeper removes it once KeptContainerTTL has passed.
+func (w *Worker) keepContainer(ctx context.Context, runID uuid.UUID) {
+	keptUntil := time.Now().Add(w.cfg.KeptContainerTTL)
+	if _, err := w.db.Pool.Exec(ctx, `UPDATE job_runs SET container_kept_until = $1 WHERE id = $2`, keptUntil, runID); err != nil {
+		log.Printf("[worker] ERROR recording kept container for %s: %v", runID, err)
+		return
+	}
+	log.Printf("[worker] Keeping failed container for run %s until %s", runID, keptUntil.Format(time.RFC3339))
+}
+
+// deref returns the string s points to, or "" if s is nil.
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// exitFailure returns the failure reason and error message recorded for a
+// container that exited with a nonzero exitCode. oomKilled is what Docker's
+// inspect reported; it takes precedence, since the exit code of an OOM kill
+// looks like any other SIGKILL.
+func exitFailure(exitCode int64, oomKilled bool, memoryMB int) (models.FailureReason, string) {
+	if oomKilled {
+		return models.FailureOOM, oomMessage(memoryMB)
+	}
+	return models.FailureNonzeroExit, fmt.Sprintf("exit code %d", exitCode)
+}
+
+// oomMessage is the error recorded when a run exceeds its memory limit.
+func oomMessage(memoryMB int) string {
+	return fmt.Sprintf("container was killed for exceeding its %dMB memory limit (OOM); consider increasing memory_mb", memoryMB)
+}
+
+// failRun marks a run as failed unless its version has moved past version.
+// The update is detached from ctx cancellation so a failure caused by
+// shutdown is still recorded — as a cancellation, once Shutdown has
+// interrupted the worker's runs.
+func (w *Worker) failRun(ctx context.Context, runID uuid.UUID, version int, startedAt time.Time, reason models.FailureReason, errorMsg string) {
+	ctx = context.WithoutCancel(ctx)
 	duration := time.Since(startedAt)
-	_, err := w.db.Pool.Exec(ctx, `
+	if w.runCtx.Err() != nil {
+		log.Printf("[worker] Run %s interrupted by shutdown: %s", runID, errorMsg)
+		err := database.CheckVersion(w.db.Pool.Exec(ctx, `
+			UPDATE job_runs SET
+				status = 'cancelled'::run_status, error_message = $1, failure_reason = 'server_shutdown',
+				finished_at = $2, duration_ms = $3, heartbeat_at = NULL, version = version + 1
+			WHERE id = $4 AND version = $5
+		`, shutdownMessage, time.Now(), duration.Milliseconds(), runID, version))
+		logFinishError(runID, "cancelled", err)
+		return
+	}
+	err := database.CheckVersion(w.db.Pool.Exec(ctx, `
 		UPDATE job_runs SET 
-			status = 'failed'::run_status, error_message = $1,
-			finished_at = $2, duration_ms = $3, heartbeat_at = NULL
-		WHERE id = $4
-	`, errorMsg, time.Now(), duration.Milliseconds(), runID)
+			status = 'failed'::run_status, error_message = $1, failure_reason = $2,
+			finished_at = $3, duration_ms = $4, heartbeat_at = NULL, version = version + 1
+		WHERE id = $5 AND version = $6
+	`, errorMsg, reason, time.Now(), duration.Milliseconds(), runID, version))
+	logFinishError(runID, "failed", err)
+	log.Printf("[worker] Run %s failed: %s", runID, errorMsg)
+}
+
+// startTimeoutMessage describes a run that missed its job's start deadline;
+// when says where the run was stuck.
+func startTimeoutMessage(job models.Job, when string) string {
+	return fmt.Sprintf("container not running within %ds of being queued (stuck %s)", *job.StartTimeoutSeconds, when)
+}
+
+// shutdownMessage is the error message of runs cancelled by a server shutdown.
+const shutdownMessage = "server shutdown"
+
+// logFinishError reports a failed terminal status update. A version conflict
+// means another writer (a kill, the reaper) already finished the run, and its
+// status is kept.
+func logFinishError(runID uuid.UUID, status string, err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, database.ErrVersionConflict):
+		log.Printf("[worker] Run %s was finished elsewhere (conflict) — not recording %s", runID, status)
+	default:
+		log.Printf("[worker] ERROR updating %s status for %s: %v", status, runID, err)
+	}
+}
+
+// checkRuntimeBudget returns a failure message if the job has used up its
+// daily runtime budget, or "" if the run may start.
+func (w *Worker) checkRuntimeBudget(ctx context.Context, job models.Job) string {
+	// Jobs created before 0 was stored as no budget may still hold a 0
+	if job.DailyRuntimeBudgetSeconds == nil || *job.DailyRuntimeBudgetSeconds <= 0 {
+		return ""
+	}
+	var usedMs int64
+	err := w.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(duration_ms), 0) FROM job_runs
+		WHERE job_id = $1 AND created_at >= date_trunc('day', now())
+	`, job.ID).Scan(&usedMs)
 	if err != nil {
-		log.Printf("[worker] ERROR marking run %s as failed: %v", runID, err)
+		log.Printf("[worker] ERROR checking runtime budget for job %s: %v", job.ID, err)
+		return "" // Don't block runs on a bookkeeping failure
 	}
-	log.Printf("[worker] Run %s failed: %s", runID, errorMsg)
+	budget := int64(*job.DailyRuntimeBudgetSeconds)
+	if usedMs/1000 >= budget {
+		return fmt.Sprintf("daily runtime budget exhausted (%ds used of %ds)", usedMs/1000, budget)
+	}
+	return ""
 }
 
 // cleanupQueue removes the queue item for a completed run.
 func (w *Worker) cleanupQueue(ctx context.Context, queueID uuid.UUID) {
-	_, _ = w.db.Pool.Exec(ctx, `DELETE FROM job_queue WHERE id = $1`, queueID)
+	_, _ = w.db.Pool.Exec(context.WithoutCancel(ctx), `DELETE FROM job_queue WHERE id = $1`, queueID)
+}
+
+// maxContainerNameLen keeps container names within the 63-character limit
+// of DNS labels, which Docker tooling often assumes for names.
+const maxContainerNameLen = 63
+
+// runContainerName returns the Docker name for a run's container:
+// "orbex-<job>-<run id>". The full run ID keeps names unique across runs;
+// the job name is sanitized to Docker's allowed characters and shortened so
+// the whole name fits in maxContainerNameLen.
+func runContainerName(jobName string, runID uuid.UUID) string {
+	suffix := "-" + runID.String()
+	maxJobLen := maxContainerNameLen - len("orbex-") - len(suffix)
+
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		}
+		return '-'
+	}, jobName)
+	if len(name) > maxJobLen {
+		name = name[:maxJobLen]
+	}
+	return "orbex-" + name + suffix
 }
 
 // scriptExtension returns the file extension for a script language.
@@ -519,11 +1154,12 @@ func scriptCommand(lang, scriptPath string) []string {
 }
 
 // executeComposeRun handles the execution of compose-type jobs.
-func (w *Worker) executeComposeRun(ctx context.Context, job models.Job, runID, queueID uuid.UUID, startedAt time.Time) {
-	defer w.cleanupQueue(ctx, queueID)
+func (w *Worker) executeComposeRun(ctx context.Context, job models.Job, runID, queueID uuid.UUID, version int, startedAt time.Time) {
+	dbCtx := context.WithoutCancel(ctx)
+	defer w.cleanupQueue(dbCtx, queueID)
 
 	if w.storage == nil {
-		w.failRun(ctx, runID, startedAt, "storage client not available for compose jobs")
+		w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, "storage client not available for compose jobs")
 		return
 	}
 
@@ -531,7 +1167,7 @@ func (w *Worker) executeComposeRun(ctx context.Context, job models.Job, runID, q
 	prefix := fmt.Sprintf("uploads/%s/%s/", job.UserID, job.ID)
 	objects, err := w.storage.List(ctx, prefix)
 	if err != nil || len(objects) == 0 {
-		w.failRun(ctx, runID, startedAt, "no compose file found in storage")
+		w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, "no compose file found in storage")
 		return
 	}
 
@@ -545,26 +1181,26 @@ func (w *Worker) executeComposeRun(ctx context.Context, job models.Job, runID, q
 		}
 	}
 	if composeKey == "" {
-		w.failRun(ctx, runID, startedAt, "no docker-compose.yml found in uploaded files")
+		w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, "no docker-compose.yml found in uploaded files")
 		return
 	}
 
 	reader, err := w.storage.Download(ctx, composeKey)
 	if err != nil {
-		w.failRun(ctx, runID, startedAt, fmt.Sprintf("failed to download compose file: %v", err))
+		w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("failed to download compose file: %v", err))
 		return
 	}
 	data, err := io.ReadAll(reader)
 	reader.Close()
 	if err != nil {
-		w.failRun(ctx, runID, startedAt, fmt.Sprintf("failed to read compose file: %v", err))
+		w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("failed to read compose file: %v", err))
 		return
 	}
 
 	// Parse compose file
 	cf, err := compose.Parse(data)
 	if err != nil {
-		w.failRun(ctx, runID, startedAt, fmt.Sprintf("failed to parse compose file: %v", err))
+		w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("failed to parse compose file: %v", err))
 		return
 	}
 
@@ -579,35 +1215,37 @@ func (w *Worker) executeComposeRun(ctx context.Context, job models.Job, runID, q
 	for svcName, svcLogs := range result.Logs {
 		allLogs.WriteString(fmt.Sprintf("=== %s ===\n%s\n", svcName, svcLogs))
 	}
-	logsTail := allLogs.String()
-	if len(logsTail) > 50000 {
-		logsTail = logsTail[len(logsTail)-50000:]
-	}
+	logsTail := w.storedLogs(dbCtx, runID, allLogs.String())
 
 	duration := time.Since(startedAt).Milliseconds()
 
 	if result.Error != nil {
-		w.failRun(ctx, runID, startedAt, fmt.Sprintf("compose error: %v", result.Error))
+		w.failRun(ctx, runID, version, startedAt, models.FailureCreateError, fmt.Sprintf("compose error: %v", result.Error))
 		// Still store logs
-		_, _ = w.db.Pool.Exec(ctx, `UPDATE job_runs SET logs_tail = $1 WHERE id = $2`, logsTail, runID)
+		_, _ = w.db.Pool.Exec(dbCtx, `UPDATE job_runs SET logs_tail = $1 WHERE id = $2`, logsTail, runID)
 		return
 	}
 
 	exitCode := result.ExitCode
 	status := models.RunStatusSucceeded
+	var reason *models.FailureReason
 	if exitCode != 0 {
 		status = models.RunStatusFailed
+		nonzero := models.FailureNonzeroExit
+		reason = &nonzero
 	}
 
-	_, _ = w.db.Pool.Exec(ctx, `
+	updateErr := database.CheckVersion(w.db.Pool.Exec(dbCtx, `
 		UPDATE job_runs
 		SET status = $1, exit_code = $2, finished_at = now(),
-		    duration_ms = $3, logs_tail = $4
-		WHERE id = $5
-	`, status, exitCode, duration, logsTail, runID)
-
-	if status == models.RunStatusSucceeded {
-		w.updateJobStats(ctx, job.ID, duration)
+		    duration_ms = $3, logs_tail = $4, failure_reason = $5, version = version + 1
+		WHERE id = $6 AND version = $7
+	`, status, exitCode, duration, logsTail, reason, runID, version))
+	logFinishError(runID, string(status), updateErr)
+
+	if updateErr == nil && status == models.RunStatusSucceeded {
+		w.updateJobStats(dbCtx, job.ID, duration)
+		w.enqueueDependents(dbCtx, job.ID, runID)
 	}
 
 	log.Printf("[worker] Compose run %s completed: %s (exit=%d, duration=%dms)", runID, status, exitCode, duration)
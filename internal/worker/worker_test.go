@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/orbex-dev/orbex/internal/models"
+)
+
+func TestDisallowedCapabilities(t *testing.T) {
+	w := &Worker{cfg: Config{AllowedCapabilities: []string{"NET_ADMIN", "cap_sys_ptrace"}}}
+	tests := []struct {
+		name   string
+		capAdd []string
+		want   []string
+	}{
+		{"none", nil, nil},
+		{"allowed", []string{"NET_ADMIN"}, nil},
+		{"prefix and case ignored", []string{"cap_net_admin", "SYS_PTRACE"}, nil},
+		{"disallowed", []string{"NET_ADMIN", "SYS_ADMIN"}, []string{"SYS_ADMIN"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.disallowedCapabilities(tt.capAdd); !slices.Equal(got, tt.want) {
+				t.Errorf("disallowedCapabilities(%v) = %v, want %v", tt.capAdd, got, tt.want)
+			}
+		})
+	}
+
+	// With no allowlist every added capability is refused
+	if got := (&Worker{}).disallowedCapabilities([]string{"NET_ADMIN"}); !slices.Equal(got, []string{"NET_ADMIN"}) {
+		t.Errorf("empty allowlist: got %v, want [NET_ADMIN]", got)
+	}
+}
+
+func TestExitFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		exitCode   int64
+		oomKilled  bool
+		wantReason models.FailureReason
+		wantMsg    string
+	}{
+		{"oom killed", 137, true, models.FailureOOM, oomMessage(256)},
+		{"sigkill without oom", 137, false, models.FailureNonzeroExit, "exit code 137"},
+		{"plain failure", 1, false, models.FailureNonzeroExit, "exit code 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, msg := exitFailure(tt.exitCode, tt.oomKilled, 256)
+			if reason != tt.wantReason || msg != tt.wantMsg {
+				t.Errorf("exitFailure(%d, %v) = %q, %q; want %q, %q",
+					tt.exitCode, tt.oomKilled, reason, msg, tt.wantReason, tt.wantMsg)
+			}
+		})
+	}
+	if msg := oomMessage(256); !strings.Contains(msg, "256MB") || !strings.Contains(msg, "memory_mb") {
+		t.Errorf("oomMessage(256) = %q, want the limit and a memory_mb hint", msg)
+	}
+}
+
+// TestRunContainerNameUnique checks that runs of the same job never share a
+// container name, however long the job name, and that names stay within
+// Docker's limit.
+func TestRunContainerNameUnique(t *testing.T) {
+	for _, jobName := range []string{"etl", "nightly report/export!", strings.Repeat("a", 200)} {
+		seen := map[string]bool{}
+		for range 1000 {
+			runID := uuid.New()
+			name := runContainerName(jobName, runID)
+			if seen[name] {
+				t.Fatalf("job %q: container name %q produced twice", jobName, name)
+			}
+			seen[name] = true
+			if len(name) > maxContainerNameLen {
+				t.Fatalf("job %q: container name %q is %d characters, over %d", jobName, name, len(name), maxContainerNameLen)
+			}
+			if !strings.HasSuffix(name, runID.String()) {
+				t.Fatalf("job %q: container name %q doesn't end in the run ID", jobName, name)
+			}
+		}
+	}
+
+	// Job names that sanitize alike ("a/b", "a b" → "a-b") still differ by run
+	if runContainerName("a/b", uuid.New()) == runContainerName("a b", uuid.New()) {
+		t.Fatal("different runs produced the same container name")
+	}
+}